@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
+	"github.com/hayohtee/fumode/internal/carrier"
 	"github.com/hayohtee/fumode/internal/data"
 	"github.com/hayohtee/fumode/internal/mailer"
+	"github.com/hayohtee/fumode/internal/payment"
 	"github.com/hayohtee/fumode/internal/uploader"
 	"sync"
 
@@ -15,7 +18,11 @@ type application struct {
 	config       configuration
 	logger       *jsonlog.Logger
 	wg           sync.WaitGroup
+	jobs         context.Context
+	stopJobs     context.CancelFunc
 	repositories data.Repositories
 	mailer       mailer.Mailer
 	s3Uploader   *uploader.S3Uploader
+	payments     payment.Provider
+	carrier      carrier.Carrier
 }
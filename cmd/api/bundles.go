@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"github.com/hayohtee/fumode/internal/data"
+	"github.com/hayohtee/fumode/internal/money"
+	"github.com/hayohtee/fumode/internal/validator"
+	"net/http"
+)
+
+func (app *application) setBundleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Components []struct {
+			FurnitureID int64  `json:"furniture_id"`
+			VariantID   *int64 `json:"variant_id"`
+			Quantity    int    `json:"quantity"`
+		} `json:"components"`
+		Price           *money.Money `json:"price"`
+		DiscountPercent *float64     `json:"discount_percent"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	bundle := data.Bundle{
+		FurnitureID:     id,
+		Price:           input.Price,
+		DiscountPercent: input.DiscountPercent,
+	}
+	for _, component := range input.Components {
+		bundle.Components = append(bundle.Components, data.BundleComponent{
+			FurnitureID: component.FurnitureID,
+			VariantID:   component.VariantID,
+			Quantity:    component.Quantity,
+		})
+	}
+
+	v := validator.New()
+	if data.ValidateBundle(v, bundle); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.repositories.Bundles.Set(bundle)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrInvalidBundle):
+			v.AddError("components", err.Error())
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	furniture, err := app.repositories.Furniture.GetByID(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	furniture.Components, err = app.repositories.Bundles.GetComponents(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"furniture": furniture}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
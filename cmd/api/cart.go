@@ -0,0 +1,173 @@
+package main
+
+import (
+	"errors"
+	"github.com/hayohtee/fumode/internal/data"
+	"github.com/hayohtee/fumode/internal/validator"
+	"net/http"
+)
+
+func (app *application) showCartHandler(w http.ResponseWriter, r *http.Request) {
+	rate, ok := app.readExchangeRate(w, r)
+	if !ok {
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	cart, err := app.repositories.Cart.Get(user.UserID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	cart.ConvertPrices(rate)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"cart": cart}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) addCartItemHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		FurnitureID int64  `json:"furniture_id"`
+		VariantID   *int64 `json:"variant_id"`
+		Quantity    int    `json:"quantity"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.FurnitureID > 0, "furniture_id", "must be provided")
+	if data.ValidateCartQuantity(v, input.Quantity); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Make sure the furniture, or the variant of it, exists before adding
+	// it to the cart.
+	if input.VariantID != nil {
+		_, err = app.repositories.Variants.GetByID(input.FurnitureID, *input.VariantID)
+	} else {
+		_, err = app.repositories.Furniture.GetByID(input.FurnitureID)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("furniture_id", "furniture or variant does not exist")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	rate, ok := app.readExchangeRate(w, r)
+	if !ok {
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	err = app.repositories.Cart.AddItem(user.UserID, input.FurnitureID, input.VariantID, input.Quantity)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	cart, err := app.repositories.Cart.Get(user.UserID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	cart.ConvertPrices(rate)
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"cart": cart}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateCartItemHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Quantity int `json:"quantity"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if data.ValidateCartQuantity(v, input.Quantity); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	rate, ok := app.readExchangeRate(w, r)
+	if !ok {
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	err = app.repositories.Cart.UpdateQuantity(user.UserID, id, input.Quantity)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	cart, err := app.repositories.Cart.Get(user.UserID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	cart.ConvertPrices(rate)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"cart": cart}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteCartItemHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	err = app.repositories.Cart.DeleteItem(user.UserID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "item successfully removed from the cart"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
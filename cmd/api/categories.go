@@ -0,0 +1,222 @@
+package main
+
+import (
+	"errors"
+	"github.com/hayohtee/fumode/internal/data"
+	"github.com/hayohtee/fumode/internal/tax"
+	"github.com/hayohtee/fumode/internal/validator"
+	"net/http"
+)
+
+func (app *application) createCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name        string `json:"name"`
+		Slug        string `json:"slug"`
+		Description string `json:"description"`
+		ParentID    *int64 `json:"parent_id"`
+		TaxClass    string `json:"tax_class"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	category := data.Category{
+		Name:        input.Name,
+		Slug:        input.Slug,
+		Description: input.Description,
+		ParentID:    input.ParentID,
+		TaxClass:    input.TaxClass,
+	}
+
+	if category.TaxClass == "" {
+		category.TaxClass = tax.ClassStandard
+	}
+
+	// Derive the slug from the name if the client did not provide one.
+	if category.Slug == "" {
+		category.Slug = data.Slugify(category.Name)
+	}
+
+	v := validator.New()
+	if data.ValidateCategory(v, category); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.repositories.Categories.Insert(&category)
+	if err != nil {
+		app.categoryWriteErrorResponse(w, r, v, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"category": category}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	category, err := app.repositories.Categories.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"category": category}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	categories, err := app.repositories.Categories.GetAll()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"categories": categories}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	category, err := app.repositories.Categories.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// The fields are pointers so that we can tell a field that was not
+	// provided apart from one that was set to its zero value. RemoveParent
+	// moves the category back to the top level.
+	var input struct {
+		Name         *string `json:"name"`
+		Slug         *string `json:"slug"`
+		Description  *string `json:"description"`
+		ParentID     *int64  `json:"parent_id"`
+		TaxClass     *string `json:"tax_class"`
+		RemoveParent bool    `json:"remove_parent"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		category.Name = *input.Name
+	}
+	if input.Slug != nil {
+		category.Slug = *input.Slug
+	}
+	if input.Description != nil {
+		category.Description = *input.Description
+	}
+	if input.ParentID != nil {
+		category.ParentID = input.ParentID
+	}
+	if input.RemoveParent {
+		category.ParentID = nil
+	}
+	if input.TaxClass != nil {
+		category.TaxClass = *input.TaxClass
+	}
+
+	v := validator.New()
+	v.Check(!(input.RemoveParent && input.ParentID != nil), "parent_id", "must not be provided together with remove_parent")
+	if data.ValidateCategory(v, category); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.repositories.Categories.Update(&category)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.categoryWriteErrorResponse(w, r, v, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"category": category}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.repositories.Categories.Delete(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrCategoryInUse):
+			app.errorResponse(w, r, http.StatusConflict, "the category still has sub categories or furniture and cannot be deleted")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "category successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// categoryWriteErrorResponse sends the appropriate response for the errors
+// returned when inserting or updating a category.
+func (app *application) categoryWriteErrorResponse(w http.ResponseWriter, r *http.Request, v *validator.Validator, err error) {
+	switch {
+	case errors.Is(err, data.ErrDuplicateSlug):
+		v.AddError("slug", "a category with this slug already exists")
+		app.errorResponse(w, r, http.StatusConflict, v.Errors)
+	case errors.Is(err, data.ErrDuplicateName):
+		v.AddError("name", "a category with this name already exists")
+		app.errorResponse(w, r, http.StatusConflict, v.Errors)
+	case errors.Is(err, data.ErrInvalidParentCategory):
+		v.AddError("parent_id", "must reference an existing category")
+		app.failedValidationResponse(w, r, v.Errors)
+	case errors.Is(err, data.ErrCategoryCycle):
+		v.AddError("parent_id", "must not reference one of the category's descendants")
+		app.failedValidationResponse(w, r, v.Errors)
+	default:
+		app.serverErrorResponse(w, r, err)
+	}
+}
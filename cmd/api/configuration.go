@@ -1,5 +1,7 @@
 package main
 
+import "time"
+
 // configuration holds all the configuration settings for the app.
 type configuration struct {
 	// The network port the server is listening on.
@@ -30,6 +32,59 @@ type configuration struct {
 		enabled bool
 	}
 
+	// Configurations for cursor pagination.
+	cursor struct {
+		// The secret used for signing the cursors.
+		secret string
+	}
+
+	// Configurations for stock reservations.
+	reservation struct {
+		// How long the stock of an order is reserved while waiting
+		// for the payment.
+		ttl time.Duration
+		// How often the expired reservations are released.
+		sweepInterval time.Duration
+	}
+
+	// Configurations for stock alerts.
+	alerts struct {
+		// How often the admins are emailed the low stock digest.
+		digestInterval time.Duration
+		// How often the customers waiting for an item are notified
+		// that it is back in stock.
+		restockInterval time.Duration
+	}
+
+	// Configurations for scheduled sales.
+	prices struct {
+		// How often the scheduled sales are started and ended.
+		scheduleInterval time.Duration
+	}
+
+	// Configurations for tax.
+	tax struct {
+		// Whether the prices include the tax, rather than having it
+		// added at checkout.
+		pricesIncludeTax bool
+	}
+
+	// Configurations for delivery slots.
+	delivery struct {
+		// How long before they start the delivery slots can no longer be
+		// booked or rescheduled.
+		slotCutoff time.Duration
+	}
+
+	// Configurations for invoices.
+	invoice struct {
+		// The name, address and tax id of the business issuing the
+		// invoices. The lines of the address are separated by newlines.
+		companyName    string
+		companyAddress string
+		companyTaxID   string
+	}
+
 	// Configurations for SMTP
 	smtp struct {
 		host     string
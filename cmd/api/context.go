@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"net/http"
+)
+
+// contextKey is a custom type for the keys of the request context,
+// so that they cannot collide with keys set by other packages.
+type contextKey string
+
+// userContextKey is the key for the claims of the authenticated user
+// in the request context.
+const userContextKey = contextKey("user")
+
+// contextSetUser returns a copy of the request with the claims of the
+// authenticated user added to its context.
+func (app *application) contextSetUser(r *http.Request, claims userClaims) *http.Request {
+	ctx := context.WithValue(r.Context(), userContextKey, claims)
+	return r.WithContext(ctx)
+}
+
+// contextGetUser retrieve the claims of the authenticated user from the
+// request context. It should only be called by handlers wrapped by the
+// authorize middleware, so a missing value is an unexpected error.
+func (app *application) contextGetUser(r *http.Request) userClaims {
+	claims, ok := r.Context().Value(userContextKey).(userClaims)
+	if !ok {
+		panic("missing user value in request context")
+	}
+	return claims
+}
@@ -0,0 +1,379 @@
+package main
+
+import (
+	"errors"
+	"github.com/hayohtee/fumode/internal/data"
+	"github.com/hayohtee/fumode/internal/money"
+	"github.com/hayohtee/fumode/internal/promotions"
+	"github.com/hayohtee/fumode/internal/validator"
+	"net/http"
+	"time"
+)
+
+func (app *application) createCouponHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Code         string       `json:"code"`
+		Description  string       `json:"description"`
+		DiscountType string       `json:"discount_type"`
+		PercentOff   *float64     `json:"percent_off"`
+		AmountOff    *money.Money `json:"amount_off"`
+		MinSubtotal  money.Money  `json:"min_subtotal"`
+		CategoryIDs  []int64      `json:"category_ids"`
+		FurnitureIDs []int64      `json:"furniture_ids"`
+		StartsAt     *time.Time   `json:"starts_at"`
+		EndsAt       *time.Time   `json:"ends_at"`
+		UsageLimit   *int         `json:"usage_limit"`
+		PerUserLimit *int         `json:"per_user_limit"`
+		Stackable    bool         `json:"stackable"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	coupon := promotions.Coupon{
+		Code:         promotions.NormalizeCode(input.Code),
+		Description:  input.Description,
+		DiscountType: input.DiscountType,
+		PercentOff:   input.PercentOff,
+		AmountOff:    input.AmountOff,
+		MinSubtotal:  input.MinSubtotal,
+		CategoryIDs:  input.CategoryIDs,
+		FurnitureIDs: input.FurnitureIDs,
+		StartsAt:     input.StartsAt,
+		EndsAt:       input.EndsAt,
+		UsageLimit:   input.UsageLimit,
+		PerUserLimit: input.PerUserLimit,
+		Stackable:    input.Stackable,
+	}
+
+	// The restrictions are stored as empty arrays when there are none.
+	if coupon.CategoryIDs == nil {
+		coupon.CategoryIDs = []int64{}
+	}
+	if coupon.FurnitureIDs == nil {
+		coupon.FurnitureIDs = []int64{}
+	}
+
+	v := validator.New()
+	if promotions.ValidateCoupon(v, coupon); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.repositories.Coupons.Insert(&coupon)
+	if err != nil {
+		app.couponWriteErrorResponse(w, r, v, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"coupon": coupon}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showCouponHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	coupon, err := app.repositories.Coupons.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"coupon": coupon}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listCouponsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Code string
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.Code = app.readString(qs, "code", "")
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "-coupon_id")
+	input.Filters.SortSafeList = []string{
+		"coupon_id", "code", "ends_at",
+		"-coupon_id", "-code", "-ends_at",
+	}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	coupons, metadata, err := app.repositories.Coupons.GetAll(input.Code, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"coupons": coupons, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateCouponHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	coupon, err := app.repositories.Coupons.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// The fields are pointers so that we can tell a field that was not
+	// provided apart from one that was set to its zero value. The Remove
+	// fields lift the validity window and usage limits.
+	var input struct {
+		Code               *string      `json:"code"`
+		Description        *string      `json:"description"`
+		DiscountType       *string      `json:"discount_type"`
+		PercentOff         *float64     `json:"percent_off"`
+		AmountOff          *money.Money `json:"amount_off"`
+		MinSubtotal        *money.Money `json:"min_subtotal"`
+		CategoryIDs        []int64      `json:"category_ids"`
+		FurnitureIDs       []int64      `json:"furniture_ids"`
+		StartsAt           *time.Time   `json:"starts_at"`
+		EndsAt             *time.Time   `json:"ends_at"`
+		UsageLimit         *int         `json:"usage_limit"`
+		PerUserLimit       *int         `json:"per_user_limit"`
+		Stackable          *bool        `json:"stackable"`
+		RemoveStartsAt     bool         `json:"remove_starts_at"`
+		RemoveEndsAt       bool         `json:"remove_ends_at"`
+		RemoveUsageLimit   bool         `json:"remove_usage_limit"`
+		RemovePerUserLimit bool         `json:"remove_per_user_limit"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Code != nil {
+		coupon.Code = promotions.NormalizeCode(*input.Code)
+	}
+	if input.Description != nil {
+		coupon.Description = *input.Description
+	}
+	// Switching the discount type replaces the amount taken off, so the
+	// previous one is cleared.
+	if input.DiscountType != nil && *input.DiscountType != coupon.DiscountType {
+		coupon.DiscountType = *input.DiscountType
+		coupon.PercentOff = nil
+		coupon.AmountOff = nil
+	}
+	if input.PercentOff != nil {
+		coupon.PercentOff = input.PercentOff
+	}
+	if input.AmountOff != nil {
+		coupon.AmountOff = input.AmountOff
+	}
+	if input.MinSubtotal != nil {
+		coupon.MinSubtotal = *input.MinSubtotal
+	}
+	if input.CategoryIDs != nil {
+		coupon.CategoryIDs = input.CategoryIDs
+	}
+	if input.FurnitureIDs != nil {
+		coupon.FurnitureIDs = input.FurnitureIDs
+	}
+	if input.StartsAt != nil || input.RemoveStartsAt {
+		coupon.StartsAt = input.StartsAt
+	}
+	if input.EndsAt != nil || input.RemoveEndsAt {
+		coupon.EndsAt = input.EndsAt
+	}
+	if input.UsageLimit != nil || input.RemoveUsageLimit {
+		coupon.UsageLimit = input.UsageLimit
+	}
+	if input.PerUserLimit != nil || input.RemovePerUserLimit {
+		coupon.PerUserLimit = input.PerUserLimit
+	}
+	if input.Stackable != nil {
+		coupon.Stackable = *input.Stackable
+	}
+
+	v := validator.New()
+	v.Check(!(input.RemoveStartsAt && input.StartsAt != nil), "starts_at", "must not be provided together with remove_starts_at")
+	v.Check(!(input.RemoveEndsAt && input.EndsAt != nil), "ends_at", "must not be provided together with remove_ends_at")
+	v.Check(!(input.RemoveUsageLimit && input.UsageLimit != nil), "usage_limit", "must not be provided together with remove_usage_limit")
+	v.Check(!(input.RemovePerUserLimit && input.PerUserLimit != nil), "per_user_limit", "must not be provided together with remove_per_user_limit")
+	if promotions.ValidateCoupon(v, coupon); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.repositories.Coupons.Update(&coupon)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.couponWriteErrorResponse(w, r, v, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"coupon": coupon}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteCouponHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.repositories.Coupons.Delete(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrCouponInUse):
+			app.errorResponse(w, r, http.StatusConflict, "the coupon has been redeemed and cannot be deleted")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "coupon successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) applyCartCouponHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Code string `json:"code"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if v.Check(input.Code != "", "code", "must be provided"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	coupon, err := app.repositories.Coupons.GetByCode(promotions.NormalizeCode(input.Code))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	rate, ok := app.readExchangeRate(w, r)
+	if !ok {
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	cart, err := app.repositories.Coupons.ApplyToCart(user.UserID, coupon.CouponID)
+	if err != nil {
+		var rejection promotions.Rejection
+		switch {
+		case errors.As(err, &rejection):
+			v.AddError("code", rejection.Reason)
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	cart.ConvertPrices(rate)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"cart": cart}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) removeCartCouponHandler(w http.ResponseWriter, r *http.Request) {
+	rate, ok := app.readExchangeRate(w, r)
+	if !ok {
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	err := app.repositories.Coupons.RemoveFromCart(user.UserID, r.PathValue("code"))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	cart, err := app.repositories.Cart.Get(user.UserID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	cart.ConvertPrices(rate)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"cart": cart}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// couponWriteErrorResponse sends the appropriate response for the errors
+// returned when inserting or updating a coupon.
+func (app *application) couponWriteErrorResponse(w http.ResponseWriter, r *http.Request, v *validator.Validator, err error) {
+	switch {
+	case errors.Is(err, data.ErrDuplicateCode):
+		v.AddError("code", "a coupon with this code already exists")
+		app.errorResponse(w, r, http.StatusConflict, v.Errors)
+	default:
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"errors"
+	"github.com/hayohtee/fumode/internal/data"
+	"github.com/hayohtee/fumode/internal/shipping"
+	"github.com/hayohtee/fumode/internal/validator"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// deliverySlotsRange is how far ahead the delivery slots are listed when
+// the end of the range is not requested.
+const deliverySlotsRange = 30 * 24 * time.Hour
+
+func (app *application) createDeliverySlotHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		ZoneID   int64     `json:"zone_id"`
+		StartsAt time.Time `json:"starts_at"`
+		EndsAt   time.Time `json:"ends_at"`
+		Capacity int       `json:"capacity"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	slot := data.DeliverySlot{
+		ZoneID:   input.ZoneID,
+		StartsAt: input.StartsAt,
+		EndsAt:   input.EndsAt,
+		Capacity: input.Capacity,
+	}
+
+	v := validator.New()
+	if data.ValidateDeliverySlot(v, slot); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.repositories.DeliverySlots.Insert(&slot)
+	if err != nil {
+		app.deliverySlotWriteErrorResponse(w, r, v, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"delivery_slot": slot}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listDeliverySlotsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	zoneID := int64(app.readInt(qs, "zone_id", 0, v))
+	from, to := app.readDateRange(qs, v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	slots, err := app.repositories.DeliverySlots.GetAll(zoneID, from, to)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"delivery_slots": slots}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listAvailableDeliverySlotsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	region := shipping.Region{
+		Country: strings.TrimSpace(app.readString(qs, "country", "")),
+		State:   strings.TrimSpace(app.readString(qs, "state", "")),
+	}
+	_, to := app.readDateRange(qs, v)
+
+	v.Check(region.Country != "", "country", "must be provided")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	slots, err := app.repositories.DeliverySlots.GetAvailable(region, app.config.delivery.slotCutoff, to)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"delivery_slots": slots}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateDeliverySlotHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	slot, err := app.repositories.DeliverySlots.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		StartsAt *time.Time `json:"starts_at"`
+		EndsAt   *time.Time `json:"ends_at"`
+		Capacity *int       `json:"capacity"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.StartsAt != nil {
+		slot.StartsAt = *input.StartsAt
+	}
+	if input.EndsAt != nil {
+		slot.EndsAt = *input.EndsAt
+	}
+	if input.Capacity != nil {
+		slot.Capacity = *input.Capacity
+	}
+
+	v := validator.New()
+	if data.ValidateDeliverySlot(v, slot); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.repositories.DeliverySlots.Update(&slot)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.deliverySlotWriteErrorResponse(w, r, v, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"delivery_slot": slot}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteDeliverySlotHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.repositories.DeliverySlots.Delete(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrSlotBooked):
+			app.errorResponse(w, r, http.StatusConflict, "deliveries are booked in the slot and it cannot be deleted")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "delivery slot successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) rescheduleDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	order, ok := app.readUserOrder(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		DeliverySlotID int64 `json:"delivery_slot_id"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if v.Check(input.DeliverySlotID > 0, "delivery_slot_id", "must be provided"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.repositories.DeliverySlots.Reschedule(order.OrderID, input.DeliverySlotID, app.config.delivery.slotCutoff)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrNotReschedulable):
+			app.errorResponse(w, r, http.StatusConflict, "the delivery of the order can no longer be rescheduled")
+		case errors.Is(err, data.ErrSlotUnavailable):
+			v.AddError("delivery_slot_id", "must be an available delivery slot of the shipping address")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	order, err = app.repositories.Orders.GetByID(order.OrderID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"order": order}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readDateRange reads the "from" and "to" dates of the query string. The
+// range starts today, and ends deliverySlotsRange later, when they are not
+// set. The end date is included in the range.
+func (app *application) readDateRange(qs url.Values, v *validator.Validator) (from, to time.Time) {
+	from = time.Now().UTC().Truncate(24 * time.Hour)
+	if date := app.readDate(qs, "from", v); date != nil {
+		from = *date
+	}
+
+	to = from.Add(deliverySlotsRange)
+	if date := app.readDate(qs, "to", v); date != nil {
+		to = date.AddDate(0, 0, 1)
+	}
+
+	v.Check(to.After(from), "to", "must not be before from")
+	return from, to
+}
+
+// deliverySlotWriteErrorResponse sends the appropriate response for the
+// errors returned when inserting or updating a delivery slot.
+func (app *application) deliverySlotWriteErrorResponse(w http.ResponseWriter, r *http.Request, v *validator.Validator, err error) {
+	switch {
+	case errors.Is(err, data.ErrDuplicateSlot):
+		v.AddError("starts_at", "the zone already has a delivery slot starting at this time")
+		app.errorResponse(w, r, http.StatusConflict, v.Errors)
+	case errors.Is(err, data.ErrInvalidZone):
+		v.AddError("zone_id", "must reference an existing shipping zone")
+		app.failedValidationResponse(w, r, v.Errors)
+	case errors.Is(err, data.ErrSlotBooked):
+		v.AddError("capacity", "must not be less than the deliveries booked in the slot")
+		app.failedValidationResponse(w, r, v.Errors)
+	default:
+		app.serverErrorResponse(w, r, err)
+	}
+}
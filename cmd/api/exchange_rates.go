@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/hayohtee/fumode/internal/data"
+	"github.com/hayohtee/fumode/internal/money"
+	"github.com/hayohtee/fumode/internal/validator"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+func (app *application) listExchangeRatesHandler(w http.ResponseWriter, r *http.Request) {
+	rates, err := app.repositories.ExchangeRates.GetAll()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	env := envelope{"base_currency": money.DefaultCurrency, "exchange_rates": rates}
+	err = app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) setExchangeRateHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Rate float64 `json:"rate"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	rate := data.ExchangeRate{
+		Currency: strings.ToUpper(r.PathValue("currency")),
+		Rate:     input.Rate,
+	}
+
+	v := validator.New()
+	if data.ValidateExchangeRate(v, rate); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.repositories.ExchangeRates.Set(&rate)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"exchange_rate": rate}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteExchangeRateHandler(w http.ResponseWriter, r *http.Request) {
+	err := app.repositories.ExchangeRates.Delete(strings.ToUpper(r.PathValue("currency")))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "exchange rate successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// importExchangeRatesHandler sets the exchange rates listed in a CSV body
+// with a "currency,rate" record per line. A header line with these names is
+// skipped. Nothing is imported unless every line is valid, and the errors
+// are reported by line number.
+func (app *application) importExchangeRatesHandler(w http.ResponseWriter, r *http.Request) {
+	maxBytes := 1_048_576
+	reader := csv.NewReader(http.MaxBytesReader(w, r.Body, int64(maxBytes)))
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+
+	v := validator.New()
+	rates := []data.ExchangeRate{}
+	seen := make(map[string]bool)
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				app.badRequestResponse(w, r, fmt.Errorf("body must not be larger than %d bytes", maxBytes))
+				return
+			}
+			app.badRequestResponse(w, r, fmt.Errorf("body contains badly-formed CSV: %w", err))
+			return
+		}
+
+		line, _ := reader.FieldPos(0)
+		key := fmt.Sprintf("line %d", line)
+
+		currency := strings.ToUpper(strings.TrimSpace(record[0]))
+		value := strings.TrimSpace(record[1])
+		if line == 1 && currency == "CURRENCY" && strings.EqualFold(value, "rate") {
+			continue
+		}
+
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			v.AddError(key, "rate must be a valid number")
+			continue
+		}
+
+		exchangeRate := data.ExchangeRate{Currency: currency, Rate: rate}
+
+		lineValidator := validator.New()
+		data.ValidateExchangeRate(lineValidator, exchangeRate)
+		lineValidator.Check(!seen[currency], "currency", "must not be listed more than once")
+		for _, field := range []string{"currency", "rate"} {
+			if message, ok := lineValidator.Errors[field]; ok {
+				v.AddError(key, field+" "+message)
+			}
+		}
+
+		seen[currency] = true
+		rates = append(rates, exchangeRate)
+	}
+
+	v.Check(len(rates) > 0 || len(v.Errors) > 0, "body", "must contain at least one exchange rate")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	imported, err := app.repositories.ExchangeRates.Import(rates)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"imported": imported, "exchange_rates": rates}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readExchangeRate retrieve the exchange rate of the currency the prices
+// are requested in, given by the "currency" query string value or else by
+// the Accept-Currency header. The base currency is used when neither is
+// set. The response is marked as varying with the header. It sends a 422
+// Unprocessable Entity response if the currency is not supported, and
+// reports whether the exchange rate was found.
+func (app *application) readExchangeRate(w http.ResponseWriter, r *http.Request) (data.ExchangeRate, bool) {
+	w.Header().Add("Vary", "Accept-Currency")
+
+	currency := r.URL.Query().Get("currency")
+	if currency == "" {
+		currency = r.Header.Get("Accept-Currency")
+	}
+	if currency == "" {
+		return data.BaseExchangeRate(), true
+	}
+
+	v := validator.New()
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if v.Check(validator.Matches(currency, money.CurrencyRX), "currency", "must be a valid ISO 4217 currency code"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return data.ExchangeRate{}, false
+	}
+
+	rate, err := app.repositories.ExchangeRates.Get(currency)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("currency", "is not supported")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return data.ExchangeRate{}, false
+	}
+	return rate, true
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"errors"
 	"github.com/hayohtee/fumode/internal/data"
+	"github.com/hayohtee/fumode/internal/money"
 	"github.com/hayohtee/fumode/internal/validator"
 	"net/http"
 	"strconv"
@@ -21,24 +23,48 @@ func (app *application) createFurnitureHandler(w http.ResponseWriter, r *http.Re
 	description := r.Form.Get("description")
 	priceStr := r.Form.Get("price")
 	stockStr := r.Form.Get("stock")
-	category := r.Form.Get("category")
+	categoryIDStr := r.Form.Get("category_id")
+	warehouseIDStr := r.Form.Get("warehouse_id")
 
 	v := validator.New()
 	v.Check(name != "", "name", "must be provided")
 	v.Check(description != "", "description", "must be provided")
-	v.Check(category != "", "category", "must be provided")
+	v.Check(categoryIDStr != "", "category_id", "must be provided")
 	v.Check(priceStr != "", "price", "must be provided")
 	v.Check(stockStr != "", "stock", "must be provided")
 
-	price, err := strconv.ParseFloat(priceStr, 64)
+	// The price is parsed exactly, an amount with more decimal places than
+	// the currency is rejected rather than rounded.
+	price, err := money.Parse(priceStr, money.DefaultCurrency)
 	if err != nil {
-		v.AddError("price", "must be a valid number")
+		v.AddError("price", "must be a valid amount with at most 2 decimal places")
+	} else {
+		data.ValidatePrice(v, "price", price)
 	}
 
 	stock, err := strconv.ParseInt(stockStr, 10, 32)
 	if err != nil {
 		v.AddError("stock", "must be a valid number")
 	}
+	v.Check(stock >= 0, "stock", "must not be negative")
+
+	// The initial stock is received in a warehouse, which is only
+	// required when there is stock.
+	var warehouseID int64
+	if stock > 0 {
+		v.Check(warehouseIDStr != "", "warehouse_id", "must be provided when stock is provided")
+		if warehouseIDStr != "" {
+			warehouseID, err = strconv.ParseInt(warehouseIDStr, 10, 64)
+			if err != nil || warehouseID < 1 {
+				v.AddError("warehouse_id", "must be a valid id")
+			}
+		}
+	}
+
+	categoryID, err := strconv.ParseInt(categoryIDStr, 10, 64)
+	if err != nil || categoryID < 1 {
+		v.AddError("category_id", "must be a valid id")
+	}
 
 	_, bannerHeader, err := r.FormFile("banner")
 	if err != nil {
@@ -50,17 +76,66 @@ func (app *application) createFurnitureHandler(w http.ResponseWriter, r *http.Re
 		v.AddError("images", "must contain at least one image")
 	}
 
-	if !v.Valid() {
+	// The attributes are optional, the form values are read with the
+	// same helpers used for the query string.
+	attributes := data.FurnitureAttributes{
+		WidthCm:          app.readFloat(r.Form, "width_cm", v),
+		DepthCm:          app.readFloat(r.Form, "depth_cm", v),
+		HeightCm:         app.readFloat(r.Form, "height_cm", v),
+		SeatHeightCm:     app.readFloat(r.Form, "seat_height_cm", v),
+		WeightKg:         app.readFloat(r.Form, "weight_kg", v),
+		Materials:        app.readCSV(r.Form, "materials", []string{}),
+		AssemblyRequired: app.readBool(r.Form, "assembly_required", false, v),
+		CareInstructions: r.Form.Get("care_instructions"),
+		WarrantyMonths:   app.readInt(r.Form, "warranty_months", 0, v),
+	}
+
+	reorderThreshold := app.readInt(r.Form, "reorder_threshold", 0, v)
+	v.Check(validator.Between(reorderThreshold, 0, 100_000), "reorder_threshold", "must be between 0 and 100000")
+
+	availability := data.Furniture{
+		Availability: app.readString(r.Form, "availability", data.AvailabilityInStock),
+		ReleaseDate:  app.readDate(r.Form, "release_date", v),
+	}
+	if r.Form.Has("lead_time_days") {
+		leadTimeDays := app.readInt(r.Form, "lead_time_days", 0, v)
+		availability.LeadTimeDays = &leadTimeDays
+	}
+	data.ValidateFurnitureAvailability(v, availability)
+
+	if data.ValidateFurnitureAttributes(v, attributes); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
+	category, err := app.repositories.Categories.GetByID(categoryID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("category_id", "must reference an existing category")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if warehouseID > 0 && !app.warehouseExists(w, r, v, warehouseID) {
+		return
+	}
+
 	furniture := data.Furniture{
-		Name:        name,
-		Description: description,
-		Price:       price,
-		Stock:       int(stock),
-		Category:    category,
+		Name:             name,
+		Description:      description,
+		Price:            price,
+		Stock:            int(stock),
+		ReorderThreshold: reorderThreshold,
+		Availability:     availability.Availability,
+		LeadTimeDays:     availability.LeadTimeDays,
+		ReleaseDate:      availability.ReleaseDate,
+		CategoryID:       category.CategoryID,
+		Category:         category.Name,
+		Attributes:       attributes,
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -82,9 +157,15 @@ func (app *application) createFurnitureHandler(w http.ResponseWriter, r *http.Re
 	furniture.BannerURL = bannerUrl
 	furniture.ImageURLs = imageUrls
 
-	err = app.repositories.Furniture.Insert(&furniture)
+	err = app.repositories.Furniture.Insert(&furniture, warehouseID, app.contextGetUser(r).UserID)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrInvalidWarehouse):
+			v.AddError("warehouse_id", "must reference an existing warehouse")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
@@ -93,3 +174,217 @@ func (app *application) createFurnitureHandler(w http.ResponseWriter, r *http.Re
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+func (app *application) showFurnitureHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	rate, ok := app.readExchangeRate(w, r)
+	if !ok {
+		return
+	}
+
+	furniture, err := app.repositories.Furniture.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	variants, err := app.repositories.Variants.GetAllForFurniture(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	furniture.Variants = variants
+	furniture.Options = data.OptionMatrix(variants)
+
+	if furniture.Type == data.ProductTypeBundle {
+		furniture.Components, err = app.repositories.Bundles.GetComponents(id)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	furniture.LowestPrice, err = app.repositories.Prices.GetLowestPrice(id, data.LowestPriceDays)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	furniture.ConvertPrices(rate)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"furniture": furniture}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listFurnitureHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.FurnitureFilters
+		data.Filters
+		Facets bool
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.Name = app.readString(qs, "name", "")
+	input.CategoryID = int64(app.readInt(qs, "category_id", 0, v))
+	input.Material = app.readString(qs, "material", "")
+	input.Color = app.readString(qs, "color", "")
+	input.InStock = app.readBool(qs, "in_stock", false, v)
+	input.Availability = app.readString(qs, "availability", "")
+	input.MinPrice = app.readMoney(qs, "min_price", v)
+	input.MaxPrice = app.readMoney(qs, "max_price", v)
+	input.MinRating = app.readFloat(qs, "min_rating", v)
+	input.MinWidth = app.readFloat(qs, "min_width", v)
+	input.MaxWidth = app.readFloat(qs, "max_width", v)
+	input.MinDepth = app.readFloat(qs, "min_depth", v)
+	input.MaxDepth = app.readFloat(qs, "max_depth", v)
+	input.MinHeight = app.readFloat(qs, "min_height", v)
+	input.MaxHeight = app.readFloat(qs, "max_height", v)
+	input.MaxWeight = app.readFloat(qs, "max_weight", v)
+	input.Facets = app.readBool(qs, "facets", false, v)
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Cursor = app.readCursor(qs, v)
+	input.Filters.Sort = app.readSort(qs, input.Filters.Cursor, "furniture_id")
+	input.Filters.SortSafeList = []string{
+		"furniture_id", "name", "price", "stock", "width_cm", "weight_kg",
+		"-furniture_id", "-name", "-price", "-stock", "-width_cm", "-weight_kg",
+	}
+	input.Filters.CursorSecret = []byte(app.config.cursor.secret)
+
+	data.ValidateFurnitureFilters(v, input.FurnitureFilters)
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	rate, ok := app.readExchangeRate(w, r)
+	if !ok {
+		return
+	}
+
+	furniture, metadata, err := app.repositories.Furniture.GetAll(input.FurnitureFilters, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	for i := range furniture {
+		furniture[i].ConvertPrices(rate)
+	}
+
+	env := envelope{"furniture": furniture, "metadata": metadata}
+
+	// Facet counts are only computed when requested, as they need a
+	// separate aggregate query over every matching furniture.
+	if input.Facets {
+		facets, err := app.repositories.Furniture.GetFacets(input.FurnitureFilters)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		env["facets"] = facets
+	}
+
+	err = app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) setFurnitureAvailabilityHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Availability string  `json:"availability"`
+		LeadTimeDays *int    `json:"lead_time_days"`
+		ReleaseDate  *string `json:"release_date"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	furniture := data.Furniture{
+		FurnitureID:  int(id),
+		Availability: input.Availability,
+		LeadTimeDays: input.LeadTimeDays,
+	}
+
+	v := validator.New()
+	if input.ReleaseDate != nil {
+		releaseDate, err := time.Parse(dateLayout, *input.ReleaseDate)
+		if err != nil {
+			v.AddError("release_date", "must be a date in the YYYY-MM-DD format")
+		} else {
+			furniture.ReleaseDate = &releaseDate
+		}
+	}
+
+	if data.ValidateFurnitureAvailability(v, furniture); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	current, err := app.repositories.Furniture.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// A bundle is only available while its components are in stock.
+	if current.Type == data.ProductTypeBundle && furniture.Availability != data.AvailabilityInStock &&
+		furniture.Availability != data.AvailabilityDiscontinued {
+		v.AddError("availability", "must be in_stock or discontinued for a bundle")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.repositories.Furniture.SetAvailability(&furniture)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	furniture, err = app.repositories.Furniture.GetByID(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"furniture": furniture}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
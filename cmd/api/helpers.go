@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/hayohtee/fumode/internal/data"
+	"github.com/hayohtee/fumode/internal/money"
 	"github.com/hayohtee/fumode/internal/validator"
 	"io"
 	"net/http"
@@ -88,6 +90,17 @@ func (app *application) readIDParam(r *http.Request) (int64, error) {
 	return id, nil
 }
 
+// readInt64Param retrieve the named URL parameter from the current
+// request and convert it to a positive integer (int64). If the operation
+// is not successful, it returns 0 and an error.
+func (app *application) readInt64Param(r *http.Request, name string) (int64, error) {
+	id, err := strconv.ParseInt(r.PathValue(name), 10, 64)
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("invalid %s parameter", name)
+	}
+	return id, nil
+}
+
 // writeJSON is a helper for sending JSON responses. This takes the destination
 // http.ResponseWriter, the HTTP status code to send, the data to encode to JSON,
 // and a header map containing additional HTTP headers we want to include in the response.
@@ -193,6 +206,117 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 	return i
 }
 
+// readFloat is a helper method that reads a string value from the query string
+// and converts it to a float64 before returning a pointer to it. If no matching
+// key could be found, it returns nil. If the value could not be converted to a
+// float64, then we record an error message in the provided validator instance.
+func (app *application) readFloat(qs url.Values, key string, v *validator.Validator) *float64 {
+	s := qs.Get(key)
+	if s == "" {
+		return nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		v.AddError(key, "must be a valid number")
+		return nil
+	}
+	return &f
+}
+
+// readMoney is a helper method that reads a string value from the query string
+// and converts it to an amount of the default currency before returning a pointer
+// to it. If no matching key could be found, it returns nil. If the value could not
+// be converted exactly, then we record an error message in the provided validator
+// instance.
+func (app *application) readMoney(qs url.Values, key string, v *validator.Validator) *money.Money {
+	s := qs.Get(key)
+	if s == "" {
+		return nil
+	}
+	m, err := money.Parse(s, money.DefaultCurrency)
+	if err != nil {
+		v.AddError(key, "must be a valid amount with at most 2 decimal places")
+		return nil
+	}
+	return &m
+}
+
+// dateLayout is the layout of the dates without time read from the
+// requests, such as 2024-03-31.
+const dateLayout = time.DateOnly
+
+// readDate is a helper method that reads a string value from the query string
+// and converts it to a date before returning a pointer to it. If no matching
+// key could be found, it returns nil. If the value could not be converted to a
+// date, then we record an error message in the provided validator instance.
+func (app *application) readDate(qs url.Values, key string, v *validator.Validator) *time.Time {
+	s := qs.Get(key)
+	if s == "" {
+		return nil
+	}
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		v.AddError(key, "must be a date in the YYYY-MM-DD format")
+		return nil
+	}
+	return &t
+}
+
+// readBool is a helper method that reads a string value from the query string
+// and converts it to a bool before returning. If no matching key could be found,
+// it returns the provided default value. If the value could not be converted to
+// a bool, then we record an error message in the provided validator instance.
+func (app *application) readBool(qs url.Values, key string, defaultValue bool, v *validator.Validator) bool {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.AddError(key, "must be a boolean value")
+		return defaultValue
+	}
+	return b
+}
+
+// readCursor is a helper method that reads the pagination cursor from the
+// query string. It returns nil when the "cursor" key is absent (page number
+// mode) and a cursor pointing at the start of the listing when it is empty.
+// If the cursor is not valid, then we record an error message in the provided
+// validator instance.
+func (app *application) readCursor(qs url.Values, v *validator.Validator) *data.Cursor {
+	if !qs.Has("cursor") {
+		return nil
+	}
+
+	s := qs.Get("cursor")
+	if s == "" {
+		return &data.Cursor{}
+	}
+
+	cursor, err := data.DecodeCursor(s, []byte(app.config.cursor.secret))
+	if err != nil {
+		v.AddError("cursor", "must be a valid cursor")
+		return nil
+	}
+	return &cursor
+}
+
+// readSort is a helper method that reads the sort value from the query string.
+// If no matching key could be found, it returns the sort value of the cursor,
+// so that a cursor keeps paginating in the order it was issued for, and the
+// provided default value when there is no cursor to take it from.
+func (app *application) readSort(qs url.Values, cursor *data.Cursor, defaultValue string) string {
+	s := qs.Get("sort")
+	if s != "" {
+		return s
+	}
+	if cursor != nil && cursor.ID != 0 {
+		return cursor.Sort
+	}
+	return defaultValue
+}
+
 // background is a helper method for launching a
 // function in the background and handle panics recovery.
 func (app *application) background(fn func()) {
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"errors"
+	"github.com/hayohtee/fumode/internal/data"
+	"github.com/hayohtee/fumode/internal/validator"
+	"net/http"
+)
+
+func (app *application) adjustStockHandler(w http.ResponseWriter, r *http.Request) {
+	furnitureID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		VariantID   *int64 `json:"variant_id"`
+		WarehouseID int64  `json:"warehouse_id"`
+		Quantity    int    `json:"quantity"`
+		Reason      string `json:"reason"`
+		Reference   string `json:"reference"`
+		Note        string `json:"note"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	actorID := app.contextGetUser(r).UserID
+
+	movement := data.InventoryMovement{
+		FurnitureID: furnitureID,
+		VariantID:   input.VariantID,
+		WarehouseID: input.WarehouseID,
+		Quantity:    input.Quantity,
+		Reason:      input.Reason,
+		ActorID:     &actorID,
+		Reference:   input.Reference,
+		Note:        input.Note,
+	}
+
+	v := validator.New()
+	if data.ValidateAdjustment(v, movement); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if !app.warehouseExists(w, r, v, movement.WarehouseID) {
+		return
+	}
+
+	err = app.repositories.Inventory.Adjust(&movement)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrInsufficientStock):
+			app.errorResponse(w, r, http.StatusConflict, err.Error())
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"movement": movement}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listInventoryMovementsHandler(w http.ResponseWriter, r *http.Request) {
+	furnitureID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		data.InventoryFilters
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.VariantID = int64(app.readInt(qs, "variant_id", 0, v))
+	input.WarehouseID = int64(app.readInt(qs, "warehouse_id", 0, v))
+	input.Reason = app.readString(qs, "reason", "")
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Cursor = app.readCursor(qs, v)
+	input.Filters.Sort = app.readSort(qs, input.Filters.Cursor, "-movement_id")
+	input.Filters.SortSafeList = []string{"movement_id", "-movement_id"}
+	input.Filters.CursorSecret = []byte(app.config.cursor.secret)
+
+	data.ValidateInventoryFilters(v, input.InventoryFilters)
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	_, err = app.repositories.Furniture.GetByID(furnitureID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	movements, metadata, err := app.repositories.Inventory.GetAllForFurniture(furnitureID, input.InventoryFilters, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"movements": movements, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) transferStockHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		FurnitureID     int64  `json:"furniture_id"`
+		VariantID       *int64 `json:"variant_id"`
+		FromWarehouseID int64  `json:"from_warehouse_id"`
+		ToWarehouseID   int64  `json:"to_warehouse_id"`
+		Quantity        int    `json:"quantity"`
+		Reference       string `json:"reference"`
+		Note            string `json:"note"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	actorID := app.contextGetUser(r).UserID
+
+	transfer := data.StockTransfer{
+		FurnitureID:     input.FurnitureID,
+		VariantID:       input.VariantID,
+		FromWarehouseID: input.FromWarehouseID,
+		ToWarehouseID:   input.ToWarehouseID,
+		Quantity:        input.Quantity,
+		ActorID:         &actorID,
+		Reference:       input.Reference,
+		Note:            input.Note,
+	}
+
+	v := validator.New()
+	if data.ValidateStockTransfer(v, transfer); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movements, err := app.repositories.Inventory.Transfer(transfer)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("furniture_id", "furniture or variant does not exist")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrInvalidWarehouse):
+			v.AddError("to_warehouse_id", "must reference an existing warehouse")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrInsufficientStock):
+			app.errorResponse(w, r, http.StatusConflict, err.Error())
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"movements": movements}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listStockDiscrepanciesHandler(w http.ResponseWriter, r *http.Request) {
+	discrepancies, err := app.repositories.Inventory.GetDiscrepancies()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"discrepancies": discrepancies}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) reconcileStockHandler(w http.ResponseWriter, r *http.Request) {
+	movements, err := app.repositories.Inventory.Reconcile(app.contextGetUser(r).UserID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"movements": movements}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hayohtee/fumode/internal/data"
+	"github.com/hayohtee/fumode/internal/invoice"
+	"github.com/hayohtee/fumode/internal/mailer"
+	"net/http"
+	"strings"
+)
+
+func (app *application) showInvoiceHandler(w http.ResponseWriter, r *http.Request) {
+	order, ok := app.readUserOrder(w, r)
+	if !ok {
+		return
+	}
+
+	if order.InvoiceNumber == nil {
+		app.errorResponse(w, r, http.StatusConflict, "the order is invoiced once it is paid")
+		return
+	}
+
+	user, err := app.repositories.Users.GetByID(order.UserID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	attachment := app.renderInvoice(order, user)
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", attachment.Filename))
+	w.WriteHeader(http.StatusOK)
+	w.Write(attachment.Data)
+}
+
+// renderInvoice renders the invoice of a paid order of the user as a PDF
+// document, ready to be attached to an email. The customer is billed at
+// the address the order is shipped to.
+func (app *application) renderInvoice(order data.Order, user data.User) mailer.Attachment {
+	number := invoice.Number(*order.InvoiceNumber)
+
+	inv := invoice.Invoice{
+		Number:  number,
+		OrderID: order.OrderID,
+		Seller: invoice.Party{
+			Name:  app.config.invoice.companyName,
+			TaxID: app.config.invoice.companyTaxID,
+		},
+		Customer: invoice.Party{
+			Name: user.Name,
+			Address: []string{
+				order.Shipment.Address,
+				fmt.Sprintf("%s, %s %s", order.Shipment.City, order.Shipment.State, order.Shipment.ZipCode),
+				order.Shipment.Country,
+				user.Email,
+			},
+		},
+		Discount:     order.Discount,
+		Shipping:     order.Shipment.ShippingCost,
+		Tax:          order.Tax,
+		TaxIncluded:  order.PricesIncludeTax,
+		Total:        order.TotalPrice,
+		Charged:      order.ChargedTotal,
+		ExchangeRate: order.ExchangeRate,
+	}
+
+	if order.InvoicedAt != nil {
+		inv.IssuedAt = *order.InvoicedAt
+	}
+
+	if app.config.invoice.companyAddress != "" {
+		inv.Seller.Address = strings.Split(app.config.invoice.companyAddress, "\n")
+	}
+
+	for _, item := range order.Items {
+		inv.Lines = append(inv.Lines, invoice.Line{
+			Name:       item.Name,
+			Quantity:   item.Quantity,
+			UnitPrice:  item.Price,
+			Discount:   item.Discount,
+			TaxClass:   item.TaxClass,
+			TaxPercent: item.TaxPercent,
+			Tax:        item.Tax,
+		})
+	}
+
+	return mailer.Attachment{
+		Filename:    number + ".pdf",
+		ContentType: "application/pdf",
+		Data:        invoice.Render(inv),
+	}
+}
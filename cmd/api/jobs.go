@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// startJob launches a background goroutine which runs the job once every
+// interval until the server shuts down. The goroutine is tracked by the
+// wait group, like the background tasks, so that a run in progress is
+// completed rather than cut off halfway through. A run that panics is
+// logged and the job carries on with the next one.
+func (app *application) startJob(interval time.Duration, job func()) {
+	app.wg.Add(1)
+	go func() {
+		defer app.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-app.jobs.Done():
+				return
+			case <-ticker.C:
+				app.runJob(job)
+			}
+		}
+	}()
+}
+
+// runJob runs a single run of a job, recovering from any panic.
+func (app *application) runJob(job func()) {
+	defer func() {
+		if err := recover(); err != nil {
+			app.logger.PrintError(fmt.Errorf("%s", err), nil)
+		}
+	}()
+
+	job()
+}
+
+// startReservationSweeper releases the expired stock reservations, and
+// expires the unpaid orders, once every interval.
+func (app *application) startReservationSweeper(interval time.Duration) {
+	app.startJob(interval, func() {
+		released, expired, err := app.repositories.Orders.ReleaseExpiredReservations()
+		if err != nil {
+			app.logger.PrintError(err, nil)
+			return
+		}
+
+		if released > 0 || expired > 0 {
+			app.logger.PrintInfo("released expired stock reservations", map[string]string{
+				"released": fmt.Sprint(released),
+				"expired":  fmt.Sprint(expired),
+			})
+		}
+	})
+}
+
+// startLowStockDigest emails the admins a digest of the items at or below
+// their reorder threshold once every interval. Nothing is sent when no item
+// is low on stock.
+func (app *application) startLowStockDigest(interval time.Duration) {
+	app.startJob(interval, func() {
+		items, err := app.repositories.Alerts.GetLowStock()
+		if err != nil {
+			app.logger.PrintError(err, nil)
+			return
+		}
+
+		if len(items) == 0 {
+			return
+		}
+
+		emails, err := app.repositories.Users.GetEmailsByRole(AdminRole)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+			return
+		}
+
+		for _, email := range emails {
+			err = app.mailer.Send(email, "low_stock_digest.tmpl", items)
+			if err != nil {
+				app.logger.PrintError(err, nil)
+			}
+		}
+	})
+}
+
+// startRestockNotifier emails the customers subscribed to items that are
+// available again once every interval. A subscription is only marked as
+// notified once its email has been sent, so a failed email is retried on
+// the next run.
+func (app *application) startRestockNotifier(interval time.Duration) {
+	app.startJob(interval, func() {
+		notices, err := app.repositories.Alerts.GetRestocked()
+		if err != nil {
+			app.logger.PrintError(err, nil)
+			return
+		}
+
+		for _, notice := range notices {
+			err = app.mailer.Send(notice.Email, "back_in_stock.tmpl", notice)
+			if err != nil {
+				app.logger.PrintError(err, nil)
+				continue
+			}
+
+			err = app.repositories.Alerts.MarkNotified(notice.NotificationID)
+			if err != nil {
+				app.logger.PrintError(err, nil)
+			}
+		}
+	})
+}
+
+// startPriceScheduler starts and ends the scheduled sales once every
+// interval, so that prices switch over without any manual edit.
+func (app *application) startPriceScheduler(interval time.Duration) {
+	app.startJob(interval, func() {
+		started, ended, err := app.repositories.Prices.ApplySchedules()
+		if err != nil {
+			app.logger.PrintError(err, nil)
+			return
+		}
+
+		if started > 0 || ended > 0 {
+			app.logger.PrintInfo("applied scheduled sales", map[string]string{
+				"started": fmt.Sprint(started),
+				"ended":   fmt.Sprint(ended),
+			})
+		}
+	})
+}
@@ -1,11 +1,16 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"flag"
+	"github.com/hayohtee/fumode/internal/carrier"
 	"github.com/hayohtee/fumode/internal/data"
 	"github.com/hayohtee/fumode/internal/mailer"
+	"github.com/hayohtee/fumode/internal/payment"
 	"github.com/hayohtee/fumode/internal/uploader"
 	"os"
+	"time"
 
 	"github.com/hayohtee/fumode/internal/jsonlog"
 	"github.com/joho/godotenv"
@@ -32,6 +37,24 @@ func main() {
 	flag.IntVar(&cfg.limiter.burst, "limiter-burst", 4, "Rate limiter maximum burst")
 	flag.BoolVar(&cfg.limiter.enabled, "limiter-enabled", true, "Enable rate limiter")
 
+	flag.StringVar(&cfg.cursor.secret, "cursor-secret", os.Getenv("CURSOR_SECRET"), "Secret for signing pagination cursors")
+
+	flag.DurationVar(&cfg.reservation.ttl, "reservation-ttl", 15*time.Minute, "How long the stock of an unpaid order is reserved")
+	flag.DurationVar(&cfg.reservation.sweepInterval, "reservation-sweep-interval", time.Minute, "Interval between releases of expired stock reservations")
+
+	flag.DurationVar(&cfg.alerts.digestInterval, "low-stock-digest-interval", 24*time.Hour, "Interval between low stock digests emailed to admins")
+	flag.DurationVar(&cfg.alerts.restockInterval, "restock-notify-interval", 5*time.Minute, "Interval between checks for back in stock notifications")
+
+	flag.DurationVar(&cfg.prices.scheduleInterval, "price-schedule-interval", time.Minute, "Interval between starts and ends of scheduled sales")
+
+	flag.BoolVar(&cfg.tax.pricesIncludeTax, "tax-inclusive-prices", false, "Whether the prices include tax rather than having it added at checkout")
+
+	flag.DurationVar(&cfg.delivery.slotCutoff, "delivery-slot-cutoff", 48*time.Hour, "How long before they start delivery slots can no longer be booked or rescheduled")
+
+	flag.StringVar(&cfg.invoice.companyName, "invoice-company-name", "Fumode", "Name of the business issuing the invoices")
+	flag.StringVar(&cfg.invoice.companyAddress, "invoice-company-address", os.Getenv("INVOICE_COMPANY_ADDRESS"), "Address of the business issuing the invoices, its lines separated by newlines")
+	flag.StringVar(&cfg.invoice.companyTaxID, "invoice-company-tax-id", os.Getenv("INVOICE_COMPANY_TAX_ID"), "Tax id of the business issuing the invoices")
+
 	flag.StringVar(&cfg.smtp.host, "smtp-host", os.Getenv("SMTP_HOST"), "SMTP host")
 	flag.IntVar(&cfg.smtp.port, "smtp-port", 587, "SMTP port")
 	flag.StringVar(&cfg.smtp.username, "smtp-username", os.Getenv("SMTP_USERNAME"), "SMTP username")
@@ -40,6 +63,18 @@ func main() {
 
 	flag.Parse()
 
+	// Without a secret anyone could forge the pagination cursors, so a
+	// random one is generated. The cursors then do not survive a restart
+	// and are not shared between instances.
+	if cfg.cursor.secret == "" {
+		secret := make([]byte, 32)
+		if _, err = rand.Read(secret); err != nil {
+			logger.PrintFatal(err, nil)
+		}
+		cfg.cursor.secret = string(secret)
+		logger.PrintInfo("no cursor secret set, signing the pagination cursors with a random key", nil)
+	}
+
 	db, err := openDB(cfg)
 	if err != nil {
 		logger.PrintFatal(err, nil)
@@ -58,14 +93,26 @@ func main() {
 		logger.PrintFatal(err, nil)
 	}
 
+	// The background jobs run until the server shuts down.
+	jobs, stopJobs := context.WithCancel(context.Background())
+
 	app := application{
 		config:       cfg,
+		jobs:         jobs,
+		stopJobs:     stopJobs,
 		logger:       logger,
 		repositories: data.NewRepositories(db),
 		mailer:       mailer.New(client, cfg.smtp.sender),
 		s3Uploader:   s3Uploader,
+		payments:     payment.FakeProvider{},
+		carrier:      carrier.FakeCarrier{},
 	}
 
+	app.startReservationSweeper(cfg.reservation.sweepInterval)
+	app.startLowStockDigest(cfg.alerts.digestInterval)
+	app.startRestockNotifier(cfg.alerts.restockInterval)
+	app.startPriceScheduler(cfg.prices.scheduleInterval)
+
 	err = app.serve()
 	if err != nil {
 		logger.PrintFatal(err, nil)
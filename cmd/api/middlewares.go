@@ -100,12 +100,12 @@ func (app *application) authorize(role string, next http.HandlerFunc) http.Handl
 		authorizationHeader := r.Header.Get("Authorization")
 		data := strings.Split(authorizationHeader, " ")
 
-		if len(data) != 2 {
+		if len(data) != 2 || data[0] != "Bearer" {
 			w.WriteHeader(http.StatusExpectationFailed)
 			return
 		}
 
-		payload, err := validateJWT(data[0])
+		payload, err := validateJWT(data[1])
 		if err != nil {
 			switch {
 			case errors.Is(err, errInvalidToken):
@@ -123,6 +123,6 @@ func (app *application) authorize(role string, next http.HandlerFunc) http.Handl
 			}
 		}
 
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, app.contextSetUser(r, payload))
 	}
 }
@@ -0,0 +1,412 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hayohtee/fumode/internal/data"
+	"github.com/hayohtee/fumode/internal/money"
+	"github.com/hayohtee/fumode/internal/payment"
+	"github.com/hayohtee/fumode/internal/validator"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func (app *application) checkoutHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Address          string `json:"address"`
+		City             string `json:"city"`
+		State            string `json:"state"`
+		Country          string `json:"country"`
+		ZipCode          string `json:"zip_code"`
+		ShippingMethodID *int64 `json:"shipping_method_id"`
+		DeliverySlotID   *int64 `json:"delivery_slot_id"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	shipment := data.Shipment{
+		Address:          input.Address,
+		City:             input.City,
+		State:            input.State,
+		Country:          input.Country,
+		ZipCode:          input.ZipCode,
+		ShippingMethodID: input.ShippingMethodID,
+		DeliverySlotID:   input.DeliverySlotID,
+	}
+
+	v := validator.New()
+	if data.ValidateShipment(v, shipment); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	rate, ok := app.readExchangeRate(w, r)
+	if !ok {
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	order, err := app.repositories.Orders.Checkout(user.UserID, shipment, rate, app.config.tax.pricesIncludeTax, app.config.reservation.ttl, app.config.delivery.slotCutoff)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEmptyCart):
+			app.errorResponse(w, r, http.StatusUnprocessableEntity, "the cart is empty")
+		case errors.Is(err, data.ErrInvalidShippingMethod):
+			v.AddError("shipping_method_id", "must be one of the shipping methods offered for the cart and the address")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrSlotUnavailable):
+			v.AddError("delivery_slot_id", "must be an available delivery slot of the shipping address")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrInsufficientStock), errors.Is(err, data.ErrInvalidCoupon):
+			app.errorResponse(w, r, http.StatusConflict, err.Error())
+		case errors.Is(err, data.ErrNoWarehouse):
+			app.errorResponse(w, r, http.StatusConflict, "no warehouse is available to fulfil the order")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"order": order}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showOrderHandler(w http.ResponseWriter, r *http.Request) {
+	order, ok := app.readUserOrder(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"order": order}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Cursor = app.readCursor(qs, v)
+	input.Filters.Sort = app.readSort(qs, input.Filters.Cursor, "-order_id")
+	input.Filters.SortSafeList = []string{
+		"order_id", "order_date", "total_price",
+		"-order_id", "-order_date", "-total_price",
+	}
+	input.Filters.CursorSecret = []byte(app.config.cursor.secret)
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	orders, metadata, err := app.repositories.Orders.GetAllForUser(user.UserID, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"orders": orders, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) payOrderHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		PaymentMethod string `json:"payment_method"`
+		Token         string `json:"token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.PaymentMethod != "", "payment_method", "must be provided")
+	v.Check(len(input.PaymentMethod) <= 50, "payment_method", "must not be more than 50 bytes long")
+	v.Check(input.Token != "", "token", "must be provided")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	order, ok := app.readUserOrder(w, r)
+	if !ok {
+		return
+	}
+
+	if order.Status != data.OrderStatusPendingPayment {
+		app.errorResponse(w, r, http.StatusConflict, "the order is not pending payment")
+		return
+	}
+
+	if order.ReservedUntil != nil && !order.ReservedUntil.After(time.Now()) {
+		app.errorResponse(w, r, http.StatusConflict, "the order has expired, please checkout again")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+
+	reference, err := app.payments.Charge(ctx, order.ChargedTotal, input.Token)
+	if err != nil {
+		if !errors.Is(err, payment.ErrDeclined) {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		// The reserved stock is released straight away rather than held
+		// until the reservations expire.
+		err = app.repositories.Orders.FailPayment(order.OrderID, input.PaymentMethod)
+		if err != nil && !errors.Is(err, data.ErrInvalidOrderStatus) {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		app.errorResponse(w, r, http.StatusPaymentRequired, "the payment was declined")
+		return
+	}
+
+	err = app.repositories.Orders.ConfirmPayment(order.OrderID, input.PaymentMethod, reference)
+	if err != nil {
+		// The payment could not be recorded, so the charge that just
+		// succeeded is refunded, even when the request was cancelled. A
+		// refund that fails is logged with the charge reference, to be
+		// reconciled by hand.
+		refundCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+
+		if refundErr := app.payments.Refund(refundCtx, reference, order.ChargedTotal); refundErr != nil {
+			app.logger.PrintError(refundErr, map[string]string{
+				"order_id":  fmt.Sprint(order.OrderID),
+				"reference": reference,
+				"charged":   order.ChargedTotal.String() + " " + order.ChargedTotal.Currency,
+			})
+			app.serverErrorResponse(w, r, refundErr)
+			return
+		}
+
+		switch {
+		case errors.Is(err, data.ErrReservationExpired), errors.Is(err, data.ErrInvalidOrderStatus):
+			app.errorResponse(w, r, http.StatusConflict, "the order has expired, please checkout again")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	order, err = app.repositories.Orders.GetByID(order.OrderID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Launch a goroutine to confirm the order to the customer, with its
+	// invoice attached.
+	app.background(func() {
+		user, err := app.repositories.Users.GetByID(order.UserID)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+			return
+		}
+
+		notice := map[string]any{
+			"UserName": user.Name,
+			"OrderID":  order.OrderID,
+			"Items":    order.Items,
+			"Total":    order.ChargedTotal.String(),
+			"Currency": order.ChargedTotal.Currency,
+		}
+
+		err = app.mailer.Send(user.Email, "order_confirmed.tmpl", notice, app.renderInvoice(order, user))
+		if err != nil {
+			app.logger.PrintError(err, nil)
+		}
+	})
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"order": order}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) cancelOrderHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Reason string `json:"reason"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if data.ValidateCancellationReason(v, input.Reason); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Admins can cancel any order, even once shipped, while customers can
+	// only cancel their own orders before they are shipped.
+	claims := app.contextGetUser(r)
+	override := strings.EqualFold(claims.Role, AdminRole)
+
+	var order data.Order
+	if override {
+		id, err := app.readIDParam(r)
+		if err != nil {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		order, err = app.repositories.Orders.GetByID(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+	} else {
+		var ok bool
+		if order, ok = app.readUserOrder(w, r); !ok {
+			return
+		}
+	}
+
+	refund, err := app.repositories.Orders.Cancel(order.OrderID, claims.UserID, input.Reason, override)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrInvalidOrderStatus):
+			app.errorResponse(w, r, http.StatusConflict, "the order can no longer be cancelled")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// The cancellation is committed, so the refund is made even when the
+	// request was cancelled. A refund that fails leaves the payment refund
+	// failed, and is logged to be reconciled by hand.
+	refunded := false
+	if refund.IsPositive() {
+		refunded = app.refundCancelledOrder(order, refund)
+	}
+
+	order, err = app.repositories.Orders.GetByID(order.OrderID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.background(func() {
+		user, err := app.repositories.Users.GetByID(order.UserID)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+			return
+		}
+
+		notice := map[string]any{
+			"UserName": user.Name,
+			"OrderID":  order.OrderID,
+			"Reason":   input.Reason,
+			"ByAdmin":  order.UserID != claims.UserID,
+			"Refunded": refunded,
+			"Pending":  refund.IsPositive() && !refunded,
+			"Amount":   refund.String(),
+			"Currency": refund.Currency,
+		}
+
+		if err = app.mailer.Send(user.Email, "order_cancelled.tmpl", notice); err != nil {
+			app.logger.PrintError(err, nil)
+		}
+	})
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"order": order}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// refundCancelledOrder refunds the amount to the customer of a cancelled
+// order, and records the result. It reports whether the refund succeeded.
+func (app *application) refundCancelledOrder(order data.Order, amount money.Money) bool {
+	properties := map[string]string{
+		"order_id": fmt.Sprint(order.OrderID),
+		"refund":   amount.String() + " " + amount.Currency,
+	}
+
+	var err error
+	if order.Payment.Reference == nil {
+		err = fmt.Errorf("order %d has no payment reference", order.OrderID)
+	} else {
+		properties["reference"] = *order.Payment.Reference
+
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+
+		err = app.payments.Refund(ctx, *order.Payment.Reference, amount)
+	}
+
+	if err != nil {
+		app.logger.PrintError(err, properties)
+	}
+
+	if recordErr := app.repositories.Orders.RecordCancellationRefund(order.OrderID, err == nil); recordErr != nil {
+		app.logger.PrintError(recordErr, properties)
+	}
+	return err == nil
+}
+
+// readUserOrder retrieve the order given by the "id" URL parameter. It
+// sends a 404 Not Found response if the order does not exist or is not
+// owned by the authenticated user, and reports whether the order was found.
+func (app *application) readUserOrder(w http.ResponseWriter, r *http.Request) (data.Order, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return data.Order{}, false
+	}
+
+	order, err := app.repositories.Orders.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return data.Order{}, false
+	}
+
+	if order.UserID != app.contextGetUser(r).UserID {
+		app.notFoundResponse(w, r)
+		return data.Order{}, false
+	}
+	return order, true
+}
@@ -0,0 +1,476 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hayohtee/fumode/internal/data"
+	"github.com/hayohtee/fumode/internal/money"
+	"github.com/hayohtee/fumode/internal/validator"
+	"maps"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func (app *application) createReturnHandler(w http.ResponseWriter, r *http.Request) {
+	order, ok := app.readUserOrder(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Reason      string `json:"reason"`
+		Description string `json:"description"`
+		Items       []struct {
+			OrderItemID int64 `json:"order_item_id"`
+			Quantity    int   `json:"quantity"`
+		} `json:"items"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ret := data.ReturnRequest{
+		OrderID:     order.OrderID,
+		UserID:      order.UserID,
+		Reason:      input.Reason,
+		Description: strings.TrimSpace(input.Description),
+	}
+	for _, item := range input.Items {
+		ret.Items = append(ret.Items, data.ReturnItem{OrderItemID: item.OrderItemID, Quantity: item.Quantity})
+	}
+
+	v := validator.New()
+	if data.ValidateReturnRequest(v, ret); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.repositories.Returns.Insert(&ret)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInvalidOrderStatus):
+			app.errorResponse(w, r, http.StatusConflict, "only the shipped items of an order can be returned")
+		case errors.Is(err, data.ErrInvalidReturnItems):
+			v.AddError("items", err.Error())
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.sendReturnEmail(ret, "return_requested.tmpl", map[string]any{"Items": ret.Items})
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"return": ret}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listOrderReturnsHandler(w http.ResponseWriter, r *http.Request) {
+	order, ok := app.readUserOrder(w, r)
+	if !ok {
+		return
+	}
+
+	returns, err := app.repositories.Returns.GetAllForOrder(order.OrderID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"returns": returns}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) uploadReturnPhotosHandler(w http.ResponseWriter, r *http.Request) {
+	ret, ok := app.readReturn(w, r)
+	if !ok {
+		return
+	}
+
+	if ret.UserID != app.contextGetUser(r).UserID {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	if ret.Status != data.ReturnStatusRequested {
+		app.errorResponse(w, r, http.StatusConflict, "photos can only be added to a return request that was not reviewed yet")
+		return
+	}
+
+	// Parse the multipart form with a 10 MB max memory limit
+	err := r.ParseMultipartForm(10 << 20)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, "must be a multipart form")
+		return
+	}
+
+	photos := r.MultipartForm.File["photos"]
+
+	v := validator.New()
+	v.Check(len(photos) > 0, "photos", "must contain at least one photo")
+	v.Check(len(ret.PhotoURLs)+len(photos) <= data.MaxReturnPhotos, "photos", fmt.Sprintf("must not be more than %d photos in total", data.MaxReturnPhotos))
+	for _, photo := range photos {
+		v.Check(strings.HasPrefix(photo.Header.Get("Content-Type"), "image/"), "photos", "must be images")
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	urls, err := app.s3Uploader.UploadImages(ctx, photos)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.repositories.Returns.AddPhotos(&ret, urls)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"return": ret}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listReturnsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Status string
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.Status = app.readString(qs, "status", "")
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "created_at")
+	input.Filters.SortSafeList = []string{
+		"return_id", "created_at", "updated_at",
+		"-return_id", "-created_at", "-updated_at",
+	}
+
+	if input.Status != "" {
+		statuses := []string{
+			data.ReturnStatusRequested, data.ReturnStatusApproved, data.ReturnStatusRejected,
+			data.ReturnStatusRefunding, data.ReturnStatusRefunded, data.ReturnStatusRefundFailed,
+		}
+		v.Check(validator.PermittedValue(input.Status, statuses...), "status", "invalid status")
+	}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	returns, metadata, err := app.repositories.Returns.GetAll(input.Status, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"returns": returns, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showReturnHandler(w http.ResponseWriter, r *http.Request) {
+	ret, ok := app.readReturn(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"return": ret}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) approveReturnHandler(w http.ResponseWriter, r *http.Request) {
+	app.reviewReturn(w, r, data.ReturnStatusApproved)
+}
+
+func (app *application) rejectReturnHandler(w http.ResponseWriter, r *http.Request) {
+	app.reviewReturn(w, r, data.ReturnStatusRejected)
+}
+
+func (app *application) refundReturnHandler(w http.ResponseWriter, r *http.Request) {
+	ret, ok := app.readReturn(w, r)
+	if !ok {
+		return
+	}
+
+	// The disposition of every returned item, and the amount to refund,
+	// which is what was paid for the items when it is not set.
+	var input struct {
+		WarehouseID int64 `json:"warehouse_id"`
+		Items       []struct {
+			OrderItemID int64  `json:"order_item_id"`
+			Disposition string `json:"disposition"`
+		} `json:"items"`
+		Amount *money.Money `json:"amount"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if ret.Status != data.ReturnStatusApproved {
+		app.errorResponse(w, r, http.StatusConflict, "only approved returns can be refunded")
+		return
+	}
+
+	order, err := app.repositories.Orders.GetByID(ret.OrderID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.WarehouseID > 0, "warehouse_id", "must be provided")
+
+	dispositions := make(map[int64]string)
+	for _, item := range input.Items {
+		v.Check(validator.PermittedValue(item.Disposition, data.DispositionRestock, data.DispositionWriteOff), "items", "must have a disposition of restock or write_off")
+		v.Check(dispositions[item.OrderItemID] == "", "items", "must not repeat an order item")
+		dispositions[item.OrderItemID] = item.Disposition
+	}
+
+	orderItems := make(map[int64]data.OrderItem)
+	for _, item := range order.Items {
+		orderItems[item.OrderItemID] = item
+	}
+
+	refundable := money.Zero(money.DefaultCurrency)
+	for i, item := range ret.Items {
+		disposition, ok := dispositions[item.OrderItemID]
+		v.Check(ok, "items", "must give the disposition of every returned item")
+		ret.Items[i].Disposition = &disposition
+		delete(dispositions, item.OrderItemID)
+
+		refundable = refundable.Add(order.RefundableAmount(orderItems[item.OrderItemID], item.Quantity))
+	}
+	v.Check(len(dispositions) == 0, "items", "must only reference the returned items")
+
+	// The earlier refunds of the order are taken off what can be refunded.
+	refundable = refundable.Min(order.Payment.Amount.Sub(order.Payment.Refunded))
+
+	ret.RefundAmount = refundable
+	if input.Amount != nil {
+		ret.RefundAmount = *input.Amount
+	}
+	data.ValidateMoney(v, "amount", ret.RefundAmount)
+	if v.Valid() {
+		v.Check(!ret.RefundAmount.IsNegative(), "amount", "must not be negative")
+		v.Check(ret.RefundAmount.Cmp(refundable) <= 0, "amount", "must not be more than "+refundable.String())
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if !app.warehouseExists(w, r, v, input.WarehouseID) {
+		return
+	}
+
+	// The refund is made in the currency the order was charged in.
+	rate := data.ExchangeRate{Currency: order.Currency, Rate: order.ExchangeRate}
+	charged := rate.Convert(ret.RefundAmount)
+
+	// The return is claimed before the customer is refunded, so that two
+	// admins refunding it at once cannot both reach the payment provider.
+	err = app.repositories.Returns.Receive(&ret, input.WarehouseID, app.contextGetUser(r).UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrInvalidWarehouse):
+			v.AddError("warehouse_id", "must reference an existing warehouse")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// The return is received, so the refund is made even when the request
+	// was cancelled. A refund that fails leaves the return refund failed,
+	// and is logged to be reconciled by hand.
+	if app.refundReturn(&ret, order, charged) {
+		app.sendReturnEmail(ret, "return_refunded.tmpl", map[string]any{
+			"Amount":   charged.String(),
+			"Currency": charged.Currency,
+		})
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"return": ret}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// refundReturn refunds the amount of a return that is refunding through
+// the payment provider, in the currency the order was charged in, and
+// records the result. Nothing is sent to the provider when the amount is
+// zero. It reports whether the return was refunded.
+func (app *application) refundReturn(ret *data.ReturnRequest, order data.Order, amount money.Money) bool {
+	properties := map[string]string{
+		"return_id": fmt.Sprint(ret.ReturnID),
+		"refund":    amount.String() + " " + amount.Currency,
+	}
+
+	var err error
+	if amount.IsPositive() {
+		if order.Payment.Reference == nil {
+			err = fmt.Errorf("order %d has no payment reference", order.OrderID)
+		} else {
+			properties["reference"] = *order.Payment.Reference
+
+			ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+			defer cancel()
+
+			err = app.payments.Refund(ctx, *order.Payment.Reference, amount)
+		}
+	}
+
+	if err != nil {
+		app.logger.PrintError(err, properties)
+	}
+
+	if recordErr := app.repositories.Returns.RecordRefund(ret, err == nil); recordErr != nil {
+		app.logger.PrintError(recordErr, properties)
+		return false
+	}
+	return err == nil
+}
+
+// reviewReturn approves or rejects the return request given by the "id"
+// URL parameter, with the note of the admin, which is required to reject
+// it. The customer is emailed the decision.
+func (app *application) reviewReturn(w http.ResponseWriter, r *http.Request, status string) {
+	ret, ok := app.readReturn(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Note string `json:"note"`
+	}
+
+	if r.ContentLength != 0 {
+		err := app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	if ret.Status != data.ReturnStatusRequested {
+		app.errorResponse(w, r, http.StatusConflict, "the return request was already reviewed")
+		return
+	}
+
+	ret.Status = status
+	ret.ResolutionNote = strings.TrimSpace(input.Note)
+
+	v := validator.New()
+	v.Check(status != data.ReturnStatusRejected || ret.ResolutionNote != "", "note", "must be provided to reject a return")
+	v.Check(len(ret.ResolutionNote) <= 2000, "note", "must not be more than 2000 bytes long")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err := app.repositories.Returns.Review(&ret)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.sendReturnEmail(ret, "return_reviewed.tmpl", map[string]any{
+		"Approved": status == data.ReturnStatusApproved,
+		"Note":     ret.ResolutionNote,
+	})
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"return": ret}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readReturn retrieve the return request given by the "id" URL parameter.
+// It sends a 404 Not Found response if the request does not exist, and
+// reports whether it was found.
+func (app *application) readReturn(w http.ResponseWriter, r *http.Request) (data.ReturnRequest, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return data.ReturnRequest{}, false
+	}
+
+	ret, err := app.repositories.Returns.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return data.ReturnRequest{}, false
+	}
+	return ret, true
+}
+
+// sendReturnEmail launches a goroutine to email the customer of a return
+// request with the template. The data of the template holds the name of
+// the customer and the ids of the return and its order, alongside extra.
+func (app *application) sendReturnEmail(ret data.ReturnRequest, template string, extra map[string]any) {
+	app.background(func() {
+		user, err := app.repositories.Users.GetByID(ret.UserID)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+			return
+		}
+
+		notice := map[string]any{
+			"UserName": user.Name,
+			"ReturnID": ret.ReturnID,
+			"OrderID":  ret.OrderID,
+		}
+		maps.Copy(notice, extra)
+
+		if err = app.mailer.Send(user.Email, template, notice); err != nil {
+			app.logger.PrintError(err, nil)
+		}
+	})
+}
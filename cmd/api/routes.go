@@ -11,7 +11,109 @@ func (app *application) routes() http.Handler {
 	mux.HandleFunc("POST /v1/admins", app.registerAdminHandler)
 	mux.HandleFunc("POST /v1/admins/login", app.loginUserHandler)
 
-	mux.HandleFunc("POST /v1/furniture", app.createFurnitureHandler)
+	mux.HandleFunc("GET /v1/furniture", app.listFurnitureHandler)
+	mux.HandleFunc("POST /v1/furniture", app.authorize(AdminRole, app.createFurnitureHandler))
+	mux.HandleFunc("GET /v1/furniture/{id}", app.showFurnitureHandler)
+	mux.HandleFunc("PUT /v1/furniture/{id}/availability", app.authorize(AdminRole, app.setFurnitureAvailabilityHandler))
+	mux.HandleFunc("PUT /v1/furniture/{id}/bundle", app.authorize(AdminRole, app.setBundleHandler))
+	mux.HandleFunc("PUT /v1/furniture/{id}/price", app.authorize(AdminRole, app.setFurniturePriceHandler))
+	mux.HandleFunc("GET /v1/furniture/{id}/price-history", app.listPriceHistoryHandler)
+	mux.HandleFunc("GET /v1/furniture/{id}/price-schedules", app.authorize(AdminRole, app.listPriceSchedulesHandler))
+	mux.HandleFunc("POST /v1/furniture/{id}/price-schedules", app.authorize(AdminRole, app.createPriceScheduleHandler))
+	mux.HandleFunc("DELETE /v1/furniture/{id}/price-schedules/{schedule_id}", app.authorize(AdminRole, app.cancelPriceScheduleHandler))
+	mux.HandleFunc("POST /v1/furniture/{id}/variants", app.authorize(AdminRole, app.createVariantHandler))
+	mux.HandleFunc("PATCH /v1/furniture/{id}/variants/{variant_id}", app.authorize(AdminRole, app.updateVariantHandler))
+	mux.HandleFunc("DELETE /v1/furniture/{id}/variants/{variant_id}", app.authorize(AdminRole, app.deleteVariantHandler))
+
+	mux.HandleFunc("GET /v1/furniture/{id}/inventory", app.authorize(AdminRole, app.listInventoryMovementsHandler))
+	mux.HandleFunc("POST /v1/furniture/{id}/inventory/adjustments", app.authorize(AdminRole, app.adjustStockHandler))
+	mux.HandleFunc("POST /v1/inventory/transfers", app.authorize(AdminRole, app.transferStockHandler))
+	mux.HandleFunc("GET /v1/inventory/discrepancies", app.authorize(AdminRole, app.listStockDiscrepanciesHandler))
+	mux.HandleFunc("POST /v1/inventory/reconcile", app.authorize(AdminRole, app.reconcileStockHandler))
+	mux.HandleFunc("GET /v1/inventory/low-stock", app.authorize(AdminRole, app.listLowStockHandler))
+	mux.HandleFunc("PUT /v1/furniture/{id}/reorder-threshold", app.authorize(AdminRole, app.setReorderThresholdHandler))
+
+	mux.HandleFunc("POST /v1/furniture/{id}/stock-notifications", app.authorize(CustomerRole, app.subscribeStockNotificationHandler))
+	mux.HandleFunc("DELETE /v1/stock-notifications/{id}", app.authorize(CustomerRole, app.unsubscribeStockNotificationHandler))
+
+	mux.HandleFunc("GET /v1/warehouses", app.authorize(AdminRole, app.listWarehousesHandler))
+	mux.HandleFunc("POST /v1/warehouses", app.authorize(AdminRole, app.createWarehouseHandler))
+	mux.HandleFunc("GET /v1/warehouses/{id}", app.authorize(AdminRole, app.showWarehouseHandler))
+	mux.HandleFunc("PATCH /v1/warehouses/{id}", app.authorize(AdminRole, app.updateWarehouseHandler))
+	mux.HandleFunc("GET /v1/warehouses/{id}/stock", app.authorize(AdminRole, app.listWarehouseStockHandler))
+
+	mux.HandleFunc("GET /v1/search", app.searchHandler)
+	mux.HandleFunc("GET /v1/search/suggest", app.suggestHandler)
+	mux.HandleFunc("GET /v1/search/queries", app.authorize(AdminRole, app.listSearchQueriesHandler))
+
+	mux.HandleFunc("GET /v1/categories", app.listCategoriesHandler)
+	mux.HandleFunc("POST /v1/categories", app.authorize(AdminRole, app.createCategoryHandler))
+	mux.HandleFunc("GET /v1/categories/{id}", app.showCategoryHandler)
+	mux.HandleFunc("PATCH /v1/categories/{id}", app.authorize(AdminRole, app.updateCategoryHandler))
+	mux.HandleFunc("DELETE /v1/categories/{id}", app.authorize(AdminRole, app.deleteCategoryHandler))
+
+	mux.HandleFunc("GET /v1/cart", app.authorize(CustomerRole, app.showCartHandler))
+	mux.HandleFunc("POST /v1/cart/items", app.authorize(CustomerRole, app.addCartItemHandler))
+	mux.HandleFunc("PATCH /v1/cart/items/{id}", app.authorize(CustomerRole, app.updateCartItemHandler))
+	mux.HandleFunc("DELETE /v1/cart/items/{id}", app.authorize(CustomerRole, app.deleteCartItemHandler))
+	mux.HandleFunc("POST /v1/cart/coupon", app.authorize(CustomerRole, app.applyCartCouponHandler))
+	mux.HandleFunc("DELETE /v1/cart/coupon/{code}", app.authorize(CustomerRole, app.removeCartCouponHandler))
+	mux.HandleFunc("POST /v1/cart/shipping-quote", app.authorize(CustomerRole, app.shippingQuoteHandler))
+
+	mux.HandleFunc("GET /v1/wishlist", app.authorize(CustomerRole, app.showWishlistHandler))
+	mux.HandleFunc("POST /v1/wishlist/items", app.authorize(CustomerRole, app.addWishlistItemHandler))
+	mux.HandleFunc("DELETE /v1/wishlist/items/{id}", app.authorize(CustomerRole, app.deleteWishlistItemHandler))
+
+	mux.HandleFunc("GET /v1/coupons", app.authorize(AdminRole, app.listCouponsHandler))
+	mux.HandleFunc("POST /v1/coupons", app.authorize(AdminRole, app.createCouponHandler))
+	mux.HandleFunc("GET /v1/coupons/{id}", app.authorize(AdminRole, app.showCouponHandler))
+	mux.HandleFunc("PATCH /v1/coupons/{id}", app.authorize(AdminRole, app.updateCouponHandler))
+	mux.HandleFunc("DELETE /v1/coupons/{id}", app.authorize(AdminRole, app.deleteCouponHandler))
+
+	mux.HandleFunc("GET /v1/exchange-rates", app.listExchangeRatesHandler)
+	mux.HandleFunc("POST /v1/exchange-rates/import", app.authorize(AdminRole, app.importExchangeRatesHandler))
+	mux.HandleFunc("PUT /v1/exchange-rates/{currency}", app.authorize(AdminRole, app.setExchangeRateHandler))
+	mux.HandleFunc("DELETE /v1/exchange-rates/{currency}", app.authorize(AdminRole, app.deleteExchangeRateHandler))
+
+	mux.HandleFunc("GET /v1/tax-rates", app.authorize(AdminRole, app.listTaxRatesHandler))
+	mux.HandleFunc("POST /v1/tax-rates", app.authorize(AdminRole, app.createTaxRateHandler))
+	mux.HandleFunc("PATCH /v1/tax-rates/{id}", app.authorize(AdminRole, app.updateTaxRateHandler))
+	mux.HandleFunc("DELETE /v1/tax-rates/{id}", app.authorize(AdminRole, app.deleteTaxRateHandler))
+
+	mux.HandleFunc("GET /v1/shipping-zones", app.authorize(AdminRole, app.listShippingZonesHandler))
+	mux.HandleFunc("POST /v1/shipping-zones", app.authorize(AdminRole, app.createShippingZoneHandler))
+	mux.HandleFunc("PATCH /v1/shipping-zones/{id}", app.authorize(AdminRole, app.updateShippingZoneHandler))
+	mux.HandleFunc("DELETE /v1/shipping-zones/{id}", app.authorize(AdminRole, app.deleteShippingZoneHandler))
+
+	mux.HandleFunc("GET /v1/shipping-methods", app.authorize(AdminRole, app.listShippingMethodsHandler))
+	mux.HandleFunc("POST /v1/shipping-methods", app.authorize(AdminRole, app.createShippingMethodHandler))
+	mux.HandleFunc("PATCH /v1/shipping-methods/{id}", app.authorize(AdminRole, app.updateShippingMethodHandler))
+	mux.HandleFunc("DELETE /v1/shipping-methods/{id}", app.authorize(AdminRole, app.deleteShippingMethodHandler))
+
+	mux.HandleFunc("GET /v1/delivery-slots", app.authorize(AdminRole, app.listDeliverySlotsHandler))
+	mux.HandleFunc("POST /v1/delivery-slots", app.authorize(AdminRole, app.createDeliverySlotHandler))
+	mux.HandleFunc("GET /v1/delivery-slots/available", app.authorize(CustomerRole, app.listAvailableDeliverySlotsHandler))
+	mux.HandleFunc("PATCH /v1/delivery-slots/{id}", app.authorize(AdminRole, app.updateDeliverySlotHandler))
+	mux.HandleFunc("DELETE /v1/delivery-slots/{id}", app.authorize(AdminRole, app.deleteDeliverySlotHandler))
+
+	mux.HandleFunc("POST /v1/checkout", app.authorize(CustomerRole, app.checkoutHandler))
+	mux.HandleFunc("GET /v1/orders", app.authorize(CustomerRole, app.listOrdersHandler))
+	mux.HandleFunc("GET /v1/orders/{id}", app.authorize(CustomerRole, app.showOrderHandler))
+	mux.HandleFunc("POST /v1/orders/{id}/pay", app.authorize(CustomerRole, app.payOrderHandler))
+	mux.HandleFunc("POST /v1/orders/{id}/cancel", app.authorize(CustomerRole, app.cancelOrderHandler))
+	mux.HandleFunc("GET /v1/orders/{id}/invoice.pdf", app.authorize(CustomerRole, app.showInvoiceHandler))
+	mux.HandleFunc("PUT /v1/orders/{id}/delivery-slot", app.authorize(CustomerRole, app.rescheduleDeliveryHandler))
+	mux.HandleFunc("GET /v1/orders/{id}/tracking", app.authorize(CustomerRole, app.trackOrderHandler))
+	mux.HandleFunc("POST /v1/orders/{id}/ship", app.authorize(AdminRole, app.shipOrderHandler))
+	mux.HandleFunc("GET /v1/orders/{id}/returns", app.authorize(CustomerRole, app.listOrderReturnsHandler))
+	mux.HandleFunc("POST /v1/orders/{id}/returns", app.authorize(CustomerRole, app.createReturnHandler))
+
+	mux.HandleFunc("GET /v1/returns", app.authorize(AdminRole, app.listReturnsHandler))
+	mux.HandleFunc("GET /v1/returns/{id}", app.authorize(AdminRole, app.showReturnHandler))
+	mux.HandleFunc("POST /v1/returns/{id}/photos", app.authorize(CustomerRole, app.uploadReturnPhotosHandler))
+	mux.HandleFunc("POST /v1/returns/{id}/approve", app.authorize(AdminRole, app.approveReturnHandler))
+	mux.HandleFunc("POST /v1/returns/{id}/reject", app.authorize(AdminRole, app.rejectReturnHandler))
+	mux.HandleFunc("POST /v1/returns/{id}/refund", app.authorize(AdminRole, app.refundReturnHandler))
 
 	return mux
 }
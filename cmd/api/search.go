@@ -0,0 +1,118 @@
+package main
+
+import (
+	"github.com/hayohtee/fumode/internal/data"
+	"github.com/hayohtee/fumode/internal/validator"
+	"net/http"
+)
+
+func (app *application) searchHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Query string
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.Query = app.readString(qs, "q", "")
+
+	// Search results are always ordered by relevance.
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = "relevance"
+	input.Filters.SortSafeList = []string{"relevance"}
+
+	data.ValidateSearchQuery(v, input.Query)
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	rate, ok := app.readExchangeRate(w, r)
+	if !ok {
+		return
+	}
+
+	results, metadata, err := app.repositories.Search.Search(input.Query, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	for i := range results {
+		results[i].Furniture.ConvertPrices(rate)
+	}
+
+	// Record the query and its result count in the background, so that the
+	// admins can see the searches which returned nothing. Only the first
+	// page is recorded, so that paging through the results counts as a
+	// single search.
+	if input.Filters.Page == 1 {
+		app.background(func() {
+			err := app.repositories.Search.RecordQuery(input.Query, metadata.TotalRecords)
+			if err != nil {
+				app.logError(r, err)
+			}
+		})
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"results": results, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) suggestHandler(w http.ResponseWriter, r *http.Request) {
+	query := app.readString(r.URL.Query(), "q", "")
+
+	v := validator.New()
+	if data.ValidateSuggestQuery(v, query); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	suggestions, err := app.repositories.Search.Suggest(query)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"suggestions": suggestions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listSearchQueriesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		ZeroResults bool
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.ZeroResults = app.readBool(qs, "zero_results", false, v)
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "-search_count")
+	input.Filters.SortSafeList = []string{"search_count", "last_searched_at", "-search_count", "-last_searched_at"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	queries, metadata, err := app.repositories.Search.GetAllQueries(input.ZeroResults, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"queries": queries, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -56,7 +56,9 @@ func (app *application) serve() error {
 			"addr": server.Addr,
 		})
 
-		// Call Wait() to block until the WaitGroup counter is 0
+		// Stop the background jobs, which finish the run in progress, and
+		// call Wait() to block until the WaitGroup counter is 0
+		app.stopJobs()
 		app.wg.Wait()
 		shutdownError <- nil
 	}()
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hayohtee/fumode/internal/carrier"
+	"github.com/hayohtee/fumode/internal/data"
+	"github.com/hayohtee/fumode/internal/validator"
+	"net/http"
+)
+
+func (app *application) shipOrderHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	order, err := app.repositories.Orders.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// The items in the parcel, which are every item left to ship when the
+	// request has no body.
+	var input struct {
+		Items []struct {
+			OrderItemID int64 `json:"order_item_id"`
+			Quantity    int   `json:"quantity"`
+		} `json:"items"`
+	}
+
+	if r.ContentLength != 0 {
+		err = app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	switch order.Status {
+	case data.OrderStatusPaid, data.OrderStatusPartiallyShipped, data.OrderStatusPartiallyDelivered:
+	default:
+		app.errorResponse(w, r, http.StatusConflict, "only paid orders with items left to ship can be shipped")
+		return
+	}
+
+	unshipped := make(map[int64]data.OrderItem)
+	for _, item := range order.Items {
+		if item.Quantity > item.ShippedQuantity {
+			unshipped[item.OrderItemID] = item
+		}
+	}
+
+	var items []data.ShipmentItem
+	if input.Items == nil {
+		for _, item := range order.Items {
+			if _, ok := unshipped[item.OrderItemID]; ok {
+				items = append(items, shipmentItem(item, item.Quantity-item.ShippedQuantity))
+			}
+		}
+	}
+
+	v := validator.New()
+	seen := make(map[int64]bool)
+	for i, in := range input.Items {
+		key := fmt.Sprintf("items[%d]", i)
+		item, ok := unshipped[in.OrderItemID]
+
+		v.Check(!seen[in.OrderItemID], key, "must not repeat an order item")
+		v.Check(ok, key, "must reference an item of the order left to ship")
+		v.Check(in.Quantity > 0, key, "must have a quantity greater than zero")
+		v.Check(!ok || in.Quantity <= item.Quantity-item.ShippedQuantity, key, "must not exceed the quantity left to ship")
+
+		seen[in.OrderItemID] = true
+		items = append(items, shipmentItem(item, in.Quantity))
+	}
+	v.Check(len(items) > 0, "items", "must contain at least one item")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	parcel := carrier.Parcel{
+		Reference: fmt.Sprintf("order:%d", order.OrderID),
+		Address:   order.Shipment.Address,
+		City:      order.Shipment.City,
+		State:     order.Shipment.State,
+		Country:   order.Shipment.Country,
+		ZipCode:   order.Shipment.ZipCode,
+	}
+
+	label, err := app.carrier.CreateLabel(r.Context(), parcel)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.repositories.Shipments.ShipItems(order.OrderID, app.contextGetUser(r).UserID, items, label)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrInvalidOrderStatus):
+			app.errorResponse(w, r, http.StatusConflict, "only paid orders with items left to ship can be shipped")
+		case errors.Is(err, data.ErrInsufficientStock), errors.Is(err, data.ErrInvalidWarehouse):
+			app.errorResponse(w, r, http.StatusConflict, err.Error())
+		case errors.Is(err, data.ErrInvalidShipmentItems):
+			v.AddError("items", err.Error())
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	order, err = app.repositories.Orders.GetByID(order.OrderID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Launch a goroutine to let the customer know the parcel is on its way.
+	app.background(func() {
+		user, err := app.repositories.Users.GetByID(order.UserID)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+			return
+		}
+
+		notice := map[string]any{
+			"UserName":       user.Name,
+			"OrderID":        order.OrderID,
+			"Partial":        order.Status != data.OrderStatusShipped && order.Status != data.OrderStatusDelivered,
+			"Items":          items,
+			"Carrier":        label.Carrier,
+			"TrackingNumber": label.TrackingNumber,
+		}
+		if err = app.mailer.Send(user.Email, "order_shipped.tmpl", notice); err != nil {
+			app.logger.PrintError(err, nil)
+		}
+	})
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"order": order}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) trackOrderHandler(w http.ResponseWriter, r *http.Request) {
+	order, ok := app.readUserOrder(w, r)
+	if !ok {
+		return
+	}
+
+	// Bring the timelines of the parcels on their way up to date with the
+	// carrier. The recorded timelines are still returned when the carrier
+	// cannot be reached.
+	refreshed := false
+	for _, shipment := range order.Shipments {
+		if shipment.Status != data.ShipmentStatusShipped || shipment.TrackingNumber == nil ||
+			shipment.Carrier == nil || *shipment.Carrier != app.carrier.Name() {
+			continue
+		}
+
+		events, err := app.carrier.TrackingEvents(r.Context(), *shipment.TrackingNumber)
+		if err == nil {
+			err = app.repositories.Shipments.RecordEvents(order.OrderID, shipment.ShipmentID, events)
+		}
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{
+				"order_id":    fmt.Sprint(order.OrderID),
+				"shipment_id": fmt.Sprint(shipment.ShipmentID),
+			})
+		}
+		refreshed = true
+	}
+
+	if refreshed {
+		var err error
+		order, err = app.repositories.Orders.GetByID(order.OrderID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	events, err := app.repositories.Shipments.GetEvents(order.OrderID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	for i, shipment := range order.Shipments {
+		order.Shipments[i].Events = events[shipment.ShipmentID]
+	}
+
+	tracking := data.Tracking{
+		OrderID:   order.OrderID,
+		Status:    order.Status,
+		Shipments: order.Shipments,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"tracking": tracking}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// shipmentItem returns the quantity of an order item shipped in a parcel.
+func shipmentItem(item data.OrderItem, quantity int) data.ShipmentItem {
+	return data.ShipmentItem{
+		OrderItemID: item.OrderItemID,
+		FurnitureID: item.FurnitureID,
+		VariantID:   item.VariantID,
+		Name:        item.Name,
+		Quantity:    quantity,
+	}
+}
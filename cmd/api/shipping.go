@@ -0,0 +1,415 @@
+package main
+
+import (
+	"errors"
+	"github.com/hayohtee/fumode/internal/data"
+	"github.com/hayohtee/fumode/internal/money"
+	"github.com/hayohtee/fumode/internal/shipping"
+	"github.com/hayohtee/fumode/internal/validator"
+	"net/http"
+	"strings"
+)
+
+func (app *application) createShippingZoneHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Code    string            `json:"code"`
+		Name    string            `json:"name"`
+		Regions []shipping.Region `json:"regions"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	zone := shipping.Zone{
+		Code:    input.Code,
+		Name:    input.Name,
+		Regions: trimRegions(input.Regions),
+	}
+
+	v := validator.New()
+	if shipping.ValidateZone(v, zone); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.repositories.Shipping.InsertZone(&zone)
+	if err != nil {
+		app.shippingWriteErrorResponse(w, r, v, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"shipping_zone": zone}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listShippingZonesHandler(w http.ResponseWriter, r *http.Request) {
+	zones, err := app.repositories.Shipping.GetZones()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"shipping_zones": zones}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateShippingZoneHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	zone, err := app.repositories.Shipping.GetZone(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Regions replaces every region of the zone when it is provided.
+	var input struct {
+		Code    *string            `json:"code"`
+		Name    *string            `json:"name"`
+		Regions *[]shipping.Region `json:"regions"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Code != nil {
+		zone.Code = *input.Code
+	}
+	if input.Name != nil {
+		zone.Name = *input.Name
+	}
+	if input.Regions != nil {
+		zone.Regions = trimRegions(*input.Regions)
+	}
+
+	v := validator.New()
+	if shipping.ValidateZone(v, zone); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.repositories.Shipping.UpdateZone(&zone)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.shippingWriteErrorResponse(w, r, v, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"shipping_zone": zone}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteShippingZoneHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.repositories.Shipping.DeleteZone(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "shipping zone successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createShippingMethodHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Code                  string          `json:"code"`
+		Name                  string          `json:"name"`
+		Kind                  string          `json:"kind"`
+		VolumetricDivisor     *int            `json:"volumetric_divisor"`
+		FreeShippingThreshold *money.Money    `json:"free_shipping_threshold"`
+		Active                *bool           `json:"active"`
+		Rates                 []shipping.Rate `json:"rates"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	method := shipping.Method{
+		Code:                  input.Code,
+		Name:                  input.Name,
+		Kind:                  input.Kind,
+		VolumetricDivisor:     shipping.DefaultVolumetricDivisor,
+		FreeShippingThreshold: input.FreeShippingThreshold,
+		Active:                true,
+		Rates:                 input.Rates,
+	}
+
+	if input.VolumetricDivisor != nil {
+		method.VolumetricDivisor = *input.VolumetricDivisor
+	}
+	if input.Active != nil {
+		method.Active = *input.Active
+	}
+	if method.Rates == nil {
+		method.Rates = []shipping.Rate{}
+	}
+
+	v := validator.New()
+	if shipping.ValidateMethod(v, method); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.repositories.Shipping.InsertMethod(&method)
+	if err != nil {
+		app.shippingWriteErrorResponse(w, r, v, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"shipping_method": method}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listShippingMethodsHandler(w http.ResponseWriter, r *http.Request) {
+	methods, err := app.repositories.Shipping.GetMethods()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"shipping_methods": methods}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateShippingMethodHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	method, err := app.repositories.Shipping.GetMethod(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Rates replaces the whole rate table of the method when it is
+	// provided. RemoveFreeShippingThreshold stops offering free shipping.
+	var input struct {
+		Code                        *string          `json:"code"`
+		Name                        *string          `json:"name"`
+		Kind                        *string          `json:"kind"`
+		VolumetricDivisor           *int             `json:"volumetric_divisor"`
+		FreeShippingThreshold       *money.Money     `json:"free_shipping_threshold"`
+		RemoveFreeShippingThreshold bool             `json:"remove_free_shipping_threshold"`
+		Active                      *bool            `json:"active"`
+		Rates                       *[]shipping.Rate `json:"rates"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Code != nil {
+		method.Code = *input.Code
+	}
+	if input.Name != nil {
+		method.Name = *input.Name
+	}
+	if input.Kind != nil {
+		method.Kind = *input.Kind
+	}
+	if input.VolumetricDivisor != nil {
+		method.VolumetricDivisor = *input.VolumetricDivisor
+	}
+	if input.FreeShippingThreshold != nil {
+		method.FreeShippingThreshold = input.FreeShippingThreshold
+	}
+	if input.RemoveFreeShippingThreshold {
+		method.FreeShippingThreshold = nil
+	}
+	if input.Active != nil {
+		method.Active = *input.Active
+	}
+	if input.Rates != nil {
+		method.Rates = *input.Rates
+		if method.Rates == nil {
+			method.Rates = []shipping.Rate{}
+		}
+	}
+
+	v := validator.New()
+	v.Check(!(input.RemoveFreeShippingThreshold && input.FreeShippingThreshold != nil), "free_shipping_threshold", "must not be provided together with remove_free_shipping_threshold")
+	if shipping.ValidateMethod(v, method); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.repositories.Shipping.UpdateMethod(&method)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.shippingWriteErrorResponse(w, r, v, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"shipping_method": method}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteShippingMethodHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.repositories.Shipping.DeleteMethod(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "shipping method successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) shippingQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Country string `json:"country"`
+		State   string `json:"state"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	region := shipping.Region{
+		Country: strings.TrimSpace(input.Country),
+		State:   strings.TrimSpace(input.State),
+	}
+
+	v := validator.New()
+	v.Check(region.Country != "", "country", "must be provided")
+	v.Check(len(region.Country) <= 100, "country", "must not be more than 100 bytes long")
+	v.Check(len(region.State) <= 100, "state", "must not be more than 100 bytes long")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	rate, ok := app.readExchangeRate(w, r)
+	if !ok {
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	options, err := app.repositories.Shipping.Quote(user.UserID, region)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEmptyCart):
+			app.errorResponse(w, r, http.StatusUnprocessableEntity, "the cart is empty")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	for i := range options {
+		options[i].Cost = rate.Convert(options[i].Cost)
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"shipping_options": options}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// shippingWriteErrorResponse sends the appropriate response for the errors
+// returned when inserting or updating a shipping zone or method.
+func (app *application) shippingWriteErrorResponse(w http.ResponseWriter, r *http.Request, v *validator.Validator, err error) {
+	switch {
+	case errors.Is(err, data.ErrDuplicateCode):
+		v.AddError("code", "a shipping zone or method with this code already exists")
+		app.errorResponse(w, r, http.StatusConflict, v.Errors)
+	case errors.Is(err, data.ErrDuplicateRegion):
+		v.AddError("regions", "must not contain a region that belongs to another zone")
+		app.errorResponse(w, r, http.StatusConflict, v.Errors)
+	case errors.Is(err, data.ErrInvalidZone):
+		v.AddError("rates", "must only reference existing zones")
+		app.failedValidationResponse(w, r, v.Errors)
+	default:
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// trimRegions returns the regions with the surrounding spaces of their
+// country and state removed.
+func trimRegions(regions []shipping.Region) []shipping.Region {
+	trimmed := make([]shipping.Region, len(regions))
+	for i, region := range regions {
+		trimmed[i] = shipping.Region{
+			Country: strings.TrimSpace(region.Country),
+			State:   strings.TrimSpace(region.State),
+		}
+	}
+	return trimmed
+}
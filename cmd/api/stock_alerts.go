@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"github.com/hayohtee/fumode/internal/data"
+	"github.com/hayohtee/fumode/internal/validator"
+	"net/http"
+)
+
+func (app *application) listLowStockHandler(w http.ResponseWriter, r *http.Request) {
+	items, err := app.repositories.Alerts.GetLowStock()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"items": items}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) setReorderThresholdHandler(w http.ResponseWriter, r *http.Request) {
+	furnitureID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		ReorderThreshold int `json:"reorder_threshold"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(validator.Between(input.ReorderThreshold, 0, 100_000), "reorder_threshold", "must be between 0 and 100000")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.repositories.Alerts.SetReorderThreshold(furnitureID, input.ReorderThreshold)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	furniture, err := app.repositories.Furniture.GetByID(furnitureID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"furniture": furniture}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) subscribeStockNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	furnitureID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		VariantID *int64 `json:"variant_id"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	furniture, err := app.repositories.Furniture.GetByID(furnitureID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Subscribing only makes sense while the item is out of stock, so
+	// its available stock is checked first.
+	available := furniture.AvailableStock
+	if input.VariantID != nil {
+		variant, err := app.repositories.Variants.GetByID(furnitureID, *input.VariantID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+		available = variant.AvailableStock
+	}
+
+	switch {
+	case furniture.Availability == data.AvailabilityDiscontinued:
+		app.errorResponse(w, r, http.StatusConflict, "the item is discontinued")
+		return
+	case available > 0:
+		app.errorResponse(w, r, http.StatusConflict, "the item is in stock")
+		return
+	}
+
+	notification := data.StockNotification{
+		UserID:      app.contextGetUser(r).UserID,
+		FurnitureID: furnitureID,
+		VariantID:   input.VariantID,
+	}
+
+	err = app.repositories.Alerts.Subscribe(&notification)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"notification": notification}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) unsubscribeStockNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.repositories.Alerts.Unsubscribe(app.contextGetUser(r).UserID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "stock notification successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"errors"
+	"github.com/hayohtee/fumode/internal/data"
+	"github.com/hayohtee/fumode/internal/tax"
+	"github.com/hayohtee/fumode/internal/validator"
+	"net/http"
+	"strings"
+)
+
+func (app *application) createTaxRateHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Country  string  `json:"country"`
+		State    string  `json:"state"`
+		TaxClass string  `json:"tax_class"`
+		Percent  float64 `json:"percent"`
+		Name     string  `json:"name"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	rate := tax.Rate{
+		Country:  strings.TrimSpace(input.Country),
+		State:    strings.TrimSpace(input.State),
+		TaxClass: input.TaxClass,
+		Percent:  input.Percent,
+		Name:     input.Name,
+	}
+
+	if rate.TaxClass == "" {
+		rate.TaxClass = tax.ClassStandard
+	}
+
+	v := validator.New()
+	if tax.ValidateRate(v, rate); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.repositories.TaxRates.Insert(&rate)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateTaxRate):
+			v.AddError("tax_class", "a rate for this tax class already exists in this region")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"tax_rate": rate}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listTaxRatesHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	country := app.readString(qs, "country", "")
+
+	rates, err := app.repositories.TaxRates.GetAll(country)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"tax_rates": rates}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateTaxRateHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	rate, err := app.repositories.TaxRates.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Country  *string  `json:"country"`
+		State    *string  `json:"state"`
+		TaxClass *string  `json:"tax_class"`
+		Percent  *float64 `json:"percent"`
+		Name     *string  `json:"name"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Country != nil {
+		rate.Country = strings.TrimSpace(*input.Country)
+	}
+	if input.State != nil {
+		rate.State = strings.TrimSpace(*input.State)
+	}
+	if input.TaxClass != nil {
+		rate.TaxClass = *input.TaxClass
+	}
+	if input.Percent != nil {
+		rate.Percent = *input.Percent
+	}
+	if input.Name != nil {
+		rate.Name = *input.Name
+	}
+
+	v := validator.New()
+	if tax.ValidateRate(v, rate); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.repositories.TaxRates.Update(&rate)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateTaxRate):
+			v.AddError("tax_class", "a rate for this tax class already exists in this region")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"tax_rate": rate}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteTaxRateHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.repositories.TaxRates.Delete(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "tax rate successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/hayohtee/fumode/internal/data"
+	"github.com/hayohtee/fumode/internal/money"
+	"github.com/hayohtee/fumode/internal/validator"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func (app *application) createVariantHandler(w http.ResponseWriter, r *http.Request) {
+	furnitureID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	furniture, err := app.repositories.Furniture.GetByID(furnitureID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if furniture.Type == data.ProductTypeBundle {
+		app.errorResponse(w, r, http.StatusConflict, "a bundle cannot have variants")
+		return
+	}
+
+	// Parse the multipart form with a 10 MB max memory limit
+	err = r.ParseMultipartForm(10 << 20)
+	if err != nil {
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, "must be a multipart form")
+		return
+	}
+
+	v := validator.New()
+
+	variant := data.Variant{
+		FurnitureID: furnitureID,
+		SKU:         r.Form.Get("sku"),
+		Options:     make(map[string]string),
+	}
+
+	// Every option type is sent as its own form field, e.g. color=walnut.
+	for _, optionType := range data.VariantOptionTypes {
+		if value := r.Form.Get(optionType); value != "" {
+			variant.Options[optionType] = value
+		}
+	}
+
+	if s := r.Form.Get("price_delta"); s != "" {
+		priceDelta, err := money.Parse(s, money.DefaultCurrency)
+		if err != nil {
+			v.AddError("price_delta", "must be a valid amount with at most 2 decimal places")
+		}
+		variant.PriceDelta = &priceDelta
+	}
+
+	if s := r.Form.Get("price_override"); s != "" {
+		priceOverride, err := money.Parse(s, money.DefaultCurrency)
+		if err != nil {
+			v.AddError("price_override", "must be a valid amount with at most 2 decimal places")
+		}
+		variant.PriceOverride = &priceOverride
+	}
+
+	stockStr := r.Form.Get("stock")
+	v.Check(stockStr != "", "stock", "must be provided")
+	stock, err := strconv.ParseInt(stockStr, 10, 32)
+	if err != nil {
+		v.AddError("stock", "must be a valid number")
+	}
+	variant.Stock = int(stock)
+
+	// The initial stock is received in a warehouse, which is only
+	// required when there is stock.
+	var warehouseID int64
+	if variant.Stock > 0 {
+		warehouseID = int64(app.readInt(r.Form, "warehouse_id", 0, v))
+		v.Check(warehouseID > 0, "warehouse_id", "must be provided when stock is provided")
+	}
+
+	if data.ValidateVariant(v, variant); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if warehouseID > 0 && !app.warehouseExists(w, r, v, warehouseID) {
+		return
+	}
+
+	// Variant images are optional, the furniture images are used
+	// when a variant does not have its own.
+	if images := r.MultipartForm.File["images"]; len(images) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+		imageUrls, err := app.s3Uploader.UploadImages(ctx, images)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		variant.ImageURLs = imageUrls
+	}
+
+	err = app.repositories.Variants.Insert(&variant, warehouseID)
+	if err != nil {
+		app.variantWriteErrorResponse(w, r, v, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"variant": variant}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateVariantHandler(w http.ResponseWriter, r *http.Request) {
+	furnitureID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	variantID, err := app.readInt64Param(r, "variant_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	variant, err := app.repositories.Variants.GetByID(furnitureID, variantID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Setting price_delta clears price_override and vice versa, as a
+	// variant is priced by one of them at a time. The stock is adjusted
+	// through the inventory ledger instead.
+	var input struct {
+		SKU           *string           `json:"sku"`
+		Options       map[string]string `json:"options"`
+		PriceDelta    *money.Money      `json:"price_delta"`
+		PriceOverride *money.Money      `json:"price_override"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.SKU != nil {
+		variant.SKU = *input.SKU
+	}
+	if input.Options != nil {
+		variant.Options = input.Options
+	}
+	if input.PriceDelta != nil {
+		variant.PriceDelta = input.PriceDelta
+		variant.PriceOverride = nil
+	}
+	if input.PriceOverride != nil {
+		variant.PriceOverride = input.PriceOverride
+		variant.PriceDelta = nil
+	}
+
+	v := validator.New()
+	v.Check(input.PriceDelta == nil || input.PriceOverride == nil, "price_override", "must not be provided together with price_delta")
+	if data.ValidateVariant(v, variant); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.repositories.Variants.Update(&variant)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.variantWriteErrorResponse(w, r, v, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"variant": variant}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteVariantHandler(w http.ResponseWriter, r *http.Request) {
+	furnitureID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	variantID, err := app.readInt64Param(r, "variant_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.repositories.Variants.Delete(furnitureID, variantID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrVariantInUse):
+			app.errorResponse(w, r, http.StatusConflict, "the variant is a component of a bundle and cannot be deleted")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "variant successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// variantWriteErrorResponse sends the appropriate response for the errors
+// returned when inserting or updating a variant.
+func (app *application) variantWriteErrorResponse(w http.ResponseWriter, r *http.Request, v *validator.Validator, err error) {
+	switch {
+	case errors.Is(err, data.ErrDuplicateSKU):
+		v.AddError("sku", "a variant with this sku already exists")
+		app.errorResponse(w, r, http.StatusConflict, v.Errors)
+	case errors.Is(err, data.ErrDuplicateVariantOptions):
+		v.AddError("options", "a variant with these options already exists for this furniture")
+		app.errorResponse(w, r, http.StatusConflict, v.Errors)
+	case errors.Is(err, data.ErrNegativeVariantPrice):
+		v.AddError("price_delta", "must not take the price of the furniture below zero")
+		app.failedValidationResponse(w, r, v.Errors)
+	default:
+		app.serverErrorResponse(w, r, err)
+	}
+}
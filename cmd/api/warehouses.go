@@ -0,0 +1,220 @@
+package main
+
+import (
+	"errors"
+	"github.com/hayohtee/fumode/internal/data"
+	"github.com/hayohtee/fumode/internal/validator"
+	"net/http"
+)
+
+func (app *application) createWarehouseHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name    string `json:"name"`
+		Address string `json:"address"`
+		City    string `json:"city"`
+		State   string `json:"state"`
+		Country string `json:"country"`
+		ZipCode string `json:"zip_code"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	warehouse := data.Warehouse{
+		Name:    input.Name,
+		Address: input.Address,
+		City:    input.City,
+		State:   input.State,
+		Country: input.Country,
+		ZipCode: input.ZipCode,
+	}
+
+	v := validator.New()
+	if data.ValidateWarehouse(v, warehouse); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.repositories.Warehouses.Insert(&warehouse)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateName):
+			v.AddError("name", "a warehouse with this name already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"warehouse": warehouse}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showWarehouseHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	warehouse, err := app.repositories.Warehouses.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"warehouse": warehouse}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listWarehousesHandler(w http.ResponseWriter, r *http.Request) {
+	warehouses, err := app.repositories.Warehouses.GetAll()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"warehouses": warehouses}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateWarehouseHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	warehouse, err := app.repositories.Warehouses.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Name    *string `json:"name"`
+		Address *string `json:"address"`
+		City    *string `json:"city"`
+		State   *string `json:"state"`
+		Country *string `json:"country"`
+		ZipCode *string `json:"zip_code"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		warehouse.Name = *input.Name
+	}
+	if input.Address != nil {
+		warehouse.Address = *input.Address
+	}
+	if input.City != nil {
+		warehouse.City = *input.City
+	}
+	if input.State != nil {
+		warehouse.State = *input.State
+	}
+	if input.Country != nil {
+		warehouse.Country = *input.Country
+	}
+	if input.ZipCode != nil {
+		warehouse.ZipCode = *input.ZipCode
+	}
+
+	v := validator.New()
+	if data.ValidateWarehouse(v, warehouse); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.repositories.Warehouses.Update(&warehouse)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateName):
+			v.AddError("name", "a warehouse with this name already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"warehouse": warehouse}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listWarehouseStockHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	_, err = app.repositories.Warehouses.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	stock, err := app.repositories.Warehouses.GetStock(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"stock": stock}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// warehouseExists checks that the warehouse referenced by the request
+// exists. Otherwise it sends a failed validation response with the
+// warehouse_id error added to v, and returns false.
+func (app *application) warehouseExists(w http.ResponseWriter, r *http.Request, v *validator.Validator, id int64) bool {
+	_, err := app.repositories.Warehouses.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("warehouse_id", "must reference an existing warehouse")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return false
+	}
+	return true
+}
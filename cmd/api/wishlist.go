@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"github.com/hayohtee/fumode/internal/data"
+	"github.com/hayohtee/fumode/internal/validator"
+	"net/http"
+)
+
+func (app *application) showWishlistHandler(w http.ResponseWriter, r *http.Request) {
+	rate, ok := app.readExchangeRate(w, r)
+	if !ok {
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	items, err := app.repositories.Wishlist.GetAll(user.UserID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	data.ConvertWishlistPrices(items, rate)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"wishlist": items}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) addWishlistItemHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		FurnitureID int64  `json:"furniture_id"`
+		VariantID   *int64 `json:"variant_id"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.FurnitureID > 0, "furniture_id", "must be provided")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Make sure the furniture, or the variant of it, exists before adding
+	// it to the wishlist.
+	if input.VariantID != nil {
+		_, err = app.repositories.Variants.GetByID(input.FurnitureID, *input.VariantID)
+	} else {
+		_, err = app.repositories.Furniture.GetByID(input.FurnitureID)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("furniture_id", "furniture or variant does not exist")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	rate, ok := app.readExchangeRate(w, r)
+	if !ok {
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	err = app.repositories.Wishlist.AddItem(user.UserID, input.FurnitureID, input.VariantID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	items, err := app.repositories.Wishlist.GetAll(user.UserID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	data.ConvertWishlistPrices(items, rate)
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"wishlist": items}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteWishlistItemHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	err = app.repositories.Wishlist.DeleteItem(user.UserID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "item successfully removed from the wishlist"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
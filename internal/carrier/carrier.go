@@ -0,0 +1,126 @@
+package carrier
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnknownTrackingNumber is a custom error that is returned when the
+// carrier has no parcel with the tracking number.
+var ErrUnknownTrackingNumber = errors.New("unknown tracking number")
+
+// The statuses of the tracking events of a parcel. A parcel is delivered
+// once an event with StatusDelivered is reported.
+const (
+	StatusLabelCreated   = "label_created"
+	StatusInTransit      = "in_transit"
+	StatusOutForDelivery = "out_for_delivery"
+	StatusDelivered      = "delivered"
+	StatusException      = "exception"
+)
+
+// Parcel is what is handed over to the carrier, sent from the warehouse to
+// the shipping address. Reference identifies the shipment on our side.
+type Parcel struct {
+	Reference string
+	Address   string
+	City      string
+	State     string
+	Country   string
+	ZipCode   string
+}
+
+// Label is the shipping label created by a carrier for a parcel, at
+// CreatedAt.
+type Label struct {
+	Carrier        string
+	TrackingNumber string
+	CreatedAt      time.Time
+}
+
+// Event is a step of the journey of a parcel reported by the carrier.
+type Event struct {
+	Status      string
+	Description string
+	Location    string
+	OccurredAt  time.Time
+}
+
+// Carrier is the interface implemented by the carriers the orders are
+// shipped with. TrackingEvents returns every event of the parcel so far,
+// from the oldest.
+type Carrier interface {
+	Name() string
+	CreateLabel(ctx context.Context, parcel Parcel) (Label, error)
+	TrackingEvents(ctx context.Context, trackingNumber string) ([]Event, error)
+}
+
+// FakeCarrier is a Carrier which moves every parcel along a fixed journey,
+// from the time its label was created. It is used until a real carrier is
+// integrated, and lets the tracking flow be exercised end to end.
+type FakeCarrier struct{}
+
+// fakeJourney is the journey of the parcels of FakeCarrier, each event
+// happening the given time after the label was created.
+var fakeJourney = []struct {
+	after       time.Duration
+	status      string
+	description string
+}{
+	{0, StatusLabelCreated, "Shipping label created"},
+	{2 * time.Hour, StatusInTransit, "Parcel picked up from the warehouse"},
+	{24 * time.Hour, StatusOutForDelivery, "Parcel out for delivery"},
+	{30 * time.Hour, StatusDelivered, "Parcel delivered"},
+}
+
+// Name returns the name of the fake carrier.
+func (FakeCarrier) Name() string {
+	return "fake"
+}
+
+// CreateLabel always succeeds. The tracking number holds the time the
+// label was created, so that the journey can be replayed without state.
+func (c FakeCarrier) CreateLabel(ctx context.Context, parcel Parcel) (Label, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return Label{}, err
+	}
+
+	created := time.Now().UTC().Truncate(time.Second)
+	number := fmt.Sprintf("FK%d%s", created.Unix(), strings.ToUpper(hex.EncodeToString(b)))
+	return Label{Carrier: c.Name(), TrackingNumber: number, CreatedAt: created}, nil
+}
+
+// TrackingEvents returns the events of the journey that already happened.
+func (FakeCarrier) TrackingEvents(ctx context.Context, trackingNumber string) ([]Event, error) {
+	// The tracking number is "FK", the unix time and 12 hex digits.
+	if len(trackingNumber) <= 14 || !strings.HasPrefix(trackingNumber, "FK") {
+		return nil, ErrUnknownTrackingNumber
+	}
+
+	seconds, err := strconv.ParseInt(trackingNumber[2:len(trackingNumber)-12], 10, 64)
+	if err != nil {
+		return nil, ErrUnknownTrackingNumber
+	}
+	created := time.Unix(seconds, 0).UTC()
+
+	events := []Event{}
+	for _, step := range fakeJourney {
+		occurredAt := created.Add(step.after)
+		if occurredAt.After(time.Now()) {
+			break
+		}
+		events = append(events, Event{
+			Status:      step.status,
+			Description: step.description,
+			OccurredAt:  occurredAt,
+		})
+	}
+	return events, nil
+}
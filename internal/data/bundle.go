@@ -0,0 +1,59 @@
+package data
+
+import (
+	"github.com/hayohtee/fumode/internal/money"
+	"github.com/hayohtee/fumode/internal/validator"
+)
+
+// The product types of a furniture. A bundle, such as a dining set, is
+// sold as one furniture but made of the stock of its components.
+const (
+	ProductTypeStandard = "standard"
+	ProductTypeBundle   = "bundle"
+)
+
+// BundleComponent is a struct that holds a furniture, or a specific
+// variant of it, that a bundle is made of with the quantity in one
+// bundle. UnitPrice is the current price of the component.
+type BundleComponent struct {
+	FurnitureID int64       `json:"furniture_id"`
+	VariantID   *int64      `json:"variant_id"`
+	Name        string      `json:"name"`
+	Quantity    int         `json:"quantity"`
+	UnitPrice   money.Money `json:"unit_price"`
+}
+
+// Bundle is a struct that holds the components of a bundle and how it
+// is priced: either at the fixed Price, or at the sum of the prices of
+// its components minus DiscountPercent.
+type Bundle struct {
+	FurnitureID     int64
+	Components      []BundleComponent
+	Price           *money.Money
+	DiscountPercent *float64
+}
+
+func ValidateBundle(v *validator.Validator, bundle Bundle) {
+	v.Check(len(bundle.Components) > 0, "components", "must contain at least one component")
+	v.Check(len(bundle.Components) <= 20, "components", "must not contain more than 20 components")
+
+	seen := make(map[[2]int64]bool)
+	for _, component := range bundle.Components {
+		key := [2]int64{component.FurnitureID, derefID(component.VariantID)}
+		v.Check(!seen[key], "components", "must not contain duplicate components")
+		seen[key] = true
+
+		v.Check(component.FurnitureID > 0, "components", "must only contain valid furniture ids")
+		v.Check(component.FurnitureID != bundle.FurnitureID, "components", "must not contain the bundle itself")
+		v.Check(validator.Between(component.Quantity, 1, 100), "components", "must only contain quantities between 1 and 100")
+	}
+
+	v.Check(bundle.Price != nil || bundle.DiscountPercent != nil, "price", "either price or discount_percent must be provided")
+	v.Check(bundle.Price == nil || bundle.DiscountPercent == nil, "price", "must not be provided together with discount_percent")
+	if bundle.Price != nil {
+		ValidatePrice(v, "price", *bundle.Price)
+	}
+	if bundle.DiscountPercent != nil {
+		v.Check(*bundle.DiscountPercent > 0 && *bundle.DiscountPercent < 100, "discount_percent", "must be more than 0 and less than 100")
+	}
+}
@@ -0,0 +1,290 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// componentPrice is the SQL expression for the current unit price of the
+// bundle component aliased as bc, joined with its furniture as cf and its
+// variant, if any, as cv.
+const componentPrice = `COALESCE(cv.price_override, cf.price + COALESCE(cv.price_delta, 0))`
+
+// bundleSets returns the SQL expression for the number of complete sets
+// the components of the bundle aliased as f can make, given the SQL
+// expression for the stock of a component. The expression may reference
+// the component as bc and its stock level as cs.stock.
+func bundleSets(stock string) string {
+	return `COALESCE((
+				SELECT MIN(GREATEST(` + stock + `, 0) / bc.quantity)
+				FROM bundle_component bc
+				LEFT JOIN furniture cf ON bc.variant_id IS NULL AND cf.furniture_id = bc.furniture_id
+				LEFT JOIN furniture_variant cv ON cv.variant_id = bc.variant_id
+				CROSS JOIN LATERAL (SELECT COALESCE(cv.stock, cf.stock) AS stock) cs
+				WHERE bc.bundle_id = f.furniture_id
+			), 0)`
+}
+
+// componentReserved is the SQL expression for the quantity of the bundle
+// component aliased as bc held by active stock reservations.
+const componentReserved = `COALESCE((
+					SELECT SUM(r.quantity) FROM stock_reservation r
+					WHERE r.furniture_id = bc.furniture_id AND r.variant_id IS NOT DISTINCT FROM bc.variant_id
+					AND r.status = 'active' AND r.expires_at > NOW()
+				), 0)`
+
+// BundleRepository is a type which wraps around a sql.DB connection pool
+// and provide methods for managing the components of the bundles.
+type BundleRepository struct {
+	DB *sql.DB
+}
+
+// Set turns a furniture into a bundle made of the provided components,
+// replacing its previous components if it already was one. The furniture
+// must not have stock or variants of its own, and the components must not
+// be bundles themselves. It returns ErrInvalidBundle otherwise.
+func (b BundleRepository) Set(bundle Bundle) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := b.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT f.stock, EXISTS (SELECT 1 FROM furniture_variant v WHERE v.furniture_id = f.furniture_id),
+			EXISTS (SELECT 1 FROM bundle_component bc WHERE bc.furniture_id = f.furniture_id),
+			EXISTS (SELECT 1 FROM price_schedule s WHERE s.furniture_id = f.furniture_id
+				AND s.status IN ('scheduled', 'active'))
+		FROM furniture f
+		WHERE f.furniture_id = $1
+		FOR UPDATE`
+
+	var stock int
+	var hasVariants, isComponent, onSale bool
+	err = tx.QueryRowContext(ctx, query, bundle.FurnitureID).Scan(&stock, &hasVariants, &isComponent, &onSale)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	switch {
+	case stock != 0:
+		return fmt.Errorf("%w: the furniture must not have stock of its own", ErrInvalidBundle)
+	case hasVariants:
+		return fmt.Errorf("%w: the furniture must not have variants", ErrInvalidBundle)
+	case isComponent:
+		return fmt.Errorf("%w: the furniture is a component of another bundle", ErrInvalidBundle)
+	case onSale:
+		return fmt.Errorf("%w: the furniture has a scheduled sale", ErrInvalidBundle)
+	}
+
+	query = `DELETE FROM bundle_component WHERE bundle_id = $1`
+	if _, err = tx.ExecContext(ctx, query, bundle.FurnitureID); err != nil {
+		return err
+	}
+
+	// The component is only inserted when it exists, is not a bundle and
+	// the variant, if any, belongs to it.
+	query = `
+		INSERT INTO bundle_component(bundle_id, furniture_id, variant_id, quantity)
+		SELECT $1, f.furniture_id, $3, $4
+		FROM furniture f
+		WHERE f.furniture_id = $2 AND f.product_type = 'standard'
+		AND ($3::BIGINT IS NULL OR EXISTS (
+			SELECT 1 FROM furniture_variant v WHERE v.variant_id = $3 AND v.furniture_id = f.furniture_id
+		))`
+
+	for _, component := range bundle.Components {
+		args := []any{bundle.FurnitureID, component.FurnitureID, component.VariantID, component.Quantity}
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return fmt.Errorf("%w: furniture %d does not exist, is a bundle or has no such variant", ErrInvalidBundle, component.FurnitureID)
+		}
+	}
+
+	// A bundle cannot be backordered or pre-ordered, as it is only
+	// available while its components are in stock.
+	query = `
+		UPDATE furniture
+		SET product_type = 'bundle', bundle_discount_percent = $1, price = COALESCE($2, price),
+			availability = CASE WHEN availability = 'discontinued' THEN availability ELSE 'in_stock' END,
+			lead_time_days = NULL, release_date = NULL, version = version + 1
+		WHERE furniture_id = $3`
+
+	_, err = tx.ExecContext(ctx, query, bundle.DiscountPercent, bundle.Price, bundle.FurnitureID)
+	if err != nil {
+		return err
+	}
+
+	if bundle.Price != nil {
+		if err = recordPriceChange(ctx, tx, bundle.FurnitureID, PriceChangeUpdated, nil); err != nil {
+			return err
+		}
+	}
+
+	if err = refreshBundlePrices(ctx, tx, bundle.FurnitureID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetComponents retrieve the components of a specific bundle.
+func (b BundleRepository) GetComponents(bundleID int64) ([]BundleComponent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	components, err := getBundleComponents(ctx, b.DB, []int64{bundleID})
+	if err != nil {
+		return nil, err
+	}
+
+	if components[bundleID] == nil {
+		return []BundleComponent{}, nil
+	}
+	return components[bundleID], nil
+}
+
+// getBundleComponents retrieve the components of the bundles, grouped by
+// bundle id.
+func getBundleComponents(ctx context.Context, q queryer, bundleIDs []int64) (map[int64][]BundleComponent, error) {
+	query := fmt.Sprintf(`
+		SELECT bc.bundle_id, bc.furniture_id, bc.variant_id, cf.name || COALESCE(' (' || cv.sku || ')', ''),
+			bc.quantity, %s
+		FROM bundle_component bc
+		JOIN furniture cf ON bc.furniture_id = cf.furniture_id
+		LEFT JOIN furniture_variant cv ON bc.variant_id = cv.variant_id
+		WHERE bc.bundle_id = ANY($1)
+		ORDER BY bc.bundle_component_id`, componentPrice)
+
+	rows, err := q.QueryContext(ctx, query, bundleIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	components := make(map[int64][]BundleComponent)
+	for rows.Next() {
+		var bundleID int64
+		var component BundleComponent
+		err = rows.Scan(
+			&bundleID,
+			&component.FurnitureID,
+			&component.VariantID,
+			&component.Name,
+			&component.Quantity,
+			&component.UnitPrice,
+		)
+		if err != nil {
+			return nil, err
+		}
+		components[bundleID] = append(components[bundleID], component)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return components, nil
+}
+
+// refreshBundlePrices recomputes the price of the discounted bundles that
+// are, or contain, the furniture, from the current prices of their
+// components, and records the new prices in their price history. It must be
+// called whenever the price of a component changes.
+func refreshBundlePrices(ctx context.Context, q queryer, furnitureID int64) error {
+	query := fmt.Sprintf(`
+		WITH refreshed AS (
+			UPDATE furniture b
+			SET price = ROUND(s.total * (100 - b.bundle_discount_percent) / 100, 2), version = b.version + 1
+			FROM (
+				SELECT bc.bundle_id, SUM(bc.quantity * %s) AS total
+				FROM bundle_component bc
+				JOIN furniture cf ON bc.furniture_id = cf.furniture_id
+				LEFT JOIN furniture_variant cv ON bc.variant_id = cv.variant_id
+				WHERE bc.bundle_id = $1
+				OR bc.bundle_id IN (SELECT bundle_id FROM bundle_component WHERE furniture_id = $1)
+				GROUP BY bc.bundle_id
+			) s
+			WHERE b.furniture_id = s.bundle_id AND b.bundle_discount_percent IS NOT NULL
+			AND b.price <> ROUND(s.total * (100 - b.bundle_discount_percent) / 100, 2)
+			RETURNING b.furniture_id, b.price
+		)
+		INSERT INTO price_history(furniture_id, price, reason)
+		SELECT furniture_id, price, '%s'
+		FROM refreshed`, componentPrice, PriceChangeComponentsChanged)
+
+	_, err := q.ExecContext(ctx, query, furnitureID)
+	return err
+}
+
+// expandBundles returns the stock each of the lines draws on, in the order
+// of the lines, which is the stock of the components for a bundle and the
+// line itself otherwise. The components are those of the bundle at the
+// time of the call.
+func expandBundles(ctx context.Context, q queryer, lines []stockLine) ([][]stockLine, error) {
+	furnitureIDs := make([]int64, 0, len(lines))
+	for _, line := range lines {
+		furnitureIDs = append(furnitureIDs, line.furnitureID)
+	}
+
+	query := `
+		SELECT furniture_id
+		FROM furniture
+		WHERE furniture_id = ANY($1) AND product_type = 'bundle'`
+
+	rows, err := q.QueryContext(ctx, query, furnitureIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bundleIDs []int64
+	for rows.Next() {
+		var bundleID int64
+		if err = rows.Scan(&bundleID); err != nil {
+			return nil, err
+		}
+		bundleIDs = append(bundleIDs, bundleID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	components, err := getBundleComponents(ctx, q, bundleIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	expanded := make([][]stockLine, len(lines))
+	for i, line := range lines {
+		c, ok := components[line.furnitureID]
+		if !ok {
+			expanded[i] = []stockLine{line}
+			continue
+		}
+		for _, component := range c {
+			expanded[i] = append(expanded[i], stockLine{component.FurnitureID, component.VariantID, component.Name, component.Quantity * line.quantity})
+		}
+	}
+	return expanded, nil
+}
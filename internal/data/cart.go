@@ -0,0 +1,40 @@
+package data
+
+import (
+	"github.com/hayohtee/fumode/internal/money"
+	"github.com/hayohtee/fumode/internal/promotions"
+	"github.com/hayohtee/fumode/internal/validator"
+	"time"
+)
+
+// CartItem is a struct that holds a furniture, or a specific variant
+// of it, added to the cart of a user. The unit price is the current
+// price of the furniture or variant, and the availability and the tax
+// class that of the furniture.
+type CartItem struct {
+	CartItemID   int64       `json:"cart_item_id"`
+	FurnitureID  int64       `json:"furniture_id"`
+	VariantID    *int64      `json:"variant_id"`
+	Name         string      `json:"name"`
+	UnitPrice    money.Money `json:"unit_price"`
+	Quantity     int         `json:"quantity"`
+	Subtotal     money.Money `json:"subtotal"`
+	Type         string      `json:"type"`
+	Availability string      `json:"availability"`
+	TaxClass     string      `json:"tax_class"`
+	LeadTimeDays *int        `json:"lead_time_days,omitempty"`
+	ReleaseDate  *time.Time  `json:"release_date,omitempty"`
+}
+
+// Cart is a struct that holds the items in the cart of a user and
+// their total price, once discounted by the coupons applied to it.
+type Cart struct {
+	Items []CartItem `json:"items"`
+	promotions.Breakdown
+	coupons []promotions.Coupon
+}
+
+func ValidateCartQuantity(v *validator.Validator, quantity int) {
+	v.Check(quantity > 0, "quantity", "must be greater than zero")
+	v.Check(quantity <= 100, "quantity", "must be a maximum of 100")
+}
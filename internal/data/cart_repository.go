@@ -0,0 +1,163 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// CartRepository is a type which wraps around a sql.DB connection pool
+// and provide methods for managing the cart of the users.
+type CartRepository struct {
+	DB *sql.DB
+}
+
+// cartItemsQuery selects the cart items of the user $1 with their
+// current unit price and availability.
+const cartItemsQuery = `
+		SELECT
+			ct.cart_id,
+			ct.furniture_id,
+			ct.variant_id,
+			f.name,
+			COALESCE(v.price_override, f.price + COALESCE(v.price_delta, 0)),
+			ct.quantity,
+			f.product_type,
+			f.availability,
+			COALESCE(cat.tax_class, 'standard'),
+			f.lead_time_days,
+			f.release_date
+		FROM cart ct
+		JOIN furniture f ON ct.furniture_id = f.furniture_id
+		LEFT JOIN furniture_variant v ON ct.variant_id = v.variant_id
+		LEFT JOIN category cat ON f.category_id = cat.category_id
+		WHERE ct.user_id = $1
+		ORDER BY ct.cart_id`
+
+// Get retrieve the cart of a specific user.
+func (c CartRepository) Get(userID int64) (Cart, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return getCart(ctx, c.DB, userID, false)
+}
+
+// AddItem adds the quantity of a furniture, or of a specific variant of it,
+// to the cart of a user. If the cart already contains it, the quantities are
+// added together.
+func (c CartRepository) AddItem(userID, furnitureID int64, variantID *int64, quantity int) error {
+	query := `
+		INSERT INTO cart(user_id, furniture_id, variant_id, quantity)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, furniture_id, COALESCE(variant_id, 0))
+		DO UPDATE SET quantity = cart.quantity + EXCLUDED.quantity`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := c.DB.ExecContext(ctx, query, userID, furnitureID, variantID, quantity)
+	return err
+}
+
+// UpdateQuantity sets the quantity of a specific item in the cart of a user.
+func (c CartRepository) UpdateQuantity(userID, cartItemID int64, quantity int) error {
+	query := `
+		UPDATE cart SET quantity = $1
+		WHERE user_id = $2 AND cart_id = $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := c.DB.ExecContext(ctx, query, quantity, userID, cartItemID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteItem removes a specific item from the cart of a user.
+func (c CartRepository) DeleteItem(userID, cartItemID int64) error {
+	query := `
+		DELETE FROM cart
+		WHERE user_id = $1 AND cart_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := c.DB.ExecContext(ctx, query, userID, cartItemID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx, so that the
+// same helpers can run inside or outside of a transaction.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// getCart retrieve the cart of a specific user and computes its total,
+// discounted by the coupons applied to it. When forUpdate is set the
+// coupons are locked until the end of the transaction.
+func getCart(ctx context.Context, q queryer, userID int64, forUpdate bool) (Cart, error) {
+	rows, err := q.QueryContext(ctx, cartItemsQuery, userID)
+	if err != nil {
+		return Cart{}, err
+	}
+	defer rows.Close()
+
+	cart := Cart{Items: []CartItem{}}
+	for rows.Next() {
+		var item CartItem
+		err = rows.Scan(
+			&item.CartItemID,
+			&item.FurnitureID,
+			&item.VariantID,
+			&item.Name,
+			&item.UnitPrice,
+			&item.Quantity,
+			&item.Type,
+			&item.Availability,
+			&item.TaxClass,
+			&item.LeadTimeDays,
+			&item.ReleaseDate,
+		)
+		if err != nil {
+			return Cart{}, err
+		}
+
+		item.Subtotal = item.UnitPrice.Mul(item.Quantity)
+		cart.Items = append(cart.Items, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return Cart{}, err
+	}
+	rows.Close()
+
+	if err = applyCoupons(ctx, q, userID, &cart, forUpdate); err != nil {
+		return Cart{}, err
+	}
+	return cart, nil
+}
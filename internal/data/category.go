@@ -0,0 +1,50 @@
+package data
+
+import (
+	"github.com/hayohtee/fumode/internal/tax"
+	"github.com/hayohtee/fumode/internal/validator"
+	"regexp"
+	"strings"
+)
+
+// slugSeparatorRX matches every run of characters that is not allowed
+// in a slug, so it can be collapsed into a single hyphen.
+var slugSeparatorRX = regexp.MustCompile("[^a-z0-9]+")
+
+// Category is a struct that holds information about a specific
+// category. Categories form a tree through ParentID, which is nil
+// for the top level categories (e.g. Living Room → Sofas → Sectionals).
+// TaxClass is the tax class of the furniture in the category, it is not
+// inherited by the sub categories.
+type Category struct {
+	CategoryID  int64  `json:"category_id"`
+	Name        string `json:"name"`
+	Slug        string `json:"slug"`
+	Description string `json:"description"`
+	ParentID    *int64 `json:"parent_id"`
+	TaxClass    string `json:"tax_class"`
+	Version     int    `json:"version"`
+}
+
+// Slugify returns the URL friendly form of s, e.g. "Living Room" becomes
+// "living-room".
+func Slugify(s string) string {
+	slug := slugSeparatorRX.ReplaceAllString(strings.ToLower(s), "-")
+	return strings.Trim(slug, "-")
+}
+
+func ValidateCategory(v *validator.Validator, category Category) {
+	v.Check(category.Name != "", "name", "must be provided")
+	v.Check(len(category.Name) <= 100, "name", "must not be more than 100 bytes long")
+
+	v.Check(category.Slug != "", "slug", "must be provided")
+	v.Check(len(category.Slug) <= 100, "slug", "must not be more than 100 bytes long")
+	v.Check(validator.Matches(category.Slug, validator.SlugRX), "slug", "must only contain lowercase letters, digits and hyphens")
+
+	if category.ParentID != nil {
+		v.Check(*category.ParentID > 0, "parent_id", "must be a positive integer")
+		v.Check(*category.ParentID != category.CategoryID, "parent_id", "must not reference the category itself")
+	}
+
+	v.Check(validator.PermittedValue(category.TaxClass, tax.Classes...), "tax_class", "must be standard, reduced or zero")
+}
@@ -0,0 +1,215 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// CategoryRepository is a type which wraps around a sql.DB connection pool
+// and provide methods for creating and managing categories to and from
+// the database.
+type CategoryRepository struct {
+	DB *sql.DB
+}
+
+// Insert a category record to the database.
+func (c CategoryRepository) Insert(category *Category) error {
+	query := `
+		INSERT INTO category(name, slug, description, parent_id, tax_class)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING category_id, version`
+
+	args := []any{category.Name, category.Slug, category.Description, category.ParentID, category.TaxClass}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := c.DB.QueryRowContext(ctx, query, args...).Scan(&category.CategoryID, &category.Version)
+	if err != nil {
+		return categoryWriteError(err)
+	}
+	return nil
+}
+
+// GetByID retrieve a specific category record from the database
+// given the id.
+func (c CategoryRepository) GetByID(id int64) (Category, error) {
+	query := `
+		SELECT category_id, name, slug, description, parent_id, tax_class, version
+		FROM category
+		WHERE category_id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var category Category
+	err := c.DB.QueryRowContext(ctx, query, id).Scan(
+		&category.CategoryID,
+		&category.Name,
+		&category.Slug,
+		&category.Description,
+		&category.ParentID,
+		&category.TaxClass,
+		&category.Version,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return Category{}, ErrRecordNotFound
+		default:
+			return Category{}, err
+		}
+	}
+	return category, nil
+}
+
+// GetAll retrieve every category from the database, ordered so that
+// parents are listed before their children.
+func (c CategoryRepository) GetAll() ([]Category, error) {
+	query := `
+		SELECT category_id, name, slug, description, parent_id, tax_class, version
+		FROM category
+		ORDER BY parent_id NULLS FIRST, name, category_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := c.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []Category{}
+	for rows.Next() {
+		var category Category
+		err = rows.Scan(
+			&category.CategoryID,
+			&category.Name,
+			&category.Slug,
+			&category.Description,
+			&category.ParentID,
+			&category.TaxClass,
+			&category.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+// Update a specific category record in the database. It returns
+// ErrCategoryCycle if the new parent is the category itself or one of
+// its descendants, and ErrEditConflict if the record was changed since
+// it was read.
+func (c CategoryRepository) Update(category *Category) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if category.ParentID != nil {
+		// Walk up the tree from the new parent and make sure we never
+		// reach the category being updated.
+		queryCycle := `
+			WITH RECURSIVE ancestors AS (
+				SELECT category_id, parent_id FROM category WHERE category_id = $1
+				UNION
+				SELECT c.category_id, c.parent_id
+				FROM category c
+				JOIN ancestors a ON c.category_id = a.parent_id
+			)
+			SELECT EXISTS (SELECT 1 FROM ancestors WHERE category_id = $2)`
+
+		var cycle bool
+		err := c.DB.QueryRowContext(ctx, queryCycle, *category.ParentID, category.CategoryID).Scan(&cycle)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return ErrCategoryCycle
+		}
+	}
+
+	query := `
+		UPDATE category
+		SET name = $1, slug = $2, description = $3, parent_id = $4, tax_class = $5, version = version + 1
+		WHERE category_id = $6 AND version = $7
+		RETURNING version`
+
+	args := []any{
+		category.Name,
+		category.Slug,
+		category.Description,
+		category.ParentID,
+		category.TaxClass,
+		category.CategoryID,
+		category.Version,
+	}
+
+	err := c.DB.QueryRowContext(ctx, query, args...).Scan(&category.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return categoryWriteError(err)
+		}
+	}
+	return nil
+}
+
+// Delete a specific category record from the database. A category that
+// still has sub categories or furniture cannot be deleted and
+// ErrCategoryInUse is returned instead.
+func (c CategoryRepository) Delete(id int64) error {
+	query := `
+		DELETE FROM category
+		WHERE category_id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := c.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "violates foreign key constraint"):
+			return ErrCategoryInUse
+		default:
+			return err
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// categoryWriteError converts the constraint violations raised when
+// inserting or updating a category to the matching custom error.
+func categoryWriteError(err error) error {
+	switch {
+	case strings.Contains(err.Error(), `duplicate key value violates unique constraint "category_slug_key"`):
+		return ErrDuplicateSlug
+	case strings.Contains(err.Error(), `duplicate key value violates unique constraint "category_name_key"`):
+		return ErrDuplicateName
+	case strings.Contains(err.Error(), `violates foreign key constraint "category_parent_id_fkey"`):
+		return ErrInvalidParentCategory
+	default:
+		return err
+	}
+}
@@ -0,0 +1,472 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/hayohtee/fumode/internal/money"
+	"github.com/hayohtee/fumode/internal/promotions"
+	"strings"
+	"time"
+)
+
+// couponColumns is the list of columns selected for a coupon, in the order
+// expected by couponScanDest. The queries must alias the coupon table as c.
+const couponColumns = `
+			c.coupon_id,
+			c.code,
+			c.description,
+			c.discount_type,
+			CASE WHEN c.discount_type = 'percentage' THEN c.amount END,
+			CASE WHEN c.discount_type = 'fixed' THEN c.amount END,
+			c.min_subtotal,
+			c.category_ids,
+			c.furniture_ids,
+			c.starts_at,
+			c.ends_at,
+			c.usage_limit,
+			c.per_user_limit,
+			c.stackable,
+			c.created_at,
+			c.version`
+
+// couponScanDest returns the destinations for scanning the coupon columns.
+func couponScanDest(coupon *promotions.Coupon) []any {
+	return []any{
+		&coupon.CouponID,
+		&coupon.Code,
+		&coupon.Description,
+		&coupon.DiscountType,
+		&coupon.PercentOff,
+		&coupon.AmountOff,
+		&coupon.MinSubtotal,
+		&coupon.CategoryIDs,
+		&coupon.FurnitureIDs,
+		&coupon.StartsAt,
+		&coupon.EndsAt,
+		&coupon.UsageLimit,
+		&coupon.PerUserLimit,
+		&coupon.Stackable,
+		&coupon.CreatedAt,
+		&coupon.Version,
+	}
+}
+
+// CouponRepository is a type which wraps around a sql.DB connection pool
+// and provide methods for managing the coupons and applying them to the
+// cart of the users.
+type CouponRepository struct {
+	DB *sql.DB
+}
+
+// Insert a coupon record to the database.
+func (c CouponRepository) Insert(coupon *promotions.Coupon) error {
+	query := `
+		INSERT INTO coupon(code, description, discount_type, amount, min_subtotal, category_ids, furniture_ids,
+			starts_at, ends_at, usage_limit, per_user_limit, stackable)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING coupon_id, created_at, version`
+
+	args := []any{
+		coupon.Code,
+		coupon.Description,
+		coupon.DiscountType,
+		couponAmount(*coupon),
+		coupon.MinSubtotal,
+		coupon.CategoryIDs,
+		coupon.FurnitureIDs,
+		coupon.StartsAt,
+		coupon.EndsAt,
+		coupon.UsageLimit,
+		coupon.PerUserLimit,
+		coupon.Stackable,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := c.DB.QueryRowContext(ctx, query, args...).Scan(&coupon.CouponID, &coupon.CreatedAt, &coupon.Version)
+	if err != nil {
+		return couponWriteError(err)
+	}
+	return nil
+}
+
+// GetByID retrieve a specific coupon record from the database given
+// the id.
+func (c CouponRepository) GetByID(id int64) (promotions.Coupon, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM coupon c
+		WHERE c.coupon_id = $1`, couponColumns)
+
+	return c.get(query, id)
+}
+
+// GetByCode retrieve a specific coupon record from the database given
+// the code, regardless of its case.
+func (c CouponRepository) GetByCode(code string) (promotions.Coupon, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM coupon c
+		WHERE UPPER(c.code) = UPPER($1)`, couponColumns)
+
+	return c.get(query, code)
+}
+
+func (c CouponRepository) get(query string, arg any) (promotions.Coupon, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var coupon promotions.Coupon
+	err := c.DB.QueryRowContext(ctx, query, arg).Scan(couponScanDest(&coupon)...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return promotions.Coupon{}, ErrRecordNotFound
+		default:
+			return promotions.Coupon{}, err
+		}
+	}
+	return coupon, nil
+}
+
+// GetAll retrieve the coupons whose code contains the provided one,
+// alongside the pagination metadata.
+func (c CouponRepository) GetAll(code string, filters Filters) ([]promotions.Coupon, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER(), %s
+		FROM coupon c
+		WHERE ($1 = '' OR c.code ILIKE '%%' || $1 || '%%')
+		ORDER BY c.%s %s, c.coupon_id ASC
+		LIMIT $2 OFFSET $3`, couponColumns, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := c.DB.QueryContext(ctx, query, code, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	coupons := []promotions.Coupon{}
+
+	for rows.Next() {
+		var coupon promotions.Coupon
+		err = rows.Scan(append([]any{&totalRecords}, couponScanDest(&coupon)...)...)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		coupons = append(coupons, coupon)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return coupons, metadata, nil
+}
+
+// Update a specific coupon record in the database. It returns
+// ErrEditConflict if the record was changed since it was read.
+func (c CouponRepository) Update(coupon *promotions.Coupon) error {
+	query := `
+		UPDATE coupon
+		SET code = $1, description = $2, discount_type = $3, amount = $4, min_subtotal = $5, category_ids = $6,
+			furniture_ids = $7, starts_at = $8, ends_at = $9, usage_limit = $10, per_user_limit = $11,
+			stackable = $12, version = version + 1
+		WHERE coupon_id = $13 AND version = $14
+		RETURNING version`
+
+	args := []any{
+		coupon.Code,
+		coupon.Description,
+		coupon.DiscountType,
+		couponAmount(*coupon),
+		coupon.MinSubtotal,
+		coupon.CategoryIDs,
+		coupon.FurnitureIDs,
+		coupon.StartsAt,
+		coupon.EndsAt,
+		coupon.UsageLimit,
+		coupon.PerUserLimit,
+		coupon.Stackable,
+		coupon.CouponID,
+		coupon.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := c.DB.QueryRowContext(ctx, query, args...).Scan(&coupon.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return couponWriteError(err)
+		}
+	}
+	return nil
+}
+
+// Delete a specific coupon record from the database. A coupon that has
+// been redeemed cannot be deleted, so that the orders keep track of their
+// discounts, and ErrCouponInUse is returned instead.
+func (c CouponRepository) Delete(id int64) error {
+	query := `
+		DELETE FROM coupon
+		WHERE coupon_id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := c.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "violates foreign key constraint"):
+			return ErrCouponInUse
+		default:
+			return err
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// ApplyToCart applies a specific coupon to the cart of a user and returns
+// the cart with its discounts. The coupon is only kept when it can be used
+// with the current items and the coupons already applied, the reason it
+// cannot is returned otherwise as a promotions.Rejection.
+func (c CouponRepository) ApplyToCart(userID, couponID int64) (Cart, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := c.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return Cart{}, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO cart_coupon(user_id, coupon_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`
+
+	if _, err = tx.ExecContext(ctx, query, userID, couponID); err != nil {
+		return Cart{}, err
+	}
+
+	cart, err := getCart(ctx, tx, userID, false)
+	if err != nil {
+		return Cart{}, err
+	}
+
+	for _, coupon := range cart.coupons {
+		if coupon.CouponID == couponID {
+			if err = cart.Err(coupon.Code); err != nil {
+				return Cart{}, err
+			}
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return Cart{}, err
+	}
+	return cart, nil
+}
+
+// RemoveFromCart removes the coupon with the provided code from the cart
+// of a user.
+func (c CouponRepository) RemoveFromCart(userID int64, code string) error {
+	query := `
+		DELETE FROM cart_coupon cc
+		USING coupon c
+		WHERE cc.coupon_id = c.coupon_id AND cc.user_id = $1 AND UPPER(c.code) = UPPER($2)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := c.DB.ExecContext(ctx, query, userID, code)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// applyCoupons loads the coupons applied to the cart of a user and
+// computes the discounts they give. When forUpdate is set the coupons are
+// locked, so that concurrent checkouts cannot go over their usage limits.
+func applyCoupons(ctx context.Context, q queryer, userID int64, cart *Cart, forUpdate bool) error {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM coupon c
+		JOIN cart_coupon cc ON cc.coupon_id = c.coupon_id
+		WHERE cc.user_id = $1
+		ORDER BY cc.created_at, c.coupon_id`, couponColumns)
+
+	if forUpdate {
+		query += " FOR UPDATE OF c"
+	}
+
+	rows, err := q.QueryContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var couponIDs []int64
+	for rows.Next() {
+		var coupon promotions.Coupon
+		if err = rows.Scan(couponScanDest(&coupon)...); err != nil {
+			return err
+		}
+		cart.coupons = append(cart.coupons, coupon)
+		couponIDs = append(couponIDs, coupon.CouponID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	lines := make([]promotions.Line, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		lines = append(lines, promotions.Line{FurnitureID: item.FurnitureID, Subtotal: item.Subtotal})
+	}
+
+	var usage map[int64]promotions.Usage
+	if len(cart.coupons) > 0 {
+		usage, err = getCouponUsage(ctx, q, userID, couponIDs)
+		if err != nil {
+			return err
+		}
+
+		if err = setLineCategories(ctx, q, lines); err != nil {
+			return err
+		}
+	}
+
+	cart.Breakdown = promotions.Apply(cart.coupons, lines, usage, money.DefaultCurrency, time.Now())
+	return nil
+}
+
+// getCouponUsage returns how many times each of the provided coupons has
+// been redeemed, by every user and by a specific user, by coupon id. Only
+// the redemptions of orders that did not fail or expire are counted, an
+// order pending payment past its expiry counting as expired even before
+// it is swept.
+func getCouponUsage(ctx context.Context, q queryer, userID int64, couponIDs []int64) (map[int64]promotions.Usage, error) {
+	query := `
+		SELECT r.coupon_id, COUNT(*), COUNT(*) FILTER (WHERE r.user_id = $2)
+		FROM coupon_redemption r
+		JOIN orders o ON o.order_id = r.order_id
+		WHERE r.coupon_id = ANY($1) AND o.status NOT IN ('payment_failed', 'expired', 'cancelled')
+		AND NOT (o.status = 'pending_payment' AND o.expires_at <= NOW())
+		GROUP BY r.coupon_id`
+
+	rows, err := q.QueryContext(ctx, query, couponIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usage := make(map[int64]promotions.Usage)
+	for rows.Next() {
+		var couponID int64
+		var u promotions.Usage
+		if err = rows.Scan(&couponID, &u.Total, &u.User); err != nil {
+			return nil, err
+		}
+		usage[couponID] = u
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// setLineCategories sets the category of the furniture of every line,
+// along with every ancestor of it.
+func setLineCategories(ctx context.Context, q queryer, lines []promotions.Line) error {
+	furnitureIDs := make([]int64, 0, len(lines))
+	for _, line := range lines {
+		furnitureIDs = append(furnitureIDs, line.FurnitureID)
+	}
+
+	query := `
+		WITH RECURSIVE ancestors AS (
+			SELECT f.furniture_id, f.category_id FROM furniture f WHERE f.furniture_id = ANY($1)
+			UNION
+			SELECT a.furniture_id, c.parent_id
+			FROM category c
+			JOIN ancestors a ON c.category_id = a.category_id
+			WHERE c.parent_id IS NOT NULL
+		)
+		SELECT furniture_id, category_id FROM ancestors`
+
+	rows, err := q.QueryContext(ctx, query, furnitureIDs)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	categories := make(map[int64][]int64)
+	for rows.Next() {
+		var furnitureID, categoryID int64
+		if err = rows.Scan(&furnitureID, &categoryID); err != nil {
+			return err
+		}
+		categories[furnitureID] = append(categories[furnitureID], categoryID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range lines {
+		lines[i].CategoryIDs = categories[lines[i].FurnitureID]
+	}
+	return nil
+}
+
+// couponAmount returns the value stored in the amount column of the
+// coupon, which is the percentage or the fixed amount it takes off.
+func couponAmount(coupon promotions.Coupon) any {
+	if coupon.PercentOff != nil {
+		return *coupon.PercentOff
+	}
+	return coupon.AmountOff
+}
+
+// couponWriteError converts the constraint violations raised when
+// inserting or updating a coupon to the matching custom error.
+func couponWriteError(err error) error {
+	switch {
+	case strings.Contains(err.Error(), `duplicate key value violates unique constraint "coupon_code_idx"`):
+		return ErrDuplicateCode
+	default:
+		return err
+	}
+}
@@ -0,0 +1,120 @@
+package data
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidCursor is a custom error that is returned when a cursor
+// cannot be decoded or its signature does not match.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is a struct that holds the position of a record in a listing,
+// keyed on the value of the sort column and the record id. A cursor
+// with a zero ID points at the start of the listing. When Backward is
+// true, the records right before the position are returned.
+type Cursor struct {
+	Sort     string  `json:"s"`
+	Value    *string `json:"v,omitempty"`
+	ID       int64   `json:"id,omitempty"`
+	Backward bool    `json:"b,omitempty"`
+}
+
+// cursorKey is the sort value and id of a listed record, used for
+// creating the cursors of the next and previous pages.
+type cursorKey struct {
+	value *string
+	id    int64
+}
+
+// EncodeCursor returns the opaque form of the cursor, signed with the
+// secret using HMAC-SHA256 so that clients cannot forge it.
+func EncodeCursor(cursor Cursor, secret []byte) string {
+	payload, _ := json.Marshal(cursor)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// DecodeCursor verifies the signature of an opaque cursor and returns
+// the cursor it holds.
+func DecodeCursor(s string, secret []byte) (Cursor, error) {
+	encodedPayload, encodedSignature, found := strings.Cut(s, ".")
+	if !found {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	if err = json.Unmarshal(payload, &cursor); err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+// paginateCursor trims the records fetched in cursor mode to the page
+// size and calculates the cursors of the next and previous pages. The
+// queries fetch one record more than the page size to know whether
+// there is a page beyond, and fetch backward pages in reverse order.
+func paginateCursor[T any](records []T, keys []cursorKey, filters Filters) ([]T, Metadata) {
+	cursor := filters.Cursor
+	hasMore := len(records) > filters.PageSize
+	if hasMore {
+		records = records[:filters.PageSize]
+		keys = keys[:filters.PageSize]
+	}
+
+	if cursor.Backward {
+		slices.Reverse(records)
+		slices.Reverse(keys)
+	}
+
+	metadata := Metadata{PageSize: filters.PageSize}
+	if len(records) == 0 {
+		return records, metadata
+	}
+
+	first, last := keys[0], keys[len(keys)-1]
+
+	// Walking forward, there is a previous page unless we started from
+	// the beginning, and a next page when more records were fetched.
+	// Walking backward, it is the other way around.
+	hasNext := hasMore
+	hasPrev := cursor.ID != 0
+	if cursor.Backward {
+		hasNext = true
+		hasPrev = hasMore
+	}
+
+	if hasNext {
+		next := Cursor{Sort: filters.Sort, Value: last.value, ID: last.id}
+		metadata.NextCursor = EncodeCursor(next, filters.CursorSecret)
+	}
+	if hasPrev {
+		prev := Cursor{Sort: filters.Sort, Value: first.value, ID: first.id, Backward: true}
+		metadata.PrevCursor = EncodeCursor(prev, filters.CursorSecret)
+	}
+	return records, metadata
+}
@@ -0,0 +1,191 @@
+package data
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncodeDecodeCursor(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	value := "149.99"
+
+	tests := []struct {
+		name   string
+		cursor Cursor
+	}{
+		{"start", Cursor{Sort: "price"}},
+		{"forward", Cursor{Sort: "price", Value: &value, ID: 42}},
+		{"backward", Cursor{Sort: "-price", Value: &value, ID: 42, Backward: true}},
+		{"id only", Cursor{Sort: "id", ID: 7}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded := EncodeCursor(tt.cursor, secret)
+			if strings.ContainsAny(encoded, "+/= ") {
+				t.Errorf("EncodeCursor() = %q, want a URL safe string", encoded)
+			}
+
+			got, err := DecodeCursor(encoded, secret)
+			if err != nil {
+				t.Fatalf("DecodeCursor() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.cursor) {
+				t.Errorf("DecodeCursor() = %+v, want %+v", got, tt.cursor)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorRejects(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	valid := EncodeCursor(Cursor{Sort: "price", ID: 42}, secret)
+	payload, signature, _ := strings.Cut(valid, ".")
+
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"s":"price","id":1}`))
+
+	tests := []struct {
+		name   string
+		s      string
+		secret []byte
+	}{
+		{"empty", "", secret},
+		{"no signature", payload, secret},
+		{"empty signature", payload + ".", secret},
+		{"payload not base64", "!!!." + signature, secret},
+		{"signature not base64", payload + ".!!!", secret},
+		{"forged payload", forged + "." + signature, secret},
+		{"truncated signature", payload + "." + signature[:len(signature)-4], secret},
+		{"wrong secret", valid, []byte("another secret of thirty-two by")},
+		{"empty secret", valid, nil},
+		{"signed payload not json", signed([]byte("not json"), secret), secret},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeCursor(tt.s, tt.secret)
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("DecodeCursor(%q) error = %v, want %v", tt.s, err, ErrInvalidCursor)
+			}
+		})
+	}
+}
+
+// signed returns the payload signed with the secret the way EncodeCursor
+// signs cursors.
+func signed(payload, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestPaginateCursor(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	keys := func(ids ...int64) []cursorKey {
+		k := make([]cursorKey, len(ids))
+		for i, id := range ids {
+			k[i] = cursorKey{id: id}
+		}
+		return k
+	}
+
+	tests := []struct {
+		name     string
+		cursor   Cursor
+		records  []int64
+		want     []int64
+		wantNext *Cursor
+		wantPrev *Cursor
+	}{
+		{
+			name:     "first page with more",
+			cursor:   Cursor{Sort: "id"},
+			records:  []int64{1, 2, 3},
+			want:     []int64{1, 2},
+			wantNext: &Cursor{Sort: "id", ID: 2},
+		},
+		{
+			name:    "only page",
+			cursor:  Cursor{Sort: "id"},
+			records: []int64{1, 2},
+			want:    []int64{1, 2},
+		},
+		{
+			name:     "middle page forward",
+			cursor:   Cursor{Sort: "id", ID: 2},
+			records:  []int64{3, 4, 5},
+			want:     []int64{3, 4},
+			wantNext: &Cursor{Sort: "id", ID: 4},
+			wantPrev: &Cursor{Sort: "id", ID: 3, Backward: true},
+		},
+		{
+			name:     "last page forward",
+			cursor:   Cursor{Sort: "id", ID: 4},
+			records:  []int64{5},
+			want:     []int64{5},
+			wantPrev: &Cursor{Sort: "id", ID: 5, Backward: true},
+		},
+		{
+			name:     "backward with more",
+			cursor:   Cursor{Sort: "id", ID: 5, Backward: true},
+			records:  []int64{4, 3, 2},
+			want:     []int64{3, 4},
+			wantNext: &Cursor{Sort: "id", ID: 4},
+			wantPrev: &Cursor{Sort: "id", ID: 3, Backward: true},
+		},
+		{
+			name:     "backward to the start",
+			cursor:   Cursor{Sort: "id", ID: 3, Backward: true},
+			records:  []int64{2, 1},
+			want:     []int64{1, 2},
+			wantNext: &Cursor{Sort: "id", ID: 2},
+		},
+		{
+			name:    "empty",
+			cursor:  Cursor{Sort: "id", ID: 9},
+			records: []int64{},
+			want:    []int64{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filters := Filters{PageSize: 2, Sort: "id", Cursor: &tt.cursor, CursorSecret: secret}
+			records := append([]int64{}, tt.records...)
+
+			got, metadata := paginateCursor(records, keys(tt.records...), filters)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("records = %v, want %v", got, tt.want)
+			}
+
+			checkCursor(t, "next", metadata.NextCursor, tt.wantNext, secret)
+			checkCursor(t, "previous", metadata.PrevCursor, tt.wantPrev, secret)
+		})
+	}
+}
+
+// checkCursor checks that the opaque cursor holds want, or is empty when
+// want is nil.
+func checkCursor(t *testing.T, name, encoded string, want *Cursor, secret []byte) {
+	t.Helper()
+
+	if want == nil {
+		if encoded != "" {
+			t.Errorf("%s cursor = %q, want none", name, encoded)
+		}
+		return
+	}
+
+	got, err := DecodeCursor(encoded, secret)
+	if err != nil {
+		t.Fatalf("%s cursor %q: %v", name, encoded, err)
+	}
+	if !reflect.DeepEqual(got, *want) {
+		t.Errorf("%s cursor = %+v, want %+v", name, got, *want)
+	}
+}
@@ -0,0 +1,30 @@
+package data
+
+import (
+	"github.com/hayohtee/fumode/internal/validator"
+	"time"
+)
+
+// DeliverySlot is a struct that holds a window in which a zone is
+// delivered, and how many deliveries can be booked in it. Available is the
+// capacity left once the bookings are taken off.
+type DeliverySlot struct {
+	SlotID    int64     `json:"slot_id"`
+	ZoneID    int64     `json:"zone_id"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	Capacity  int       `json:"capacity"`
+	Booked    int       `json:"booked"`
+	Available int       `json:"available"`
+	CreatedAt time.Time `json:"created_at"`
+	Version   int       `json:"version"`
+}
+
+func ValidateDeliverySlot(v *validator.Validator, slot DeliverySlot) {
+	v.Check(slot.ZoneID > 0, "zone_id", "must be provided")
+	v.Check(!slot.StartsAt.IsZero(), "starts_at", "must be provided")
+	v.Check(!slot.EndsAt.IsZero(), "ends_at", "must be provided")
+	v.Check(slot.EndsAt.After(slot.StartsAt), "ends_at", "must be after starts_at")
+	v.Check(slot.EndsAt.Sub(slot.StartsAt) <= 24*time.Hour, "ends_at", "must not be more than 24 hours after starts_at")
+	v.Check(validator.Between(slot.Capacity, 1, 1000), "capacity", "must be between 1 and 1000")
+}
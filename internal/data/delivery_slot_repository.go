@@ -0,0 +1,345 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/hayohtee/fumode/internal/shipping"
+	"strings"
+	"time"
+)
+
+// deliverySlotColumns is the list of columns selected for a delivery slot,
+// in the order expected by DeliverySlot.scanDest.
+const deliverySlotColumns = `slot_id, zone_id, starts_at, ends_at, capacity, booked, created_at, version`
+
+// scanDest returns the destinations for scanning the delivery slot columns.
+func (slot *DeliverySlot) scanDest() []any {
+	return []any{
+		&slot.SlotID,
+		&slot.ZoneID,
+		&slot.StartsAt,
+		&slot.EndsAt,
+		&slot.Capacity,
+		&slot.Booked,
+		&slot.CreatedAt,
+		&slot.Version,
+	}
+}
+
+// DeliverySlotRepository is a type which wraps around a sql.DB connection
+// pool and provide methods for managing the delivery slots of the zones,
+// and for rescheduling the deliveries booked in them, to and from the
+// database.
+type DeliverySlotRepository struct {
+	DB *sql.DB
+}
+
+// Insert a delivery slot record to the database. It returns
+// ErrDuplicateSlot if the zone already has a slot starting at the same
+// time and ErrInvalidZone if the zone does not exist.
+func (d DeliverySlotRepository) Insert(slot *DeliverySlot) error {
+	query := `
+		INSERT INTO delivery_slot(zone_id, starts_at, ends_at, capacity)
+		VALUES ($1, $2, $3, $4)
+		RETURNING slot_id, booked, created_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []any{slot.ZoneID, slot.StartsAt, slot.EndsAt, slot.Capacity}
+	err := d.DB.QueryRowContext(ctx, query, args...).Scan(&slot.SlotID, &slot.Booked, &slot.CreatedAt, &slot.Version)
+	if err != nil {
+		return deliverySlotWriteError(err)
+	}
+	slot.Available = slot.Capacity - slot.Booked
+	return nil
+}
+
+// GetByID retrieve a specific delivery slot from the database given the id.
+func (d DeliverySlotRepository) GetByID(id int64) (DeliverySlot, error) {
+	query := `
+		SELECT ` + deliverySlotColumns + `
+		FROM delivery_slot
+		WHERE slot_id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var slot DeliverySlot
+	err := d.DB.QueryRowContext(ctx, query, id).Scan(slot.scanDest()...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return DeliverySlot{}, ErrRecordNotFound
+		default:
+			return DeliverySlot{}, err
+		}
+	}
+	slot.Available = slot.Capacity - slot.Booked
+	return slot, nil
+}
+
+// GetAll retrieve the delivery slots of a zone, or of every zone when
+// zoneID is zero, that start between from and to.
+func (d DeliverySlotRepository) GetAll(zoneID int64, from, to time.Time) ([]DeliverySlot, error) {
+	query := `
+		SELECT ` + deliverySlotColumns + `
+		FROM delivery_slot
+		WHERE ($1::BIGINT = 0 OR zone_id = $1) AND starts_at >= $2 AND starts_at < $3
+		ORDER BY starts_at, zone_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return queryDeliverySlots(ctx, d.DB, query, zoneID, from, to)
+}
+
+// GetAvailable retrieve the delivery slots that can still be booked for a
+// shipping address in the region, which are those of its zone with some
+// capacity left that start after cutoff has elapsed and before to.
+func (d DeliverySlotRepository) GetAvailable(region shipping.Region, cutoff time.Duration, to time.Time) ([]DeliverySlot, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	zoneID, err := findShippingZone(ctx, d.DB, region)
+	if err != nil {
+		return nil, err
+	}
+
+	if zoneID == nil {
+		return []DeliverySlot{}, nil
+	}
+
+	query := `
+		SELECT ` + deliverySlotColumns + `
+		FROM delivery_slot
+		WHERE zone_id = $1 AND booked < capacity AND starts_at > $2 AND starts_at < $3
+		ORDER BY starts_at`
+
+	return queryDeliverySlots(ctx, d.DB, query, *zoneID, time.Now().Add(cutoff), to)
+}
+
+// Update a specific delivery slot record in the database. The window of the
+// shipments booked in the slot is moved with it. It returns ErrSlotBooked
+// if the capacity is below the bookings.
+func (d DeliverySlotRepository) Update(slot *DeliverySlot) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE delivery_slot
+		SET starts_at = $1, ends_at = $2, capacity = $3, version = version + 1
+		WHERE slot_id = $4 AND version = $5
+		RETURNING booked, version`
+
+	args := []any{slot.StartsAt, slot.EndsAt, slot.Capacity, slot.SlotID, slot.Version}
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&slot.Booked, &slot.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return deliverySlotWriteError(err)
+		}
+	}
+	slot.Available = slot.Capacity - slot.Booked
+
+	query = `
+		UPDATE shipment
+		SET delivery_window_start = $1, delivery_window_end = $2
+		WHERE delivery_slot_id = $3`
+
+	if _, err = tx.ExecContext(ctx, query, slot.StartsAt, slot.EndsAt, slot.SlotID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes a specific delivery slot from the database. It returns
+// ErrSlotBooked if deliveries are booked in it.
+func (d DeliverySlotRepository) Delete(id int64) error {
+	query := `
+		WITH deleted AS (
+			DELETE FROM delivery_slot
+			WHERE slot_id = $1 AND booked = 0
+			RETURNING slot_id
+		)
+		SELECT (SELECT COUNT(*) FROM deleted), EXISTS (SELECT 1 FROM delivery_slot WHERE slot_id = $1)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var deleted int
+	var exists bool
+	if err := d.DB.QueryRowContext(ctx, query, id).Scan(&deleted, &exists); err != nil {
+		return err
+	}
+
+	switch {
+	case deleted > 0:
+		return nil
+	case exists:
+		return ErrSlotBooked
+	default:
+		return ErrRecordNotFound
+	}
+}
+
+// Reschedule moves the delivery of an order to another slot of the same
+// zone, as long as both slots start after cutoff has elapsed. The order
+// must be pending payment or paid and have a delivery slot, otherwise
+// ErrNotReschedulable is returned. It returns ErrSlotUnavailable if the
+// new slot cannot be booked.
+func (d DeliverySlotRepository) Reschedule(orderID, slotID int64, cutoff time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT o.status, s.shipment_id, s.delivery_slot_id, ds.zone_id, ds.starts_at
+		FROM orders o
+		JOIN shipment s ON o.shipment_id = s.shipment_id
+		LEFT JOIN delivery_slot ds ON s.delivery_slot_id = ds.slot_id
+		WHERE o.order_id = $1
+		FOR UPDATE OF o, s`
+
+	var status string
+	var shipmentID int64
+	var currentID, zoneID *int64
+	var startsAt *time.Time
+	err = tx.QueryRowContext(ctx, query, orderID).Scan(&status, &shipmentID, &currentID, &zoneID, &startsAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	closes := time.Now().Add(cutoff)
+	if (status != OrderStatusPendingPayment && status != OrderStatusPaid) || currentID == nil || !startsAt.After(closes) {
+		return ErrNotReschedulable
+	}
+
+	if *currentID == slotID {
+		return nil
+	}
+
+	startsAt, endsAt, err := bookDeliverySlot(ctx, tx, slotID, *zoneID, cutoff)
+	if err != nil {
+		return err
+	}
+
+	query = `UPDATE delivery_slot SET booked = booked - 1 WHERE slot_id = $1`
+	if _, err = tx.ExecContext(ctx, query, *currentID); err != nil {
+		return err
+	}
+
+	query = `
+		UPDATE shipment
+		SET delivery_slot_id = $1, delivery_window_start = $2, delivery_window_end = $3
+		WHERE shipment_id = $4`
+
+	if _, err = tx.ExecContext(ctx, query, slotID, startsAt, endsAt, shipmentID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// queryDeliverySlots runs a query selecting the delivery slot columns.
+func queryDeliverySlots(ctx context.Context, q queryer, query string, args ...any) ([]DeliverySlot, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	slots := []DeliverySlot{}
+	for rows.Next() {
+		var slot DeliverySlot
+		if err = rows.Scan(slot.scanDest()...); err != nil {
+			return nil, err
+		}
+		slot.Available = slot.Capacity - slot.Booked
+		slots = append(slots, slot)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return slots, nil
+}
+
+// bookDeliverySlot takes one delivery off the capacity of a slot of the
+// zone and returns its window. The slot must start after cutoff has
+// elapsed, otherwise, or if it is full, ErrSlotUnavailable is returned.
+func bookDeliverySlot(ctx context.Context, q queryer, slotID, zoneID int64, cutoff time.Duration) (startsAt, endsAt *time.Time, err error) {
+	query := `
+		UPDATE delivery_slot
+		SET booked = booked + 1
+		WHERE slot_id = $1 AND zone_id = $2 AND booked < capacity AND starts_at > $3
+		RETURNING starts_at, ends_at`
+
+	err = q.QueryRowContext(ctx, query, slotID, zoneID, time.Now().Add(cutoff)).Scan(&startsAt, &endsAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, nil, ErrSlotUnavailable
+		default:
+			return nil, nil, err
+		}
+	}
+	return startsAt, endsAt, nil
+}
+
+// releaseDeliverySlots gives the delivery slots booked by the orders back
+// to their capacity, as the orders will not be delivered. The window is
+// kept on the shipments.
+func releaseDeliverySlots(ctx context.Context, q queryer, orderIDs []int64) error {
+	query := `
+		WITH released AS (
+			UPDATE shipment s
+			SET delivery_slot_id = NULL
+			FROM orders o, shipment old
+			WHERE o.order_id = ANY($1) AND o.shipment_id = s.shipment_id
+			AND old.shipment_id = s.shipment_id AND old.delivery_slot_id IS NOT NULL
+			RETURNING old.delivery_slot_id
+		)
+		UPDATE delivery_slot ds
+		SET booked = ds.booked - r.bookings
+		FROM (SELECT delivery_slot_id, COUNT(*) AS bookings FROM released GROUP BY delivery_slot_id) r
+		WHERE ds.slot_id = r.delivery_slot_id`
+
+	_, err := q.ExecContext(ctx, query, orderIDs)
+	return err
+}
+
+// deliverySlotWriteError maps the constraint violations of a delivery slot
+// write to the repository errors.
+func deliverySlotWriteError(err error) error {
+	switch {
+	case strings.Contains(err.Error(), `duplicate key value violates unique constraint "delivery_slot_window_idx"`):
+		return ErrDuplicateSlot
+	case strings.Contains(err.Error(), `violates foreign key constraint "delivery_slot_zone_id_fkey"`):
+		return ErrInvalidZone
+	case strings.Contains(err.Error(), `violates check constraint "delivery_slot_capacity_check"`):
+		return ErrSlotBooked
+	default:
+		return err
+	}
+}
@@ -0,0 +1,92 @@
+package data
+
+import (
+	"github.com/hayohtee/fumode/internal/money"
+	"github.com/hayohtee/fumode/internal/validator"
+	"math"
+	"time"
+)
+
+// ExchangeRate is a struct that holds the rate the prices are converted
+// at from the base currency, money.DefaultCurrency, to Currency. Rate is
+// the amount of Currency worth one unit of the base currency.
+type ExchangeRate struct {
+	Currency  string    `json:"currency"`
+	Rate      float64   `json:"rate"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Version   int       `json:"version"`
+}
+
+// BaseExchangeRate returns the rate of the base currency to itself, which
+// is not stored.
+func BaseExchangeRate() ExchangeRate {
+	return ExchangeRate{Currency: money.DefaultCurrency, Rate: 1}
+}
+
+// Convert returns the amount of the base currency converted at the rate.
+func (r ExchangeRate) Convert(amount money.Money) money.Money {
+	return amount.Convert(r.Currency, r.Rate)
+}
+
+func ValidateExchangeRate(v *validator.Validator, rate ExchangeRate) {
+	v.Check(validator.Matches(rate.Currency, money.CurrencyRX), "currency", "must be a valid ISO 4217 currency code")
+	v.Check(rate.Currency != money.DefaultCurrency, "currency", "must not be the base currency")
+	v.Check(rate.Rate > 0, "rate", "must be greater than zero")
+	v.Check(rate.Rate < 10_000_000_000, "rate", "must be less than 10000000000")
+	v.Check(rate.Rate == math.Round(rate.Rate*1e8)/1e8, "rate", "must not have more than 8 decimal places")
+}
+
+// ConvertPrices converts every price of the furniture, and of its
+// variants and components, from the base currency at the rate.
+func (furniture *Furniture) ConvertPrices(rate ExchangeRate) {
+	furniture.Price = rate.Convert(furniture.Price)
+	furniture.CompareAtPrice = convertOptional(rate, furniture.CompareAtPrice)
+	furniture.LowestPrice = convertOptional(rate, furniture.LowestPrice)
+
+	for i := range furniture.Variants {
+		variant := &furniture.Variants[i]
+		variant.PriceDelta = convertOptional(rate, variant.PriceDelta)
+		variant.PriceOverride = convertOptional(rate, variant.PriceOverride)
+		variant.Price = rate.Convert(variant.Price)
+	}
+
+	for i := range furniture.Components {
+		furniture.Components[i].UnitPrice = rate.Convert(furniture.Components[i].UnitPrice)
+	}
+}
+
+// ConvertPrices converts every amount of the cart from the base currency
+// at the rate. Each amount is converted on its own, so the converted
+// total can differ by a minor unit from the sum of the converted lines.
+func (cart *Cart) ConvertPrices(rate ExchangeRate) {
+	for i := range cart.Items {
+		cart.Items[i].UnitPrice = rate.Convert(cart.Items[i].UnitPrice)
+		cart.Items[i].Subtotal = rate.Convert(cart.Items[i].Subtotal)
+	}
+
+	for i := range cart.Discounts {
+		cart.Discounts[i].EligibleSubtotal = rate.Convert(cart.Discounts[i].EligibleSubtotal)
+		cart.Discounts[i].Amount = rate.Convert(cart.Discounts[i].Amount)
+	}
+
+	cart.Subtotal = rate.Convert(cart.Subtotal)
+	cart.DiscountTotal = rate.Convert(cart.DiscountTotal)
+	cart.Total = rate.Convert(cart.Total)
+}
+
+// ConvertWishlistPrices converts the price of every wishlist item from the
+// base currency at the rate.
+func ConvertWishlistPrices(items []WishlistItem, rate ExchangeRate) {
+	for i := range items {
+		items[i].Price = rate.Convert(items[i].Price)
+	}
+}
+
+// convertOptional converts the amount at the rate, when it is not nil.
+func convertOptional(rate ExchangeRate, amount *money.Money) *money.Money {
+	if amount == nil {
+		return nil
+	}
+	converted := rate.Convert(*amount)
+	return &converted
+}
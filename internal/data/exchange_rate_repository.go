@@ -0,0 +1,147 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/hayohtee/fumode/internal/money"
+	"time"
+)
+
+// ExchangeRateRepository is a type which wraps around a sql.DB connection
+// pool and provide methods for managing the exchange rates to and from the
+// database.
+type ExchangeRateRepository struct {
+	DB *sql.DB
+}
+
+// Get retrieve the exchange rate of a specific currency from the database.
+// The base currency always has a rate of 1.
+func (e ExchangeRateRepository) Get(currency string) (ExchangeRate, error) {
+	if currency == money.DefaultCurrency {
+		return BaseExchangeRate(), nil
+	}
+
+	query := `
+		SELECT currency, rate, updated_at, version
+		FROM exchange_rates
+		WHERE currency = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var rate ExchangeRate
+	err := e.DB.QueryRowContext(ctx, query, currency).Scan(&rate.Currency, &rate.Rate, &rate.UpdatedAt, &rate.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ExchangeRate{}, ErrRecordNotFound
+		default:
+			return ExchangeRate{}, err
+		}
+	}
+	return rate, nil
+}
+
+// GetAll retrieve every exchange rate from the database.
+func (e ExchangeRateRepository) GetAll() ([]ExchangeRate, error) {
+	query := `
+		SELECT currency, rate, updated_at, version
+		FROM exchange_rates
+		ORDER BY currency`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := e.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := []ExchangeRate{}
+	for rows.Next() {
+		var rate ExchangeRate
+		err = rows.Scan(&rate.Currency, &rate.Rate, &rate.UpdatedAt, &rate.Version)
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// Set inserts the exchange rate of a currency, or replaces its current
+// rate.
+func (e ExchangeRateRepository) Set(rate *ExchangeRate) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return setExchangeRate(ctx, e.DB, rate)
+}
+
+// Import sets every exchange rate in a single transaction, so that either
+// all of them are imported or none is. It returns the number of rates
+// imported.
+func (e ExchangeRateRepository) Import(rates []ExchangeRate) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := e.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	for i := range rates {
+		if err = setExchangeRate(ctx, tx, &rates[i]); err != nil {
+			return 0, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(rates), nil
+}
+
+// Delete removes the exchange rate of a specific currency from the
+// database, after which the prices can no longer be converted to it.
+func (e ExchangeRateRepository) Delete(currency string) error {
+	query := `DELETE FROM exchange_rates WHERE currency = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := e.DB.ExecContext(ctx, query, currency)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// setExchangeRate upserts the exchange rate of a currency, bumping its
+// version when it already exists.
+func setExchangeRate(ctx context.Context, q queryer, rate *ExchangeRate) error {
+	query := `
+		INSERT INTO exchange_rates(currency, rate)
+		VALUES ($1, $2)
+		ON CONFLICT (currency) DO UPDATE
+		SET rate = EXCLUDED.rate, updated_at = NOW(), version = exchange_rates.version + 1
+		RETURNING updated_at, version`
+
+	return q.QueryRowContext(ctx, query, rate.Currency, rate.Rate).Scan(&rate.UpdatedAt, &rate.Version)
+}
@@ -0,0 +1,18 @@
+package data
+
+// FacetNames holds the names of the facets computed for the
+// furniture listing.
+var FacetNames = []string{"category", "material", "color", "price", "rating", "in_stock", "availability"}
+
+// Facets maps each facet name to the counts of its values.
+type Facets map[string][]FacetValue
+
+// FacetValue is a struct that holds the number of furniture that
+// would be listed if the facet value was added to the filters. Value
+// is what should be sent back as filter, and Label what should be
+// displayed to the customer.
+type FacetValue struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
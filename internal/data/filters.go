@@ -1,6 +1,7 @@
 package data
 
 import (
+	"fmt"
 	"github.com/hayohtee/fumode/internal/validator"
 	"strings"
 )
@@ -10,6 +11,10 @@ type Filters struct {
 	PageSize     int
 	Sort         string
 	SortSafeList []string
+	// Cursor is set when the listing is paginated with cursors rather
+	// than page numbers, CursorSecret is the key the cursors are signed with.
+	Cursor       *Cursor
+	CursorSecret []byte
 }
 
 func ValidateFilters(v *validator.Validator, f Filters) {
@@ -18,6 +23,11 @@ func ValidateFilters(v *validator.Validator, f Filters) {
 	v.Check(f.PageSize > 0, "page_size", "must be greater than zero")
 	v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100")
 	v.Check(validator.PermittedValue(f.Sort, f.SortSafeList...), "sort", "invalid sort value")
+
+	if f.Cursor != nil {
+		v.Check(f.Page == 1, "page", "must not be provided together with cursor")
+		v.Check(f.Cursor.ID == 0 || f.Cursor.Sort == f.Sort, "cursor", "does not match the sort value")
+	}
 }
 
 // sortColumn check that the client-provided Sort field matches one of the entries
@@ -41,12 +51,85 @@ func (f Filters) sortDirection() string {
 	return "ASC"
 }
 
-// limit returns the  maximum number of records to return.
+// limit returns the  maximum number of records to return. In cursor mode,
+// one more record is fetched to know whether there is a page beyond.
 func (f Filters) limit() int {
+	if f.Cursor != nil {
+		return f.PageSize + 1
+	}
 	return f.PageSize
 }
 
 // offset returns the starting offset.
 func (f Filters) offset() int {
+	if f.Cursor != nil {
+		return 0
+	}
 	return (f.Page - 1) * f.PageSize
 }
+
+// orderBy returns the ORDER BY expressions for the sort column of the table
+// alias, using the id column as tie breaker. NULL values are always listed
+// last. When walking backward from a cursor, the order is reversed so that
+// the records right before the cursor come first.
+func (f Filters) orderBy(alias, idColumn string) string {
+	direction, idDirection, nulls := f.sortDirection(), "ASC", "NULLS LAST"
+
+	if f.Cursor != nil && f.Cursor.Backward {
+		direction, idDirection, nulls = reverseDirection(direction), "DESC", "NULLS FIRST"
+	}
+
+	return fmt.Sprintf("%s.%s %s %s, %s.%s %s", alias, f.sortColumn(), direction, nulls, alias, idColumn, idDirection)
+}
+
+// keysetCondition returns the condition selecting the records after the
+// cursor (or before it when walking backward), alongside its arguments.
+// The arguments are numbered from $n. Outside of cursor mode, or at the
+// start of the listing, the condition is always true.
+func (f Filters) keysetCondition(alias, idColumn string, n int) (string, []any) {
+	if f.Cursor == nil || f.Cursor.ID == 0 {
+		return "TRUE", nil
+	}
+
+	column := alias + "." + f.sortColumn()
+	id := alias + "." + idColumn
+
+	// The operator moving along the sort column in the walking direction.
+	op := ">"
+	if f.sortDirection() == "DESC" {
+		op = "<"
+	}
+
+	if f.Cursor.Backward {
+		if f.Cursor.Value == nil {
+			// NULL values are listed last, so every non NULL value is before.
+			return fmt.Sprintf("(%s IS NOT NULL OR %s < $%d)", column, id, n), []any{f.Cursor.ID}
+		}
+		return fmt.Sprintf("(%s %s $%d OR (%s = $%d AND %s < $%d))",
+				column, reverseOperator(op), n, column, n, id, n+1),
+			[]any{*f.Cursor.Value, f.Cursor.ID}
+	}
+
+	if f.Cursor.Value == nil {
+		return fmt.Sprintf("(%s IS NULL AND %s > $%d)", column, id, n), []any{f.Cursor.ID}
+	}
+	return fmt.Sprintf("(%s IS NULL OR %s %s $%d OR (%s = $%d AND %s > $%d))",
+			column, column, op, n, column, n, id, n+1),
+		[]any{*f.Cursor.Value, f.Cursor.ID}
+}
+
+// reverseDirection returns the opposite sort direction.
+func reverseDirection(direction string) string {
+	if direction == "DESC" {
+		return "ASC"
+	}
+	return "DESC"
+}
+
+// reverseOperator returns the opposite comparison operator.
+func reverseOperator(op string) string {
+	if op == "<" {
+		return ">"
+	}
+	return "<"
+}
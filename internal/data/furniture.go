@@ -1,15 +1,191 @@
 package data
 
+import (
+	"github.com/hayohtee/fumode/internal/money"
+	"github.com/hayohtee/fumode/internal/validator"
+	"time"
+)
+
+// The availability modes of a furniture. Backordered furniture can be
+// ordered beyond its stock and ships after its lead time, pre-ordered
+// furniture ships on its release date and discontinued furniture is
+// only sold until it is out of stock.
+const (
+	AvailabilityInStock      = "in_stock"
+	AvailabilityBackorder    = "backorder"
+	AvailabilityPreorder     = "preorder"
+	AvailabilityDiscontinued = "discontinued"
+)
+
+// Availabilities holds every availability mode of the furniture.
+var Availabilities = []string{
+	AvailabilityInStock,
+	AvailabilityBackorder,
+	AvailabilityPreorder,
+	AvailabilityDiscontinued,
+}
+
 // Furniture is a struct that holds information about
-// a specific furniture.
+// a specific furniture. AvailableStock is the stock minus the
+// quantities reserved by pending checkouts, and ReorderThreshold
+// the stock at or below which it is reported as low on stock.
+// LeadTimeDays is only set for backorders and ReleaseDate for
+// pre-orders. BundleDiscountPercent is only set for bundles priced at
+// a discount on their components. CompareAtPrice is the regular price
+// while the furniture is on sale.
 type Furniture struct {
-	FurnitureID int
-	Name        string
-	Description string
-	Price       float64
-	Stock       int
-	BannerURL   string
-	ImageURLs   []string
-	Category    string
-	Version     int
+	FurnitureID           int                 `json:"furniture_id"`
+	Name                  string              `json:"name"`
+	Description           string              `json:"description"`
+	Price                 money.Money         `json:"price"`
+	CompareAtPrice        *money.Money        `json:"compare_at_price"`
+	Stock                 int                 `json:"stock"`
+	AvailableStock        int                 `json:"available_stock"`
+	ReorderThreshold      int                 `json:"reorder_threshold"`
+	Availability          string              `json:"availability"`
+	LeadTimeDays          *int                `json:"lead_time_days"`
+	ReleaseDate           *time.Time          `json:"release_date"`
+	Type                  string              `json:"type"`
+	BundleDiscountPercent *float64            `json:"bundle_discount_percent,omitempty"`
+	BannerURL             string              `json:"banner_url"`
+	ImageURLs             []string            `json:"image_urls"`
+	CategoryID            int64               `json:"category_id"`
+	Category              string              `json:"category"`
+	Attributes            FurnitureAttributes `json:"attributes"`
+	Version               int                 `json:"version"`
+	// Options, Variants, Components and LowestPrice are only populated
+	// when showing a specific furniture. Options maps each option type to
+	// the values offered by the variants, Components lists what a bundle
+	// is made of and LowestPrice is the lowest price over the
+	// LowestPriceDays before the current price took effect.
+	Options     map[string][]string `json:"options,omitempty"`
+	Variants    []Variant           `json:"variants,omitempty"`
+	Components  []BundleComponent   `json:"components,omitempty"`
+	LowestPrice *money.Money        `json:"lowest_price,omitempty"`
+}
+
+// FurnitureAttributes holds the physical attributes of a furniture.
+// Dimensions are in centimeters and weight is in kilograms, they are
+// nil when unknown.
+type FurnitureAttributes struct {
+	WidthCm          *float64 `json:"width_cm"`
+	DepthCm          *float64 `json:"depth_cm"`
+	HeightCm         *float64 `json:"height_cm"`
+	SeatHeightCm     *float64 `json:"seat_height_cm"`
+	WeightKg         *float64 `json:"weight_kg"`
+	Materials        []string `json:"materials"`
+	AssemblyRequired bool     `json:"assembly_required"`
+	CareInstructions string   `json:"care_instructions"`
+	WarrantyMonths   int      `json:"warranty_months"`
+}
+
+// FurnitureFilters holds the criteria used for listing furniture.
+// The range filters are ignored when nil.
+type FurnitureFilters struct {
+	Name         string
+	CategoryID   int64
+	Material     string
+	Color        string
+	InStock      bool
+	Availability string
+	MinPrice     *money.Money
+	MaxPrice     *money.Money
+	MinRating    *float64
+	MinWidth     *float64
+	MaxWidth     *float64
+	MinDepth     *float64
+	MaxDepth     *float64
+	MinHeight    *float64
+	MaxHeight    *float64
+	MaxWeight    *float64
+}
+
+func ValidateFurnitureAttributes(v *validator.Validator, attributes FurnitureAttributes) {
+	dimensions := map[string]*float64{
+		"width_cm":       attributes.WidthCm,
+		"depth_cm":       attributes.DepthCm,
+		"height_cm":      attributes.HeightCm,
+		"seat_height_cm": attributes.SeatHeightCm,
+	}
+	for key, value := range dimensions {
+		if value != nil {
+			v.Check(validator.Between(*value, 0.1, 10_000), key, "must be between 0.1 and 10000 centimeters")
+		}
+	}
+
+	if attributes.SeatHeightCm != nil && attributes.HeightCm != nil {
+		v.Check(*attributes.SeatHeightCm <= *attributes.HeightCm, "seat_height_cm", "must not be more than the height")
+	}
+
+	if attributes.WeightKg != nil {
+		v.Check(validator.Between(*attributes.WeightKg, 0.01, 5_000), "weight_kg", "must be between 0.01 and 5000 kilograms")
+	}
+
+	v.Check(len(attributes.Materials) <= 10, "materials", "must not contain more than 10 materials")
+	v.Check(validator.Unique(attributes.Materials), "materials", "must not contain duplicate values")
+	for _, material := range attributes.Materials {
+		v.Check(material != "", "materials", "must not contain empty values")
+		v.Check(len(material) <= 50, "materials", "must not contain values more than 50 bytes long")
+	}
+
+	v.Check(len(attributes.CareInstructions) <= 2_000, "care_instructions", "must not be more than 2000 bytes long")
+	v.Check(validator.Between(attributes.WarrantyMonths, 0, 600), "warranty_months", "must be between 0 and 600")
+}
+
+func ValidateFurnitureFilters(v *validator.Validator, f FurnitureFilters) {
+	v.Check(f.CategoryID >= 0, "category_id", "must be a valid id")
+
+	if f.MinPrice != nil && f.MaxPrice != nil {
+		v.Check(f.MinPrice.Cmp(*f.MaxPrice) <= 0, "min_price", "must not be more than max_price")
+	}
+
+	ranges := []struct {
+		key      string
+		min, max *float64
+	}{
+		{"width", f.MinWidth, f.MaxWidth},
+		{"depth", f.MinDepth, f.MaxDepth},
+		{"height", f.MinHeight, f.MaxHeight},
+	}
+	for _, r := range ranges {
+		if r.min != nil && r.max != nil {
+			v.Check(*r.min <= *r.max, "min_"+r.key, "must not be more than max_"+r.key)
+		}
+	}
+
+	if f.MinRating != nil {
+		v.Check(validator.Between(*f.MinRating, 1, 5), "min_rating", "must be between 1 and 5")
+	}
+
+	if f.Availability != "" {
+		v.Check(validator.PermittedValue(f.Availability, Availabilities...), "availability", "invalid availability")
+	}
+}
+
+func ValidateFurnitureAvailability(v *validator.Validator, furniture Furniture) {
+	v.Check(validator.PermittedValue(furniture.Availability, Availabilities...), "availability", "invalid availability")
+
+	if furniture.Availability == AvailabilityBackorder {
+		v.Check(furniture.LeadTimeDays != nil, "lead_time_days", "must be provided for backorder")
+	} else {
+		v.Check(furniture.LeadTimeDays == nil, "lead_time_days", "must only be provided for backorder")
+	}
+	if furniture.LeadTimeDays != nil {
+		v.Check(validator.Between(*furniture.LeadTimeDays, 1, 365), "lead_time_days", "must be between 1 and 365")
+	}
+
+	if furniture.Availability == AvailabilityPreorder {
+		v.Check(furniture.ReleaseDate != nil, "release_date", "must be provided for preorder")
+	} else {
+		v.Check(furniture.ReleaseDate == nil, "release_date", "must only be provided for preorder")
+	}
+	if furniture.ReleaseDate != nil {
+		v.Check(!furniture.ReleaseDate.Before(today()), "release_date", "must not be in the past")
+	}
+}
+
+// today returns the current date at midnight UTC, which is how the
+// dates without time are stored.
+func today() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
 }
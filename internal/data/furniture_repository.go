@@ -4,9 +4,89 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 )
 
+// furnitureStock is the SQL expression for the stock of the furniture aliased
+// as f. The stock of a bundle is the number of sets its components make.
+var furnitureStock = `(CASE WHEN f.product_type = 'bundle' THEN ` + bundleSets("cs.stock") + `
+			ELSE f.stock END)::INTEGER`
+
+// furnitureAvailableStock is the SQL expression for the stock of the furniture
+// aliased as f, minus the quantities held by active stock reservations.
+var furnitureAvailableStock = `(CASE WHEN f.product_type = 'bundle' THEN ` + bundleSets("cs.stock - "+componentReserved) + `
+			ELSE f.stock - COALESCE((
+				SELECT SUM(r.quantity) FROM stock_reservation r
+				WHERE r.furniture_id = f.furniture_id AND r.variant_id IS NULL
+				AND r.status = 'active' AND r.expires_at > NOW()
+			), 0) END)::INTEGER`
+
+// furnitureColumns is the list of columns selected for a furniture,
+// in the order expected by Furniture.scanDest. The queries must alias
+// the furniture table as f and the category table as c.
+var furnitureColumns = `
+			f.furniture_id,
+			f.name,
+			f.description,
+			f.price,
+			f.compare_at_price,
+			` + furnitureStock + `,
+			` + furnitureAvailableStock + `,
+			f.reorder_threshold,
+			f.availability,
+			f.lead_time_days,
+			f.release_date,
+			f.product_type,
+			f.bundle_discount_percent,
+			f.banner_url,
+			f.image_urls,
+			f.category_id,
+			c.name AS category,
+			f.width_cm,
+			f.depth_cm,
+			f.height_cm,
+			f.seat_height_cm,
+			f.weight_kg,
+			f.materials,
+			f.assembly_required,
+			f.care_instructions,
+			f.warranty_months,
+			f.version`
+
+// scanDest returns the destinations for scanning the furniture columns.
+func (furniture *Furniture) scanDest() []any {
+	return []any{
+		&furniture.FurnitureID,
+		&furniture.Name,
+		&furniture.Description,
+		&furniture.Price,
+		&furniture.CompareAtPrice,
+		&furniture.Stock,
+		&furniture.AvailableStock,
+		&furniture.ReorderThreshold,
+		&furniture.Availability,
+		&furniture.LeadTimeDays,
+		&furniture.ReleaseDate,
+		&furniture.Type,
+		&furniture.BundleDiscountPercent,
+		&furniture.BannerURL,
+		&furniture.ImageURLs,
+		&furniture.CategoryID,
+		&furniture.Category,
+		&furniture.Attributes.WidthCm,
+		&furniture.Attributes.DepthCm,
+		&furniture.Attributes.HeightCm,
+		&furniture.Attributes.SeatHeightCm,
+		&furniture.Attributes.WeightKg,
+		&furniture.Attributes.Materials,
+		&furniture.Attributes.AssemblyRequired,
+		&furniture.Attributes.CareInstructions,
+		&furniture.Attributes.WarrantyMonths,
+		&furniture.Version,
+	}
+}
+
 // FurnitureRepository is a type which wraps around a sql.DB connection pool
 // and provide methods for creating and managing furniture to and from
 // the database.
@@ -14,90 +94,99 @@ type FurnitureRepository struct {
 	DB *sql.DB
 }
 
-// Insert a furniture record to the database.
-func (f FurnitureRepository) Insert(furniture *Furniture) error {
-	var categoryID int64
-	queryCategory := `
-		INSERT INTO category(name)
-		VALUES ($1)
-		ON CONFLICT (name) DO NOTHING
-		RETURNING category_id`
-
-	ctx, cancel := context.WithTimeout(context.Background(), 6*time.Second)
-	defer cancel()
+// Insert a furniture record to the database. The initial stock is
+// recorded as a receipt of the actor in the inventory ledger of the
+// warehouse, and the initial price in the price history.
+func (f FurnitureRepository) Insert(furniture *Furniture, warehouseID, actorID int64) error {
+	query := `
+		INSERT INTO furniture(name, description, price, stock, banner_url, image_urls, category_id,
+			width_cm, depth_cm, height_cm, seat_height_cm, weight_kg, materials, assembly_required,
+			care_instructions, warranty_months, reorder_threshold, availability, lead_time_days, release_date)
+		VALUES ($1, $2, $3, 0, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+		RETURNING furniture_id, version`
 
-	err := f.DB.QueryRowContext(ctx, queryCategory, furniture.Category).Scan(&categoryID)
-	if err != nil {
-		switch {
-		// If no new rows was inserted (category already exist), retrieve the existing category id
-		case errors.Is(err, sql.ErrNoRows):
-			queryCategory = `
-				SELECT category_id FROM category
-				WHERE name = $1`
-			err = f.DB.QueryRowContext(ctx, queryCategory, furniture.Category).Scan(&categoryID)
-			if err != nil {
-				return err
-			}
-		default:
-			return err
-		}
+	// Store an empty array rather than NULL when no material is provided.
+	materials := furniture.Attributes.Materials
+	if materials == nil {
+		materials = []string{}
 	}
 
-	queryFurniture := `
-		INSERT INTO furniture(name, description, price, stock, banner_url, image_urls, category_id)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING furniture_id, version`
-
 	args := []any{
 		furniture.Name,
 		furniture.Description,
 		furniture.Price,
-		furniture.Stock,
 		furniture.BannerURL,
 		furniture.ImageURLs,
-		categoryID,
+		furniture.CategoryID,
+		furniture.Attributes.WidthCm,
+		furniture.Attributes.DepthCm,
+		furniture.Attributes.HeightCm,
+		furniture.Attributes.SeatHeightCm,
+		furniture.Attributes.WeightKg,
+		materials,
+		furniture.Attributes.AssemblyRequired,
+		furniture.Attributes.CareInstructions,
+		furniture.Attributes.WarrantyMonths,
+		furniture.ReorderThreshold,
+		furniture.Availability,
+		furniture.LeadTimeDays,
+		furniture.ReleaseDate,
 	}
 
-	return f.DB.QueryRowContext(ctx, queryFurniture, args...).Scan(
-		&furniture.FurnitureID,
-		&furniture.Version,
-	)
+	ctx, cancel := context.WithTimeout(context.Background(), 6*time.Second)
+	defer cancel()
+
+	tx, err := f.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&furniture.FurnitureID, &furniture.Version)
+	if err != nil {
+		return err
+	}
+
+	err = recordPriceChange(ctx, tx, int64(furniture.FurnitureID), PriceChangeCreated, nil)
+	if err != nil {
+		return err
+	}
+
+	if furniture.Stock > 0 {
+		movement := InventoryMovement{
+			FurnitureID: int64(furniture.FurnitureID),
+			WarehouseID: warehouseID,
+			Quantity:    furniture.Stock,
+			Reason:      MovementReasonReceipt,
+			ActorID:     &actorID,
+			Note:        "initial stock",
+		}
+		if err = recordMovement(ctx, tx, &movement); err != nil {
+			return err
+		}
+		furniture.Version++
+	}
+
+	furniture.AvailableStock = furniture.Stock
+	furniture.Type = ProductTypeStandard
+	return tx.Commit()
 }
 
 // GetByID retrieve a specific furniture record from the database
 // given the id.
 func (f FurnitureRepository) GetByID(id int64) (Furniture, error) {
-	query := `
-		SELECT 
-			f.furniture_id, 
-			f.name, 
-			f.description, 
-			f.price, 
-			f.stock, 
-			f.banner_url, 
-			f.image_urls, 
-			c.name AS category, 
-			f.version
-		FROM 
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM
 		    furniture f
 		JOIN category c ON f.category_id = c.category_id
-		WHERE f.furniture_id = $1`
+		WHERE f.furniture_id = $1`, furnitureColumns)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	var furniture Furniture
-	err := f.DB.QueryRowContext(ctx, query, id).Scan(
-		&furniture.FurnitureID,
-		&furniture.Name,
-		&furniture.Description,
-		&furniture.Price,
-		&furniture.Stock,
-		&furniture.BannerURL,
-		&furniture.ImageURLs,
-		&furniture.Category,
-		&furniture.Version,
-	)
+	err := f.DB.QueryRowContext(ctx, query, id).Scan(furniture.scanDest()...)
 
 	if err != nil {
 		switch {
@@ -109,3 +198,246 @@ func (f FurnitureRepository) GetByID(id int64) (Furniture, error) {
 	}
 	return furniture, nil
 }
+
+// SetAvailability sets the availability mode of a specific furniture,
+// with its lead time or release date.
+func (f FurnitureRepository) SetAvailability(furniture *Furniture) error {
+	query := `
+		UPDATE furniture
+		SET availability = $1, lead_time_days = $2, release_date = $3, version = version + 1
+		WHERE furniture_id = $4
+		RETURNING version`
+
+	args := []any{furniture.Availability, furniture.LeadTimeDays, furniture.ReleaseDate, furniture.FurnitureID}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := f.DB.QueryRowContext(ctx, query, args...).Scan(&furniture.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// furnitureFilterQuery returns the common table expression and the WHERE
+// clause matching the furniture filters, alongside their arguments. The
+// clause uses the parameters $1 to $16, so the queries built on it must
+// number their own parameters from $17.
+func furnitureFilterQuery(ff FurnitureFilters) (cte, where string, args []any) {
+	cte = `
+		WITH RECURSIVE subcategories AS (
+			SELECT category_id FROM category WHERE category_id = $2
+			UNION
+			SELECT c.category_id
+			FROM category c
+			JOIN subcategories s ON c.parent_id = s.category_id
+		)`
+
+	where = `
+		WHERE (f.name ILIKE '%' || $1 || '%' OR $1 = '')
+		AND ($2 = 0 OR f.category_id IN (SELECT category_id FROM subcategories))
+		AND ($3 = '' OR LOWER($3) = ANY (SELECT LOWER(m) FROM UNNEST(f.materials) m))
+		AND ($4 = '' OR EXISTS (
+			SELECT 1 FROM furniture_variant v
+			WHERE v.furniture_id = f.furniture_id AND LOWER(v.options->>'color') = LOWER($4)
+		))
+		AND (NOT $5 OR ` + furnitureAvailableStock + ` > 0)
+		AND ($6::DECIMAL IS NULL OR f.price >= $6)
+		AND ($7::DECIMAL IS NULL OR f.price <= $7)
+		AND ($8::DECIMAL IS NULL OR (
+			SELECT AVG(rating) FROM review r WHERE r.furniture_id = f.furniture_id
+		) >= $8)
+		AND ($9::DECIMAL IS NULL OR f.width_cm >= $9)
+		AND ($10::DECIMAL IS NULL OR f.width_cm <= $10)
+		AND ($11::DECIMAL IS NULL OR f.depth_cm >= $11)
+		AND ($12::DECIMAL IS NULL OR f.depth_cm <= $12)
+		AND ($13::DECIMAL IS NULL OR f.height_cm >= $13)
+		AND ($14::DECIMAL IS NULL OR f.height_cm <= $14)
+		AND ($15::DECIMAL IS NULL OR f.weight_kg <= $15)
+		AND ($16 = '' OR f.availability = $16)`
+
+	args = []any{
+		ff.Name,
+		ff.CategoryID,
+		ff.Material,
+		ff.Color,
+		ff.InStock,
+		ff.MinPrice,
+		ff.MaxPrice,
+		ff.MinRating,
+		ff.MinWidth,
+		ff.MaxWidth,
+		ff.MinDepth,
+		ff.MaxDepth,
+		ff.MinHeight,
+		ff.MaxHeight,
+		ff.MaxWeight,
+		ff.Availability,
+	}
+	return cte, where, args
+}
+
+// GetAll retrieve the furniture records matching the furniture filters, alongside
+// the pagination metadata. When a category is provided, furniture in any of its
+// descendant categories is returned as well. The records are paginated by page
+// number, or by cursor when filters.Cursor is set.
+func (f FurnitureRepository) GetAll(furnitureFilters FurnitureFilters, filters Filters) ([]Furniture, Metadata, error) {
+	cte, where, args := furnitureFilterQuery(furnitureFilters)
+
+	keyset, keysetArgs := filters.keysetCondition("f", "furniture_id", len(args)+1)
+	args = append(args, keysetArgs...)
+	args = append(args, filters.limit(), filters.offset())
+
+	query := fmt.Sprintf(`%s
+		SELECT COUNT(*) OVER(), f.%s::TEXT, %s
+		FROM
+		    furniture f
+		JOIN category c ON f.category_id = c.category_id
+		%s
+		AND %s
+		ORDER BY %s
+		LIMIT $%d OFFSET $%d`,
+		cte, filters.sortColumn(), furnitureColumns, where, keyset,
+		filters.orderBy("f", "furniture_id"), len(args)-1, len(args))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := f.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	furniture := []Furniture{}
+	keys := []cursorKey{}
+
+	for rows.Next() {
+		var item Furniture
+		var key cursorKey
+		err = rows.Scan(append([]any{&totalRecords, &key.value}, item.scanDest()...)...)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		key.id = int64(item.FurnitureID)
+		furniture = append(furniture, item)
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	if filters.Cursor != nil {
+		furniture, metadata := paginateCursor(furniture, keys, filters)
+		return furniture, metadata, nil
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return furniture, metadata, nil
+}
+
+// GetFacets count the furniture matching the furniture filters for each
+// category, material, color, price range, rating, stock status and
+// availability mode.
+func (f FurnitureRepository) GetFacets(furnitureFilters FurnitureFilters) (Facets, error) {
+	cte, where, args := furnitureFilterQuery(furnitureFilters)
+
+	// Every facet returns its name, the value to filter by, a label
+	// for display, the count and a position used for ordering.
+	query := fmt.Sprintf(`%s,
+		filtered AS (
+			SELECT f.furniture_id, f.category_id, f.materials, f.price, %s AS stock, f.availability
+			FROM furniture f
+			%s
+		)
+		SELECT 'category', c.category_id::TEXT, c.name, COUNT(*), -COUNT(*)
+		FROM filtered f
+		JOIN category c ON f.category_id = c.category_id
+		GROUP BY c.category_id, c.name
+		UNION ALL
+		SELECT 'material', LOWER(m), LOWER(m), COUNT(DISTINCT f.furniture_id), -COUNT(DISTINCT f.furniture_id)
+		FROM filtered f, UNNEST(f.materials) m
+		GROUP BY LOWER(m)
+		UNION ALL
+		SELECT 'color', LOWER(v.options->>'color'), LOWER(v.options->>'color'),
+			COUNT(DISTINCT f.furniture_id), -COUNT(DISTINCT f.furniture_id)
+		FROM filtered f
+		JOIN furniture_variant v ON v.furniture_id = f.furniture_id
+		WHERE v.options ? 'color'
+		GROUP BY LOWER(v.options->>'color')
+		UNION ALL
+		SELECT 'price', b.value, b.label, COUNT(*), b.position
+		FROM filtered f
+		JOIN (VALUES
+			(1, '0-100', 'Under 100', 0, 100),
+			(2, '100-250', '100 to 250', 100, 250),
+			(3, '250-500', '250 to 500', 250, 500),
+			(4, '500-1000', '500 to 1000', 500, 1000),
+			(5, '1000-', '1000 and above', 1000, NULL)
+		) AS b(position, value, label, min, max)
+			ON f.price >= b.min AND (b.max IS NULL OR f.price < b.max)
+		GROUP BY b.position, b.value, b.label
+		UNION ALL
+		SELECT 'rating', b::TEXT, b::TEXT || ' stars and up', COUNT(*), -b
+		FROM filtered f
+		JOIN (SELECT furniture_id, AVG(rating) AS rating FROM review GROUP BY furniture_id) r
+			ON r.furniture_id = f.furniture_id
+		CROSS JOIN generate_series(1, 4) b
+		WHERE r.rating >= b
+		GROUP BY b
+		UNION ALL
+		SELECT 'in_stock', (f.stock > 0)::TEXT, CASE WHEN f.stock > 0 THEN 'In stock' ELSE 'Out of stock' END,
+			COUNT(*), CASE WHEN f.stock > 0 THEN 1 ELSE 2 END
+		FROM filtered f
+		GROUP BY f.stock > 0
+		UNION ALL
+		SELECT 'availability', a.value, a.label, COUNT(*), a.position
+		FROM filtered f
+		JOIN (VALUES
+			(1, 'in_stock', 'In stock'),
+			(2, 'backorder', 'Backorder'),
+			(3, 'preorder', 'Pre-order'),
+			(4, 'discontinued', 'Discontinued')
+		) AS a(position, value, label) ON f.availability = a.value
+		GROUP BY a.position, a.value, a.label
+		ORDER BY 1, 5, 2`, cte, furnitureAvailableStock, where)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := f.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	facets := Facets{}
+	for _, name := range FacetNames {
+		facets[name] = []FacetValue{}
+	}
+
+	for rows.Next() {
+		var name string
+		var value FacetValue
+		var position int
+
+		err = rows.Scan(&name, &value.Value, &value.Label, &value.Count, &position)
+		if err != nil {
+			return nil, err
+		}
+		facets[name] = append(facets[name], value)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return facets, nil
+}
@@ -0,0 +1,102 @@
+package data
+
+import (
+	"github.com/hayohtee/fumode/internal/validator"
+	"time"
+)
+
+// The reasons the stock of an item can change for.
+const (
+	MovementReasonReceipt          = "receipt"
+	MovementReasonSale             = "sale"
+	MovementReasonReturn           = "return"
+	MovementReasonDamage           = "damage"
+	MovementReasonManualCorrection = "manual_correction"
+	MovementReasonTransfer         = "transfer"
+	MovementReasonCancellation     = "cancellation"
+)
+
+// MovementReasons holds every reason of the inventory movements.
+var MovementReasons = []string{
+	MovementReasonReceipt,
+	MovementReasonSale,
+	MovementReasonReturn,
+	MovementReasonDamage,
+	MovementReasonManualCorrection,
+	MovementReasonTransfer,
+	MovementReasonCancellation,
+}
+
+// AdjustmentReasons holds the reasons admins can adjust the stock for.
+// Sales are only recorded when an order is paid, cancellations when it is
+// cancelled and transfers when stock is moved between warehouses.
+var AdjustmentReasons = []string{
+	MovementReasonReceipt,
+	MovementReasonReturn,
+	MovementReasonDamage,
+	MovementReasonManualCorrection,
+}
+
+// InventoryMovement is a struct that holds an entry of the append-only
+// inventory ledger. Quantity is the change of the stock of a furniture,
+// or of a specific variant of it, in a warehouse and StockAfter the
+// resulting stock in that warehouse.
+// ActorID is the user who caused the movement, if any, and Reference an
+// identifier of its source such as "order:42".
+type InventoryMovement struct {
+	MovementID  int64     `json:"movement_id"`
+	FurnitureID int64     `json:"furniture_id"`
+	VariantID   *int64    `json:"variant_id"`
+	WarehouseID int64     `json:"warehouse_id"`
+	Quantity    int       `json:"quantity"`
+	Reason      string    `json:"reason"`
+	ActorID     *int64    `json:"actor_id"`
+	Reference   string    `json:"reference,omitempty"`
+	Note        string    `json:"note,omitempty"`
+	StockAfter  int       `json:"stock_after"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func ValidateAdjustment(v *validator.Validator, movement InventoryMovement) {
+	v.Check(movement.WarehouseID > 0, "warehouse_id", "must be provided")
+	v.Check(movement.Quantity != 0, "quantity", "must not be zero")
+	v.Check(validator.Between(movement.Quantity, -100_000, 100_000), "quantity", "must be between -100000 and 100000")
+
+	v.Check(validator.PermittedValue(movement.Reason, AdjustmentReasons...), "reason", "invalid reason")
+	switch movement.Reason {
+	case MovementReasonReceipt, MovementReasonReturn:
+		v.Check(movement.Quantity > 0, "quantity", "must be positive for "+movement.Reason)
+	case MovementReasonDamage:
+		v.Check(movement.Quantity < 0, "quantity", "must be negative for damage")
+	}
+
+	v.Check(len(movement.Reference) <= 100, "reference", "must not be more than 100 bytes long")
+	v.Check(len(movement.Note) <= 1000, "note", "must not be more than 1000 bytes long")
+}
+
+// InventoryFilters is a struct that holds the filters for the
+// movement history of a furniture.
+type InventoryFilters struct {
+	VariantID   int64
+	WarehouseID int64
+	Reason      string
+}
+
+func ValidateInventoryFilters(v *validator.Validator, f InventoryFilters) {
+	v.Check(f.VariantID >= 0, "variant_id", "must not be negative")
+	v.Check(f.WarehouseID >= 0, "warehouse_id", "must not be negative")
+	if f.Reason != "" {
+		v.Check(validator.PermittedValue(f.Reason, MovementReasons...), "reason", "invalid reason")
+	}
+}
+
+// StockDiscrepancy is a struct that holds an item whose stock in a
+// warehouse does not match the sum of its inventory movements there.
+type StockDiscrepancy struct {
+	WarehouseID   int64  `json:"warehouse_id"`
+	FurnitureID   int64  `json:"furniture_id"`
+	VariantID     *int64 `json:"variant_id"`
+	Name          string `json:"name"`
+	Stock         int    `json:"stock"`
+	LedgerBalance int    `json:"ledger_balance"`
+}
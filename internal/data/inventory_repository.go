@@ -0,0 +1,341 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// movementColumns is the list of columns selected for an inventory
+// movement, in the order expected by InventoryMovement.scanDest.
+const movementColumns = `
+			m.movement_id,
+			m.furniture_id,
+			m.variant_id,
+			m.warehouse_id,
+			m.quantity,
+			m.reason,
+			m.actor_id,
+			m.reference,
+			m.note,
+			m.stock_after,
+			m.created_at`
+
+// scanDest returns the destinations for scanning the movement columns.
+func (movement *InventoryMovement) scanDest() []any {
+	return []any{
+		&movement.MovementID,
+		&movement.FurnitureID,
+		&movement.VariantID,
+		&movement.WarehouseID,
+		&movement.Quantity,
+		&movement.Reason,
+		&movement.ActorID,
+		&movement.Reference,
+		&movement.Note,
+		&movement.StockAfter,
+		&movement.CreatedAt,
+	}
+}
+
+// InventoryRepository is a type which wraps around a sql.DB connection pool
+// and provide methods for recording and querying the inventory ledger.
+type InventoryRepository struct {
+	DB *sql.DB
+}
+
+// Adjust records a stock adjustment made by an admin and applies it to
+// the stock of the furniture or variant in the warehouse. It returns
+// ErrInsufficientStock if the adjustment would take the stock below the
+// quantity reserved by pending checkouts.
+func (i InventoryRepository) Adjust(movement *InventoryMovement) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := i.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	available, err := lockWarehouseStock(ctx, tx, movement.FurnitureID, movement.VariantID)
+	if err != nil {
+		return err
+	}
+
+	if movement.Quantity < 0 && available[movement.WarehouseID]+movement.Quantity < 0 {
+		return fmt.Errorf("%w: only %d available", ErrInsufficientStock, max(available[movement.WarehouseID], 0))
+	}
+
+	if err = recordMovement(ctx, tx, movement); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Transfer moves stock of a furniture, or of a specific variant of it,
+// from a warehouse to another. It is recorded as two transfer movements
+// sharing the same reference, which are returned.
+func (i InventoryRepository) Transfer(transfer StockTransfer) ([]InventoryMovement, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := i.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	available, err := lockWarehouseStock(ctx, tx, transfer.FurnitureID, transfer.VariantID)
+	if err != nil {
+		return nil, err
+	}
+
+	if available[transfer.FromWarehouseID] < transfer.Quantity {
+		return nil, fmt.Errorf("%w: only %d available", ErrInsufficientStock, max(available[transfer.FromWarehouseID], 0))
+	}
+
+	movements := []InventoryMovement{
+		{WarehouseID: transfer.FromWarehouseID, Quantity: -transfer.Quantity},
+		{WarehouseID: transfer.ToWarehouseID, Quantity: transfer.Quantity},
+	}
+
+	for n := range movements {
+		movements[n].FurnitureID = transfer.FurnitureID
+		movements[n].VariantID = transfer.VariantID
+		movements[n].Reason = MovementReasonTransfer
+		movements[n].ActorID = transfer.ActorID
+		movements[n].Reference = transfer.Reference
+		movements[n].Note = transfer.Note
+
+		if err = recordMovement(ctx, tx, &movements[n]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return movements, nil
+}
+
+// GetAllForFurniture retrieve the movement history of a specific furniture
+// and its variants in every warehouse, alongside the pagination metadata.
+func (i InventoryRepository) GetAllForFurniture(furnitureID int64, inf InventoryFilters, filters Filters) ([]InventoryMovement, Metadata, error) {
+	args := []any{furnitureID, inf.VariantID, inf.WarehouseID, inf.Reason}
+
+	keyset, keysetArgs := filters.keysetCondition("m", "movement_id", len(args)+1)
+	args = append(args, keysetArgs...)
+	args = append(args, filters.limit(), filters.offset())
+
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER(), m.%s::TEXT, %s
+		FROM inventory_movement m
+		WHERE m.furniture_id = $1
+		AND (m.variant_id = $2 OR $2 = 0)
+		AND (m.warehouse_id = $3 OR $3 = 0)
+		AND (m.reason = $4 OR $4 = '')
+		AND %s
+		ORDER BY %s
+		LIMIT $%d OFFSET $%d`,
+		filters.sortColumn(), movementColumns, keyset, filters.orderBy("m", "movement_id"), len(args)-1, len(args))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := i.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	movements := []InventoryMovement{}
+	keys := []cursorKey{}
+
+	for rows.Next() {
+		var movement InventoryMovement
+		var key cursorKey
+		err = rows.Scan(append([]any{&totalRecords, &key.value}, movement.scanDest()...)...)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		key.id = movement.MovementID
+		movements = append(movements, movement)
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	if filters.Cursor != nil {
+		movements, metadata := paginateCursor(movements, keys, filters)
+		return movements, metadata, nil
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return movements, metadata, nil
+}
+
+// stockBalancesQuery selects the stock level of every furniture and variant
+// in every warehouse alongside the sum of its movements there.
+const stockBalancesQuery = `
+		WITH balance AS (
+			SELECT ws.warehouse_id, ws.furniture_id, ws.variant_id,
+				f.name || COALESCE(' (' || v.sku || ')', '') AS name, ws.stock,
+				COALESCE((SELECT SUM(m.quantity) FROM inventory_movement m
+					WHERE m.warehouse_id = ws.warehouse_id AND m.furniture_id = ws.furniture_id
+					AND m.variant_id IS NOT DISTINCT FROM ws.variant_id), 0)::INTEGER AS ledger_balance
+			FROM warehouse_stock ws
+			JOIN furniture f ON ws.furniture_id = f.furniture_id
+			LEFT JOIN furniture_variant v ON ws.variant_id = v.variant_id
+		)`
+
+// GetDiscrepancies retrieve the stock levels that do not match the sum of
+// their movements, which happens when the stock is changed without going
+// through the ledger.
+func (i InventoryRepository) GetDiscrepancies() ([]StockDiscrepancy, error) {
+	query := stockBalancesQuery + `
+		SELECT warehouse_id, furniture_id, variant_id, name, stock, ledger_balance
+		FROM balance
+		WHERE stock <> ledger_balance
+		ORDER BY warehouse_id, furniture_id, variant_id NULLS FIRST`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := i.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	discrepancies := []StockDiscrepancy{}
+	for rows.Next() {
+		var discrepancy StockDiscrepancy
+		err = rows.Scan(
+			&discrepancy.WarehouseID,
+			&discrepancy.FurnitureID,
+			&discrepancy.VariantID,
+			&discrepancy.Name,
+			&discrepancy.Stock,
+			&discrepancy.LedgerBalance,
+		)
+		if err != nil {
+			return nil, err
+		}
+		discrepancies = append(discrepancies, discrepancy)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return discrepancies, nil
+}
+
+// Reconcile records a manual correction for every stock level that does
+// not match the sum of its movements, so that the ledger accounts for the
+// current stock. The stock itself is left unchanged.
+func (i InventoryRepository) Reconcile(actorID int64) ([]InventoryMovement, error) {
+	query := stockBalancesQuery + `
+		INSERT INTO inventory_movement(furniture_id, variant_id, warehouse_id, quantity, reason, actor_id, note, stock_after)
+		SELECT furniture_id, variant_id, warehouse_id, stock - ledger_balance, $1, $2, 'reconciliation', stock
+		FROM balance
+		WHERE stock <> ledger_balance
+		RETURNING movement_id, furniture_id, variant_id, warehouse_id, quantity, reason, actor_id, reference, note,
+			stock_after, created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := i.DB.QueryContext(ctx, query, MovementReasonManualCorrection, actorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movements := []InventoryMovement{}
+	for rows.Next() {
+		var movement InventoryMovement
+		if err = rows.Scan(movement.scanDest()...); err != nil {
+			return nil, err
+		}
+		movements = append(movements, movement)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return movements, nil
+}
+
+// recordMovement appends a movement to the inventory ledger and applies
+// it to the stock level of the warehouse and to the total stock of the
+// furniture, or of the variant when VariantID is set. Every change of the
+// stock must go through it, so that the stock always matches the sum of
+// the movements. Bundles hold no stock of their own, so recording a
+// movement for one returns ErrRecordNotFound.
+func recordMovement(ctx context.Context, q queryer, movement *InventoryMovement) error {
+	update := `UPDATE furniture SET stock = stock + $4, version = version + 1
+			WHERE furniture_id = $1 AND $2::BIGINT IS NULL AND product_type = 'standard' RETURNING stock`
+	if movement.VariantID != nil {
+		update = `UPDATE furniture_variant SET stock = stock + $4, version = version + 1
+			WHERE furniture_id = $1 AND variant_id = $2 RETURNING stock`
+	}
+
+	query := fmt.Sprintf(`
+		WITH item AS (
+			%s
+		), updated AS (
+			UPDATE warehouse_stock SET stock = stock + $4
+			WHERE warehouse_id = $3 AND furniture_id = $1 AND variant_id IS NOT DISTINCT FROM $2::BIGINT
+			AND EXISTS (SELECT 1 FROM item)
+			RETURNING stock
+		), inserted AS (
+			INSERT INTO warehouse_stock(warehouse_id, furniture_id, variant_id, stock)
+			SELECT $3, $1, $2, $4 FROM item
+			WHERE NOT EXISTS (SELECT 1 FROM updated)
+			RETURNING stock
+		), level AS (
+			SELECT stock FROM updated
+			UNION ALL
+			SELECT stock FROM inserted
+		)
+		INSERT INTO inventory_movement(furniture_id, variant_id, warehouse_id, quantity, reason, actor_id,
+			reference, note, stock_after)
+		SELECT $1, $2, $3, $4, $5, $6, $7, $8, stock FROM level
+		RETURNING movement_id, stock_after, created_at`, update)
+
+	args := []any{
+		movement.FurnitureID,
+		movement.VariantID,
+		movement.WarehouseID,
+		movement.Quantity,
+		movement.Reason,
+		movement.ActorID,
+		movement.Reference,
+		movement.Note,
+	}
+
+	err := q.QueryRowContext(ctx, query, args...).Scan(&movement.MovementID, &movement.StockAfter, &movement.CreatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		case strings.Contains(err.Error(), `violates foreign key constraint "warehouse_stock_warehouse_id_fkey"`):
+			return ErrInvalidWarehouse
+		case strings.Contains(err.Error(), "furniture_stock_check"),
+			strings.Contains(err.Error(), "furniture_variant_stock_check"),
+			strings.Contains(err.Error(), "warehouse_stock_stock_check"):
+			return ErrInsufficientStock
+		default:
+			return err
+		}
+	}
+	return nil
+}
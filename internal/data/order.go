@@ -0,0 +1,218 @@
+package data
+
+import (
+	"github.com/hayohtee/fumode/internal/money"
+	"github.com/hayohtee/fumode/internal/validator"
+	"time"
+)
+
+// The statuses an order goes through. An order is pending payment while
+// its stock is reserved, it is expired when the reservations run out
+// before the payment succeeds. A paid order can be shipped in several
+// shipments, it is partially shipped until every item is shipped and
+// partially delivered until every item is delivered. It is returned once
+// every item was returned and refunded. An order cancelled by its customer
+// before it was shipped, or by an admin at any time, is cancelled.
+const (
+	OrderStatusPendingPayment     = "pending_payment"
+	OrderStatusPaid               = "paid"
+	OrderStatusPaymentFailed      = "payment_failed"
+	OrderStatusExpired            = "expired"
+	OrderStatusPartiallyShipped   = "partially_shipped"
+	OrderStatusShipped            = "shipped"
+	OrderStatusPartiallyDelivered = "partially_delivered"
+	OrderStatusDelivered          = "delivered"
+	OrderStatusReturned           = "returned"
+	OrderStatusCancelled          = "cancelled"
+)
+
+// The statuses of a shipment. A shipment is pending until its label is
+// created with the carrier, and delivered once the carrier reports it.
+const (
+	ShipmentStatusPending   = "pending"
+	ShipmentStatusShipped   = "shipped"
+	ShipmentStatusDelivered = "delivered"
+)
+
+// The statuses of a payment. A paid payment is partially refunded until
+// the whole amount is refunded. The payment of a cancelled order is
+// refund pending until what is left of it is refunded, and refund failed
+// if the refund did not go through.
+const (
+	PaymentStatusPending           = "pending"
+	PaymentStatusPaid              = "paid"
+	PaymentStatusFailed            = "failed"
+	PaymentStatusCancelled         = "cancelled"
+	PaymentStatusPartiallyRefunded = "partially_refunded"
+	PaymentStatusRefunded          = "refunded"
+	PaymentStatusRefundPending     = "refund_pending"
+	PaymentStatusRefundFailed      = "refund_failed"
+)
+
+// The statuses of a stock reservation. An active reservation holds stock
+// until it expires, it is committed when the stock is decremented on
+// payment success and released otherwise.
+const (
+	ReservationStatusActive    = "active"
+	ReservationStatusCommitted = "committed"
+	ReservationStatusReleased  = "released"
+)
+
+// Order is a struct that holds information about a specific order.
+// ReservedUntil is when a pending order expires, and the stock reserved
+// for it is released, if the order has not been paid. Discount is the amount taken
+// off the total price by the coupons used. The amounts of an order are in
+// the base currency, and ChargedTotal is the total price converted to the
+// Currency the order is charged in at ExchangeRate. Tax is the tax of the
+// items, which is part of their prices when PricesIncludeTax is set and
+// added to the total price otherwise. Shipment is where the order is
+// shipped to, and Shipments are the parcels it was shipped in so far.
+// InvoiceNumber is the number of the invoice issued at InvoicedAt, once the
+// order is paid. CancelledAt and CancellationReason are set once the order
+// is cancelled.
+type Order struct {
+	OrderID            int64           `json:"order_id"`
+	UserID             int64           `json:"user_id"`
+	Status             string          `json:"status"`
+	TotalPrice         money.Money     `json:"total_price"`
+	Discount           money.Money     `json:"discount"`
+	Tax                money.Money     `json:"tax"`
+	PricesIncludeTax   bool            `json:"prices_include_tax"`
+	Currency           string          `json:"currency"`
+	ExchangeRate       float64         `json:"exchange_rate"`
+	ChargedTotal       money.Money     `json:"charged_total"`
+	OrderDate          time.Time       `json:"order_date"`
+	ReservedUntil      *time.Time      `json:"reserved_until,omitempty"`
+	Payment            Payment         `json:"payment"`
+	Shipment           Shipment        `json:"shipment"`
+	Shipments          []OrderShipment `json:"shipments"`
+	Items              []OrderItem     `json:"items"`
+	InvoiceNumber      *int64          `json:"invoice_number,omitempty"`
+	InvoicedAt         *time.Time      `json:"invoiced_at,omitempty"`
+	CancelledAt        *time.Time      `json:"cancelled_at,omitempty"`
+	CancellationReason *string         `json:"cancellation_reason,omitempty"`
+	Version            int             `json:"version"`
+}
+
+// OrderItem is a struct that holds a furniture, or a specific variant
+// of it, in an order with the unit price it was ordered at.
+// BackorderedQuantity is the part of the quantity ordered beyond the
+// stock of a backordered or pre-ordered furniture, which ships on
+// ExpectedShipDate instead of being reserved. Discount is the part of the
+// discount of the order taken off the item, and Tax the tax due on the
+// item once discounted, at TaxPercent. ShippedQuantity is the part of the
+// quantity shipped so far, and ReturnedQuantity the part of it in return
+// requests that were not rejected.
+type OrderItem struct {
+	OrderItemID         int64       `json:"order_item_id"`
+	FurnitureID         int64       `json:"furniture_id"`
+	VariantID           *int64      `json:"variant_id"`
+	Name                string      `json:"name"`
+	Quantity            int         `json:"quantity"`
+	Price               money.Money `json:"price"`
+	Discount            money.Money `json:"discount"`
+	TaxClass            string      `json:"tax_class"`
+	TaxPercent          float64     `json:"tax_percent"`
+	Tax                 money.Money `json:"tax"`
+	BackorderedQuantity int         `json:"backordered_quantity"`
+	ExpectedShipDate    *time.Time  `json:"expected_ship_date"`
+	ShippedQuantity     int         `json:"shipped_quantity"`
+	ReturnedQuantity    int         `json:"returned_quantity"`
+}
+
+// Payment is a struct that holds information about the payment of
+// an order. Reference is the identifier of the charge at the payment
+// provider. Refunded is the part of the amount given back to the customer.
+type Payment struct {
+	PaymentID     int64       `json:"payment_id"`
+	PaymentDate   *time.Time  `json:"payment_date"`
+	PaymentMethod *string     `json:"payment_method"`
+	Amount        money.Money `json:"amount"`
+	Refunded      money.Money `json:"refunded"`
+	Status        string      `json:"status"`
+	Reference     *string     `json:"-"`
+}
+
+// Shipment is a struct that holds the shipping address of an order, the
+// warehouse it is fulfilled from and the shipping method it is shipped
+// with at ShippingCost. ShippingMethod is the name of the method, which
+// is kept once the method is deleted. Large items are delivered in the
+// window of the booked DeliverySlotID, the window being kept once the
+// slot is released. ShipmentDate is when the first parcel of the order was
+// shipped.
+type Shipment struct {
+	ShipmentID          int64       `json:"shipment_id"`
+	ShipmentDate        *time.Time  `json:"shipment_date"`
+	Address             string      `json:"address"`
+	City                string      `json:"city"`
+	State               string      `json:"state"`
+	Country             string      `json:"country"`
+	ZipCode             string      `json:"zip_code"`
+	WarehouseID         *int64      `json:"warehouse_id"`
+	ShippingMethodID    *int64      `json:"shipping_method_id"`
+	ShippingMethod      *string     `json:"shipping_method"`
+	ShippingCost        money.Money `json:"shipping_cost"`
+	DeliverySlotID      *int64      `json:"delivery_slot_id"`
+	DeliveryWindowStart *time.Time  `json:"delivery_window_start"`
+	DeliveryWindowEnd   *time.Time  `json:"delivery_window_end"`
+}
+
+// OrderShipment is a struct that holds a parcel an order was shipped in,
+// the items in it and the carrier tracking it by TrackingNumber. Events is
+// the timeline of the parcel, which is only set when tracking the order.
+type OrderShipment struct {
+	ShipmentID     int64           `json:"shipment_id"`
+	Status         string          `json:"status"`
+	WarehouseID    *int64          `json:"warehouse_id"`
+	Carrier        *string         `json:"carrier"`
+	TrackingNumber *string         `json:"tracking_number"`
+	ShipmentDate   *time.Time      `json:"shipment_date"`
+	Items          []ShipmentItem  `json:"items"`
+	Events         []ShipmentEvent `json:"events,omitempty"`
+}
+
+// ShipmentItem is a struct that holds the quantity of an order item
+// shipped in a shipment.
+type ShipmentItem struct {
+	OrderItemID int64  `json:"order_item_id"`
+	FurnitureID int64  `json:"furniture_id"`
+	VariantID   *int64 `json:"variant_id"`
+	Name        string `json:"name"`
+	Quantity    int    `json:"quantity"`
+}
+
+// Tracking is a struct that holds the status of an order and the timeline
+// of every shipment it was shipped in, which is empty until the order is
+// shipped.
+type Tracking struct {
+	OrderID   int64           `json:"order_id"`
+	Status    string          `json:"status"`
+	Shipments []OrderShipment `json:"shipments"`
+}
+
+// ShipmentEvent is a struct that holds a step of the journey of a
+// shipment, as reported by its carrier.
+type ShipmentEvent struct {
+	EventID     int64     `json:"event_id"`
+	Status      string    `json:"status"`
+	Description string    `json:"description"`
+	Location    string    `json:"location"`
+	OccurredAt  time.Time `json:"occurred_at"`
+}
+
+func ValidateCancellationReason(v *validator.Validator, reason string) {
+	v.Check(reason != "", "reason", "must be provided")
+	v.Check(len(reason) <= 500, "reason", "must not be more than 500 bytes long")
+}
+
+func ValidateShipment(v *validator.Validator, shipment Shipment) {
+	v.Check(shipment.Address != "", "address", "must be provided")
+	v.Check(shipment.City != "", "city", "must be provided")
+	v.Check(len(shipment.City) <= 100, "city", "must not be more than 100 bytes long")
+	v.Check(shipment.State != "", "state", "must be provided")
+	v.Check(len(shipment.State) <= 100, "state", "must not be more than 100 bytes long")
+	v.Check(shipment.Country != "", "country", "must be provided")
+	v.Check(len(shipment.Country) <= 100, "country", "must not be more than 100 bytes long")
+	v.Check(shipment.ZipCode != "", "zip_code", "must be provided")
+	v.Check(len(shipment.ZipCode) <= 10, "zip_code", "must not be more than 10 bytes long")
+}
@@ -0,0 +1,1298 @@
+package data
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/hayohtee/fumode/internal/money"
+	"github.com/hayohtee/fumode/internal/shipping"
+	"github.com/hayohtee/fumode/internal/tax"
+	"maps"
+	"slices"
+	"time"
+)
+
+// orderColumns is the list of columns selected for an order, in the order
+// expected by Order.scanDest. The queries must alias the orders table as o,
+// the payment table as p and the shipment table as s.
+const orderColumns = `
+			o.order_id,
+			o.user_id,
+			o.status,
+			o.total_price,
+			o.discount_amount,
+			o.tax_amount,
+			o.prices_include_tax,
+			o.currency,
+			o.exchange_rate,
+			o.order_date,
+			CASE WHEN o.status = 'pending_payment' THEN o.expires_at END,
+			o.invoice_number,
+			o.invoiced_at,
+			o.cancelled_at,
+			o.cancellation_reason,
+			o.version,
+			p.payment_id,
+			p.payment_date,
+			p.payment_method,
+			p.amount,
+			p.refunded_amount,
+			p.status,
+			p.reference,
+			s.shipment_id,
+			s.shipment_date,
+			s.address,
+			s.city,
+			s.state,
+			s.country,
+			s.zip_code,
+			s.warehouse_id,
+			s.shipping_method_id,
+			s.shipping_method,
+			s.shipping_cost,
+			s.delivery_slot_id,
+			s.delivery_window_start,
+			s.delivery_window_end`
+
+// scanDest returns the destinations for scanning the order columns.
+func (order *Order) scanDest() []any {
+	return []any{
+		&order.OrderID,
+		&order.UserID,
+		&order.Status,
+		&order.TotalPrice,
+		&order.Discount,
+		&order.Tax,
+		&order.PricesIncludeTax,
+		&order.Currency,
+		&order.ExchangeRate,
+		&order.OrderDate,
+		&order.ReservedUntil,
+		&order.InvoiceNumber,
+		&order.InvoicedAt,
+		&order.CancelledAt,
+		&order.CancellationReason,
+		&order.Version,
+		&order.Payment.PaymentID,
+		&order.Payment.PaymentDate,
+		&order.Payment.PaymentMethod,
+		&order.Payment.Amount,
+		&order.Payment.Refunded,
+		&order.Payment.Status,
+		&order.Payment.Reference,
+		&order.Shipment.ShipmentID,
+		&order.Shipment.ShipmentDate,
+		&order.Shipment.Address,
+		&order.Shipment.City,
+		&order.Shipment.State,
+		&order.Shipment.Country,
+		&order.Shipment.ZipCode,
+		&order.Shipment.WarehouseID,
+		&order.Shipment.ShippingMethodID,
+		&order.Shipment.ShippingMethod,
+		&order.Shipment.ShippingCost,
+		&order.Shipment.DeliverySlotID,
+		&order.Shipment.DeliveryWindowStart,
+		&order.Shipment.DeliveryWindowEnd,
+	}
+}
+
+// setChargedTotal sets the charged total of a scanned order from its
+// total price and the exchange rate it was recorded with.
+func (order *Order) setChargedTotal() {
+	rate := ExchangeRate{Currency: order.Currency, Rate: order.ExchangeRate}
+	order.ChargedTotal = rate.Convert(order.TotalPrice)
+}
+
+// OrderRepository is a type which wraps around a sql.DB connection pool
+// and provide methods for placing and managing orders to and from the
+// database.
+type OrderRepository struct {
+	DB *sql.DB
+}
+
+// Checkout places an order for the items in the cart of a user, shipped to
+// the address of the shipment from the nearest warehouse holding every item,
+// and charged in the currency of the rate. The stock of every item, or of
+// the components of a bundle, is reserved until ttl has elapsed, when the
+// order expires unless it was paid. The tax of the items is computed from
+// the tax rates of the shipping address and added to the total price unless
+// pricesIncludeTax is set, as is the cost of the shipping method. A delivery
+// slot must start after slotCutoff has elapsed. The coupons of the cart are
+// redeemed with the order, and any order of the user still pending payment
+// is expired first. It returns ErrEmptyCart, ErrInvalidShippingMethod,
+// ErrSlotUnavailable, ErrInvalidCoupon, ErrInsufficientStock or
+// ErrNoWarehouse when the order cannot be placed.
+func (o OrderRepository) Checkout(userID int64, shipment Shipment, rate ExchangeRate, pricesIncludeTax bool, ttl, slotCutoff time.Duration) (Order, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := o.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return Order{}, err
+	}
+	defer tx.Rollback()
+
+	_, err = expirePendingOrders(ctx, tx, userID)
+	if err != nil {
+		return Order{}, err
+	}
+
+	cart, err := getCart(ctx, tx, userID, true)
+	if err != nil {
+		return Order{}, err
+	}
+
+	if len(cart.Items) == 0 {
+		return Order{}, ErrEmptyCart
+	}
+
+	if len(cart.Rejected) > 0 {
+		return Order{}, fmt.Errorf("%w: %w", ErrInvalidCoupon, cart.Rejected[0])
+	}
+
+	fulfilments, candidates, err := planFulfilment(ctx, tx, cart.Items)
+	if err != nil {
+		return Order{}, err
+	}
+
+	// When nothing is reserved, any warehouse can fulfil the order.
+	warehouseID, err := nearestWarehouse(ctx, tx, candidates, shipment)
+	if err != nil {
+		return Order{}, err
+	}
+	shipment.WarehouseID = &warehouseID
+
+	region := tax.Region{Country: shipment.Country, State: shipment.State}
+	rates, err := getTaxRates(ctx, tx, region)
+	if err != nil {
+		return Order{}, err
+	}
+
+	lines := make([]tax.Line, 0, len(cart.Items))
+	for i, item := range cart.Items {
+		lines = append(lines, tax.Line{TaxClass: item.TaxClass, Amount: item.Subtotal.Sub(cart.LineDiscounts[i])})
+	}
+	taxes := tax.Calculate(rates, region, lines, money.DefaultCurrency, pricesIncludeTax)
+
+	// The order must be shipped with one of the methods offered for the
+	// cart and the address, unless no method is configured at all.
+	methods, err := getShippingMethods(ctx, tx, 0, true)
+	if err != nil {
+		return Order{}, err
+	}
+
+	shipment.ShippingMethod = nil
+	shipment.ShippingCost = money.Zero(money.DefaultCurrency)
+	if len(methods) > 0 {
+		zoneID, err := findShippingZone(ctx, tx, shipping.Region{Country: shipment.Country, State: shipment.State})
+		if err != nil {
+			return Order{}, err
+		}
+
+		options, err := quoteShipping(ctx, tx, methods, userID, cart.Total, zoneID)
+		if err != nil {
+			return Order{}, err
+		}
+
+		if shipment.ShippingMethodID == nil {
+			return Order{}, fmt.Errorf("%w: no shipping method was chosen", ErrInvalidShippingMethod)
+		}
+
+		i := slices.IndexFunc(options, func(option shipping.Option) bool {
+			return option.MethodID == *shipment.ShippingMethodID
+		})
+		if i < 0 {
+			return Order{}, fmt.Errorf("%w: shipping method %d", ErrInvalidShippingMethod, *shipment.ShippingMethodID)
+		}
+		shipment.ShippingMethod = &options[i].Name
+		shipment.ShippingCost = options[i].Cost
+
+		// Large items are delivered in a booked slot of the zone, which
+		// is taken off its capacity until the order expires.
+		if shipping.NeedsDeliverySlot(options[i].Kind) {
+			if shipment.DeliverySlotID == nil {
+				return Order{}, fmt.Errorf("%w: no delivery slot was chosen", ErrSlotUnavailable)
+			}
+
+			shipment.DeliveryWindowStart, shipment.DeliveryWindowEnd, err = bookDeliverySlot(ctx, tx, *shipment.DeliverySlotID, *zoneID, slotCutoff)
+			if err != nil {
+				return Order{}, err
+			}
+		} else {
+			shipment.DeliverySlotID = nil
+		}
+	} else {
+		shipment.ShippingMethodID = nil
+		shipment.DeliverySlotID = nil
+	}
+
+	total := cart.Total.Add(shipment.ShippingCost)
+	if !pricesIncludeTax {
+		total = total.Add(taxes.Total)
+	}
+
+	order := Order{
+		UserID:           userID,
+		Status:           OrderStatusPendingPayment,
+		TotalPrice:       total,
+		Discount:         cart.DiscountTotal,
+		Tax:              taxes.Total,
+		PricesIncludeTax: pricesIncludeTax,
+		Currency:         rate.Currency,
+		ExchangeRate:     rate.Rate,
+		ChargedTotal:     rate.Convert(total),
+		Shipment:         shipment,
+		Payment: Payment{
+			Amount:   total,
+			Refunded: money.Zero(money.DefaultCurrency),
+			Status:   PaymentStatusPending,
+		},
+	}
+
+	query := `
+		INSERT INTO payment(amount, user_id, status)
+		VALUES ($1, $2, $3)
+		RETURNING payment_id`
+
+	err = tx.QueryRowContext(ctx, query, order.Payment.Amount, userID, order.Payment.Status).Scan(&order.Payment.PaymentID)
+	if err != nil {
+		return Order{}, err
+	}
+
+	query = `
+		INSERT INTO shipment(address, city, state, country, zip_code, user_id, warehouse_id, shipping_method_id,
+			shipping_method, shipping_cost, delivery_slot_id, delivery_window_start, delivery_window_end)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING shipment_id`
+
+	args := []any{
+		shipment.Address,
+		shipment.City,
+		shipment.State,
+		shipment.Country,
+		shipment.ZipCode,
+		userID,
+		warehouseID,
+		shipment.ShippingMethodID,
+		shipment.ShippingMethod,
+		shipment.ShippingCost,
+		shipment.DeliverySlotID,
+		shipment.DeliveryWindowStart,
+		shipment.DeliveryWindowEnd,
+	}
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&order.Shipment.ShipmentID)
+	if err != nil {
+		return Order{}, err
+	}
+
+	query = `
+		INSERT INTO orders(order_date, total_price, discount_amount, tax_amount, prices_include_tax, currency,
+			exchange_rate, user_id, payment_id, shipment_id, status, expires_at)
+		VALUES (NOW(), $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW() + $11 * INTERVAL '1 second')
+		RETURNING order_id, order_date, expires_at, version`
+
+	args = []any{
+		order.TotalPrice,
+		order.Discount,
+		order.Tax,
+		order.PricesIncludeTax,
+		order.Currency,
+		order.ExchangeRate,
+		userID,
+		order.Payment.PaymentID,
+		order.Shipment.ShipmentID,
+		order.Status,
+		int64(ttl.Seconds()),
+	}
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&order.OrderID, &order.OrderDate, &order.ReservedUntil, &order.Version)
+	if err != nil {
+		return Order{}, err
+	}
+
+	query = `UPDATE shipment SET order_id = $1 WHERE shipment_id = $2`
+	if _, err = tx.ExecContext(ctx, query, order.OrderID, order.Shipment.ShipmentID); err != nil {
+		return Order{}, err
+	}
+
+	for _, coupon := range cart.coupons {
+		discount := cart.Discount(coupon.Code)
+
+		query = `
+			INSERT INTO coupon_redemption(coupon_id, user_id, order_id, amount)
+			VALUES ($1, $2, $3, $4)`
+
+		_, err = tx.ExecContext(ctx, query, coupon.CouponID, userID, order.OrderID, discount.Amount)
+		if err != nil {
+			return Order{}, err
+		}
+	}
+
+	for i, item := range cart.Items {
+		f := fulfilments[item.CartItemID]
+
+		orderItem := OrderItem{
+			FurnitureID:         item.FurnitureID,
+			VariantID:           item.VariantID,
+			Name:                item.Name,
+			Quantity:            item.Quantity,
+			Price:               item.UnitPrice,
+			Discount:            cart.LineDiscounts[i],
+			TaxClass:            item.TaxClass,
+			TaxPercent:          taxes.Lines[i].Percent,
+			Tax:                 taxes.Lines[i].Amount,
+			BackorderedQuantity: f.backordered,
+			ExpectedShipDate:    f.expectedShipDate,
+		}
+
+		query = `
+			INSERT INTO order_item(quantity, price, discount_amount, tax_class, tax_percent, tax_amount, furniture_id,
+				variant_id, order_id, backordered_quantity, expected_ship_date)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			RETURNING order_item_id`
+
+		args = []any{
+			orderItem.Quantity,
+			orderItem.Price,
+			orderItem.Discount,
+			orderItem.TaxClass,
+			orderItem.TaxPercent,
+			orderItem.Tax,
+			orderItem.FurnitureID,
+			orderItem.VariantID,
+			order.OrderID,
+			orderItem.BackorderedQuantity,
+			orderItem.ExpectedShipDate,
+		}
+		err = tx.QueryRowContext(ctx, query, args...).Scan(&orderItem.OrderItemID)
+		if err != nil {
+			return Order{}, err
+		}
+
+		order.Items = append(order.Items, orderItem)
+
+		// The stock is reserved until the order expires.
+		for _, line := range f.lines {
+			query = `
+				INSERT INTO stock_reservation(order_id, furniture_id, variant_id, warehouse_id, quantity, expires_at)
+				VALUES ($1, $2, $3, $4, $5, $6)`
+
+			args = []any{order.OrderID, line.furnitureID, line.variantID, warehouseID, line.quantity, order.ReservedUntil}
+			_, err = tx.ExecContext(ctx, query, args...)
+			if err != nil {
+				return Order{}, err
+			}
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return Order{}, err
+	}
+	return order, nil
+}
+
+// GetByID retrieve a specific order, with its items and shipments, from
+// the database given the id.
+func (o OrderRepository) GetByID(id int64) (Order, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM orders o
+		JOIN payment p ON o.payment_id = p.payment_id
+		JOIN shipment s ON o.shipment_id = s.shipment_id
+		WHERE o.order_id = $1`, orderColumns)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var order Order
+	err := o.DB.QueryRowContext(ctx, query, id).Scan(order.scanDest()...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return Order{}, ErrRecordNotFound
+		default:
+			return Order{}, err
+		}
+	}
+	order.setChargedTotal()
+
+	items, err := getOrderItems(ctx, o.DB, []int64{order.OrderID})
+	if err != nil {
+		return Order{}, err
+	}
+
+	shipments, err := getOrderShipments(ctx, o.DB, []int64{order.OrderID})
+	if err != nil {
+		return Order{}, err
+	}
+
+	order.Items = items[order.OrderID]
+	order.Shipments = shipments[order.OrderID]
+	return order, nil
+}
+
+// GetAllForUser retrieve the orders of a specific user, with their items
+// and shipments, alongside the pagination metadata. The orders are paginated by page
+// number, or by cursor when filters.Cursor is set.
+func (o OrderRepository) GetAllForUser(userID int64, filters Filters) ([]Order, Metadata, error) {
+	args := []any{userID}
+
+	keyset, keysetArgs := filters.keysetCondition("o", "order_id", len(args)+1)
+	args = append(args, keysetArgs...)
+	args = append(args, filters.limit(), filters.offset())
+
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER(), o.%s::TEXT, %s
+		FROM orders o
+		JOIN payment p ON o.payment_id = p.payment_id
+		JOIN shipment s ON o.shipment_id = s.shipment_id
+		WHERE o.user_id = $1
+		AND %s
+		ORDER BY %s
+		LIMIT $%d OFFSET $%d`,
+		filters.sortColumn(), orderColumns, keyset, filters.orderBy("o", "order_id"), len(args)-1, len(args))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := o.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	orders := []Order{}
+	keys := []cursorKey{}
+	orderIDs := []int64{}
+
+	for rows.Next() {
+		var order Order
+		var key cursorKey
+		err = rows.Scan(append([]any{&totalRecords, &key.value}, order.scanDest()...)...)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		order.setChargedTotal()
+		key.id = order.OrderID
+		orders = append(orders, order)
+		keys = append(keys, key)
+		orderIDs = append(orderIDs, order.OrderID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	items, err := getOrderItems(ctx, o.DB, orderIDs)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	shipments, err := getOrderShipments(ctx, o.DB, orderIDs)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	for i := range orders {
+		orders[i].Items = items[orders[i].OrderID]
+		orders[i].Shipments = shipments[orders[i].OrderID]
+	}
+
+	if filters.Cursor != nil {
+		orders, metadata := paginateCursor(orders, keys, filters)
+		return orders, metadata, nil
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return orders, metadata, nil
+}
+
+// ConfirmPayment records the successful payment of a pending order. The
+// stock reservations of the order are committed by recording a sale in the
+// inventory ledger, the order is invoiced with the next invoice number and
+// the ordered items and redeemed coupons are removed from the cart of the
+// user. It returns ErrReservationExpired if the order expired, or its
+// reservations ran out, before the payment.
+func (o OrderRepository) ConfirmPayment(orderID int64, paymentMethod, reference string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := o.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	userID, paymentID, err := lockPendingOrder(ctx, tx, orderID)
+	if err != nil {
+		return err
+	}
+
+	// The order may have expired without being swept yet.
+	query := `SELECT COALESCE(expires_at <= NOW(), FALSE) FROM orders WHERE order_id = $1`
+
+	var expired bool
+	if err = tx.QueryRowContext(ctx, query, orderID).Scan(&expired); err != nil {
+		return err
+	}
+	if expired {
+		return ErrReservationExpired
+	}
+
+	// Lock the reservations, so that the sweeper cannot release them
+	// while they are being committed.
+	query = `
+		SELECT furniture_id, variant_id, warehouse_id, quantity, status = 'active' AND expires_at > NOW()
+		FROM stock_reservation
+		WHERE order_id = $1
+		ORDER BY furniture_id, variant_id
+		FOR UPDATE`
+
+	rows, err := tx.QueryContext(ctx, query, orderID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	type reservation struct {
+		furnitureID int64
+		variantID   *int64
+		warehouseID int64
+		quantity    int
+	}
+
+	var reservations []reservation
+	for rows.Next() {
+		var r reservation
+		var active bool
+		if err = rows.Scan(&r.furnitureID, &r.variantID, &r.warehouseID, &r.quantity, &active); err != nil {
+			return err
+		}
+		if !active {
+			return ErrReservationExpired
+		}
+		reservations = append(reservations, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, r := range reservations {
+		movement := InventoryMovement{
+			FurnitureID: r.furnitureID,
+			VariantID:   r.variantID,
+			WarehouseID: r.warehouseID,
+			Quantity:    -r.quantity,
+			Reason:      MovementReasonSale,
+			ActorID:     &userID,
+			Reference:   fmt.Sprintf("order:%d", orderID),
+		}
+		if err = recordMovement(ctx, tx, &movement); err != nil {
+			return err
+		}
+	}
+
+	query = `UPDATE stock_reservation SET status = $1 WHERE order_id = $2`
+	if _, err = tx.ExecContext(ctx, query, ReservationStatusCommitted, orderID); err != nil {
+		return err
+	}
+
+	query = `
+		UPDATE payment
+		SET status = $1, payment_method = $2, reference = $3, payment_date = NOW()
+		WHERE payment_id = $4`
+
+	if _, err = tx.ExecContext(ctx, query, PaymentStatusPaid, paymentMethod, reference, paymentID); err != nil {
+		return err
+	}
+
+	query = `
+		DELETE FROM cart c
+		USING order_item oi
+		WHERE c.user_id = $1 AND oi.order_id = $2
+		AND c.furniture_id = oi.furniture_id AND c.variant_id IS NOT DISTINCT FROM oi.variant_id`
+
+	if _, err = tx.ExecContext(ctx, query, userID, orderID); err != nil {
+		return err
+	}
+
+	query = `
+		DELETE FROM cart_coupon cc
+		USING coupon_redemption r
+		WHERE cc.user_id = $1 AND r.order_id = $2 AND cc.coupon_id = r.coupon_id`
+
+	if _, err = tx.ExecContext(ctx, query, userID, orderID); err != nil {
+		return err
+	}
+
+	// The counter stays locked until the transaction ends, so that the
+	// invoice numbers are gap-free. It is taken last, to hold it briefly.
+	query = `UPDATE invoice_counter SET last_number = last_number + 1 RETURNING last_number`
+
+	var invoiceNumber int64
+	if err = tx.QueryRowContext(ctx, query).Scan(&invoiceNumber); err != nil {
+		return err
+	}
+
+	query = `
+		UPDATE orders
+		SET status = $1, invoice_number = $2, invoiced_at = NOW(), version = version + 1
+		WHERE order_id = $3`
+
+	if _, err = tx.ExecContext(ctx, query, OrderStatusPaid, invoiceNumber, orderID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// FailPayment records the failed payment of a pending order and releases
+// its stock reservations and delivery slot.
+func (o OrderRepository) FailPayment(orderID int64, paymentMethod string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := o.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, paymentID, err := lockPendingOrder(ctx, tx, orderID)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE stock_reservation SET status = $1 WHERE order_id = $2 AND status = 'active'`
+	if _, err = tx.ExecContext(ctx, query, ReservationStatusReleased, orderID); err != nil {
+		return err
+	}
+
+	query = `UPDATE payment SET status = $1, payment_method = $2 WHERE payment_id = $3`
+	if _, err = tx.ExecContext(ctx, query, PaymentStatusFailed, paymentMethod, paymentID); err != nil {
+		return err
+	}
+
+	query = `UPDATE orders SET status = $1, version = version + 1 WHERE order_id = $2`
+	if _, err = tx.ExecContext(ctx, query, OrderStatusPaymentFailed, orderID); err != nil {
+		return err
+	}
+
+	if err = releaseDeliverySlots(ctx, tx, []int64{orderID}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Cancel cancels an order in a single transaction. The active stock
+// reservations of the order are released, and the stock that was committed
+// but not shipped is put back with a cancellation in the inventory ledger.
+// A pending payment is voided, and a paid one is left refund pending, so
+// that the refund is only made once the cancellation is committed and its
+// result recorded with RecordCancellationRefund. Customers can only cancel
+// an order before it is shipped, an admin can override this and cancel any
+// order that is not closed yet. It returns what is left of the payment to
+// refund, in the currency the order was charged in, and
+// ErrInvalidOrderStatus if the order cannot be cancelled.
+func (o OrderRepository) Cancel(orderID, actorID int64, reason string, override bool) (money.Money, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := o.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return money.Money{}, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT o.status, o.currency, o.exchange_rate, p.payment_id, p.status, p.amount, p.refunded_amount
+		FROM orders o
+		JOIN payment p ON o.payment_id = p.payment_id
+		WHERE o.order_id = $1
+		FOR UPDATE OF o, p`
+
+	var status, paymentStatus string
+	var rate ExchangeRate
+	var paymentID int64
+	var amount, refunded money.Money
+	err = tx.QueryRowContext(ctx, query, orderID).Scan(&status, &rate.Currency, &rate.Rate, &paymentID, &paymentStatus,
+		&amount, &refunded)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return money.Money{}, ErrRecordNotFound
+		default:
+			return money.Money{}, err
+		}
+	}
+
+	switch status {
+	case OrderStatusPendingPayment, OrderStatusPaid:
+	case OrderStatusPartiallyShipped, OrderStatusShipped, OrderStatusPartiallyDelivered, OrderStatusDelivered:
+		if !override {
+			return money.Money{}, ErrInvalidOrderStatus
+		}
+	default:
+		return money.Money{}, ErrInvalidOrderStatus
+	}
+
+	query = `UPDATE stock_reservation SET status = $1 WHERE order_id = $2 AND status = 'active'`
+	if _, err = tx.ExecContext(ctx, query, ReservationStatusReleased, orderID); err != nil {
+		return money.Money{}, err
+	}
+
+	if err = restockCancelledOrder(ctx, tx, orderID, actorID); err != nil {
+		return money.Money{}, err
+	}
+
+	// The delivery slot is only given back when nothing was shipped yet.
+	if status == OrderStatusPendingPayment || status == OrderStatusPaid {
+		if err = releaseDeliverySlots(ctx, tx, []int64{orderID}); err != nil {
+			return money.Money{}, err
+		}
+	}
+
+	// What is left to refund is worked out in the charged currency, so
+	// that the whole charge is refunded when nothing was refunded yet.
+	refund := money.Zero(rate.Currency)
+	switch paymentStatus {
+	case PaymentStatusPending:
+		query = `UPDATE payment SET status = $1 WHERE payment_id = $2`
+		if _, err = tx.ExecContext(ctx, query, PaymentStatusCancelled, paymentID); err != nil {
+			return money.Money{}, err
+		}
+	case PaymentStatusPaid, PaymentStatusPartiallyRefunded:
+		refund = rate.Convert(amount).Sub(rate.Convert(refunded))
+		if refund.IsPositive() {
+			query = `UPDATE payment SET status = $1 WHERE payment_id = $2`
+			if _, err = tx.ExecContext(ctx, query, PaymentStatusRefundPending, paymentID); err != nil {
+				return money.Money{}, err
+			}
+		}
+	}
+
+	query = `
+		UPDATE orders
+		SET status = $1, cancelled_at = NOW(), cancellation_reason = $2, cancelled_by = $3, version = version + 1
+		WHERE order_id = $4`
+
+	if _, err = tx.ExecContext(ctx, query, OrderStatusCancelled, reason, actorID, orderID); err != nil {
+		return money.Money{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return money.Money{}, err
+	}
+	return refund, nil
+}
+
+// RecordCancellationRefund records the result of the refund of a cancelled
+// order whose payment is refund pending. The whole payment is refunded when
+// the refund succeeded, and the payment is left refund failed otherwise, to
+// be reconciled by hand.
+func (o OrderRepository) RecordCancellationRefund(orderID int64, succeeded bool) error {
+	query := `
+		UPDATE payment p
+		SET status = $1, refunded_amount = CASE WHEN $2 THEN p.amount ELSE p.refunded_amount END
+		FROM orders o
+		WHERE o.order_id = $3 AND o.payment_id = p.payment_id AND p.status = $4`
+
+	status := PaymentStatusRefundFailed
+	if succeeded {
+		status = PaymentStatusRefunded
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := o.DB.ExecContext(ctx, query, status, succeeded, orderID, PaymentStatusRefundPending)
+	return err
+}
+
+// ReleaseExpiredReservations expires the orders still pending payment
+// past their expiry, whether or not they reserved any stock, and releases
+// their stock reservations along with any other reservation that ran out.
+// The delivery slots the expired orders booked are given back. It returns
+// the number of released reservations and of expired orders.
+func (o OrderRepository) ReleaseExpiredReservations() (released, expired int, err error) {
+	query := `
+		WITH expired AS (
+			UPDATE orders SET status = 'expired', version = version + 1
+			WHERE status = 'pending_payment' AND expires_at <= NOW()
+			RETURNING order_id, payment_id
+		), released AS (
+			UPDATE stock_reservation SET status = 'released'
+			WHERE status = 'active' AND (expires_at <= NOW() OR order_id IN (SELECT order_id FROM expired))
+			RETURNING order_id
+		), cancelled AS (
+			UPDATE payment SET status = 'cancelled'
+			WHERE payment_id IN (SELECT payment_id FROM expired)
+		)
+		SELECT (SELECT COUNT(*) FROM released), COALESCE((SELECT ARRAY_AGG(order_id) FROM expired), '{}')`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := o.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	var orderIDs []int64
+	if err = tx.QueryRowContext(ctx, query).Scan(&released, &orderIDs); err != nil {
+		return 0, 0, err
+	}
+
+	if err = releaseDeliverySlots(ctx, tx, orderIDs); err != nil {
+		return 0, 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+	return released, len(orderIDs), nil
+}
+
+// stockKey identifies the stock of a furniture, or of a specific variant
+// of it, the variant id being zero when there is none.
+type stockKey struct {
+	furnitureID int64
+	variantID   int64
+}
+
+// stockLine is a quantity of a furniture, or of a specific variant of it,
+// reserved for an order item.
+type stockLine struct {
+	furnitureID int64
+	variantID   *int64
+	name        string
+	quantity    int
+}
+
+// fulfilment is how an order item is fulfilled: the stock reserved for it,
+// which is the stock of the components for a bundle, and the quantity
+// backordered until the expected ship date.
+type fulfilment struct {
+	lines            []stockLine
+	backordered      int
+	expectedShipDate *time.Time
+}
+
+// planFulfilment locks the stock needed by the cart items and works out
+// how each of them is fulfilled. It returns the fulfilment of every item,
+// by cart item id, and the warehouses with enough available stock to
+// fulfil the whole order, which are every warehouse when nothing needs to
+// be reserved. It returns ErrInsufficientStock when an item cannot be
+// fulfilled, or when no single warehouse holds every item.
+func planFulfilment(ctx context.Context, q queryer, items []CartItem) (map[int64]fulfilment, []int64, error) {
+	var bundleIDs []int64
+	for _, item := range items {
+		if item.Type == ProductTypeBundle {
+			bundleIDs = append(bundleIDs, item.FurnitureID)
+		}
+	}
+
+	components, err := getBundleComponents(ctx, q, bundleIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The lines each item needs reserved in full. Backordered items only
+	// reserve what is left once the other items are served, and pre-orders
+	// nothing before their release date.
+	fulfilments := make(map[int64]fulfilment)
+	var backorders []CartItem
+
+	for _, item := range items {
+		var f fulfilment
+		switch {
+		case item.Type == ProductTypeBundle:
+			for _, c := range components[item.FurnitureID] {
+				f.lines = append(f.lines, stockLine{c.FurnitureID, c.VariantID, c.Name, c.Quantity * item.Quantity})
+			}
+		case item.Availability == AvailabilityPreorder && item.ReleaseDate != nil && item.ReleaseDate.After(today()):
+			f.backordered = item.Quantity
+			f.expectedShipDate = item.ReleaseDate
+		case item.Availability == AvailabilityBackorder:
+			backorders = append(backorders, item)
+		default:
+			f.lines = append(f.lines, stockLine{item.FurnitureID, item.VariantID, item.Name, item.Quantity})
+		}
+		fulfilments[item.CartItemID] = f
+	}
+
+	// Lock the stock levels in a consistent order, so that concurrent
+	// checkouts of the same items cannot deadlock.
+	names := make(map[stockKey]stockLine)
+	for _, f := range fulfilments {
+		for _, line := range f.lines {
+			names[stockKey{line.furnitureID, derefID(line.variantID)}] = line
+		}
+	}
+	for _, item := range backorders {
+		names[stockKey{item.FurnitureID, derefID(item.VariantID)}] = stockLine{item.FurnitureID, item.VariantID, item.Name, 0}
+	}
+
+	keys := slices.Collect(maps.Keys(names))
+	slices.SortFunc(keys, func(a, b stockKey) int {
+		if a.furnitureID != b.furnitureID {
+			return cmp.Compare(a.furnitureID, b.furnitureID)
+		}
+		return cmp.Compare(a.variantID, b.variantID)
+	})
+
+	available := make(map[stockKey]map[int64]int)
+	totals := make(map[stockKey]int)
+	for _, key := range keys {
+		stock, err := lockWarehouseStock(ctx, q, names[key].furnitureID, names[key].variantID)
+		if err != nil {
+			return nil, nil, err
+		}
+		available[key] = stock
+		for _, level := range stock {
+			totals[key] += max(level, 0)
+		}
+	}
+
+	demand := make(map[stockKey]int)
+	for _, f := range fulfilments {
+		for _, line := range f.lines {
+			demand[stockKey{line.furnitureID, derefID(line.variantID)}] += line.quantity
+		}
+	}
+
+	for _, key := range keys {
+		if totals[key] < demand[key] {
+			return nil, nil, fmt.Errorf("%w: only %d of %s available", ErrInsufficientStock, totals[key], names[key].name)
+		}
+	}
+
+	for _, item := range backorders {
+		key := stockKey{item.FurnitureID, derefID(item.VariantID)}
+		reserved := min(item.Quantity, totals[key]-demand[key])
+		demand[key] += reserved
+
+		var f fulfilment
+		if reserved > 0 {
+			f.lines = append(f.lines, stockLine{item.FurnitureID, item.VariantID, item.Name, reserved})
+		}
+		if reserved < item.Quantity {
+			shipDate := today().AddDate(0, 0, derefInt(item.LeadTimeDays))
+			f.backordered = item.Quantity - reserved
+			f.expectedShipDate = &shipDate
+		}
+		fulfilments[item.CartItemID] = f
+	}
+
+	// The order is fulfilled by a single warehouse, so only the warehouses
+	// with enough available stock of every reserved item are candidates.
+	var candidates []int64
+	constrained := false
+	for _, key := range keys {
+		if demand[key] == 0 {
+			continue
+		}
+
+		if !constrained {
+			for warehouseID := range available[key] {
+				candidates = append(candidates, warehouseID)
+			}
+			constrained = true
+		}
+		candidates = slices.DeleteFunc(candidates, func(warehouseID int64) bool {
+			return available[key][warehouseID] < demand[key]
+		})
+	}
+
+	if constrained && len(candidates) == 0 {
+		return nil, nil, fmt.Errorf("%w: no single warehouse holds every item", ErrInsufficientStock)
+	}
+	return fulfilments, candidates, nil
+}
+
+// expirePendingOrders expires the orders of a user that are still pending
+// payment and releases their stock reservations and delivery slots. It
+// returns the number of expired orders.
+func expirePendingOrders(ctx context.Context, q queryer, userID int64) (int, error) {
+	query := `
+		WITH expired AS (
+			UPDATE orders SET status = 'expired', version = version + 1
+			WHERE user_id = $1 AND status = 'pending_payment'
+			RETURNING order_id, payment_id
+		), released AS (
+			UPDATE stock_reservation SET status = 'released'
+			WHERE order_id IN (SELECT order_id FROM expired) AND status = 'active'
+		), cancelled AS (
+			UPDATE payment SET status = 'cancelled'
+			WHERE payment_id IN (SELECT payment_id FROM expired)
+		)
+		SELECT COALESCE(ARRAY_AGG(order_id), '{}') FROM expired`
+
+	var expired []int64
+	if err := q.QueryRowContext(ctx, query, userID).Scan(&expired); err != nil {
+		return 0, err
+	}
+
+	if err := releaseDeliverySlots(ctx, q, expired); err != nil {
+		return 0, err
+	}
+	return len(expired), nil
+}
+
+// lockPendingOrder locks a specific order for the rest of the transaction
+// and returns its user and payment ids. It returns ErrInvalidOrderStatus if
+// the order is no longer pending payment.
+func lockPendingOrder(ctx context.Context, q queryer, orderID int64) (userID, paymentID int64, err error) {
+	query := `
+		SELECT user_id, payment_id, status
+		FROM orders
+		WHERE order_id = $1
+		FOR UPDATE`
+
+	var status string
+	err = q.QueryRowContext(ctx, query, orderID).Scan(&userID, &paymentID, &status)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, 0, ErrRecordNotFound
+		default:
+			return 0, 0, err
+		}
+	}
+
+	if status != OrderStatusPendingPayment {
+		return 0, 0, ErrInvalidOrderStatus
+	}
+	return userID, paymentID, nil
+}
+
+// restockCancelledOrder puts back the stock committed for a cancelled
+// order that was not shipped, with a cancellation in the inventory ledger
+// of the warehouses it was reserved in. The shipped items are taken from
+// the reserved stock before the backordered one, as the backordered stock
+// was never reserved.
+func restockCancelledOrder(ctx context.Context, q queryer, orderID, actorID int64) error {
+	query := `
+		SELECT furniture_id, variant_id, warehouse_id, SUM(quantity)
+		FROM stock_reservation
+		WHERE order_id = $1 AND status = 'committed'
+		GROUP BY furniture_id, variant_id, warehouse_id
+		ORDER BY furniture_id, variant_id, warehouse_id`
+
+	rows, err := q.QueryContext(ctx, query, orderID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	type committed struct {
+		furnitureID int64
+		variantID   *int64
+		warehouseID int64
+		quantity    int
+	}
+
+	var reservations []committed
+	for rows.Next() {
+		var c committed
+		if err = rows.Scan(&c.furnitureID, &c.variantID, &c.warehouseID, &c.quantity); err != nil {
+			return err
+		}
+		reservations = append(reservations, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	if len(reservations) == 0 {
+		return nil
+	}
+
+	items, err := getOrderItems(ctx, q, []int64{orderID})
+	if err != nil {
+		return err
+	}
+
+	var consumed []stockLine
+	for _, item := range items[orderID] {
+		quantity := min(item.ShippedQuantity, item.Quantity-item.BackorderedQuantity)
+		if quantity > 0 {
+			consumed = append(consumed, stockLine{item.FurnitureID, item.VariantID, item.Name, quantity})
+		}
+	}
+
+	lines, err := expandBundles(ctx, q, consumed)
+	if err != nil {
+		return err
+	}
+
+	shipped := make(map[stockKey]int)
+	for _, expanded := range lines {
+		for _, line := range expanded {
+			shipped[stockKey{line.furnitureID, derefID(line.variantID)}] += line.quantity
+		}
+	}
+
+	for _, r := range reservations {
+		key := stockKey{r.furnitureID, derefID(r.variantID)}
+		taken := min(shipped[key], r.quantity)
+		shipped[key] -= taken
+		if r.quantity == taken {
+			continue
+		}
+
+		movement := InventoryMovement{
+			FurnitureID: r.furnitureID,
+			VariantID:   r.variantID,
+			WarehouseID: r.warehouseID,
+			Quantity:    r.quantity - taken,
+			Reason:      MovementReasonCancellation,
+			ActorID:     &actorID,
+			Reference:   fmt.Sprintf("order:%d", orderID),
+		}
+		if err = recordMovement(ctx, q, &movement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getOrderItems retrieve the items of the orders, with the quantities
+// shipped and returned so far, grouped by order id.
+func getOrderItems(ctx context.Context, q queryer, orderIDs []int64) (map[int64][]OrderItem, error) {
+	query := `
+		SELECT oi.order_id, oi.order_item_id, oi.furniture_id, oi.variant_id, f.name, oi.quantity, oi.price,
+			oi.discount_amount, oi.tax_class, oi.tax_percent, oi.tax_amount, oi.backordered_quantity,
+			oi.expected_ship_date,
+			(SELECT COALESCE(SUM(si.quantity), 0) FROM shipment_item si WHERE si.order_item_id = oi.order_item_id),
+			(SELECT COALESCE(SUM(ri.quantity), 0) FROM return_item ri
+				JOIN return_request rr ON ri.return_id = rr.return_id
+				WHERE ri.order_item_id = oi.order_item_id AND rr.status <> 'rejected')
+		FROM order_item oi
+		JOIN furniture f ON oi.furniture_id = f.furniture_id
+		WHERE oi.order_id = ANY($1)
+		ORDER BY oi.order_item_id`
+
+	rows, err := q.QueryContext(ctx, query, orderIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make(map[int64][]OrderItem)
+	for rows.Next() {
+		var orderID int64
+		var item OrderItem
+		err = rows.Scan(
+			&orderID,
+			&item.OrderItemID,
+			&item.FurnitureID,
+			&item.VariantID,
+			&item.Name,
+			&item.Quantity,
+			&item.Price,
+			&item.Discount,
+			&item.TaxClass,
+			&item.TaxPercent,
+			&item.Tax,
+			&item.BackorderedQuantity,
+			&item.ExpectedShipDate,
+			&item.ShippedQuantity,
+			&item.ReturnedQuantity,
+		)
+		if err != nil {
+			return nil, err
+		}
+		items[orderID] = append(items[orderID], item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// getOrderShipments retrieve the shipments the orders were shipped in, with
+// their items, grouped by order id. The shipment of an order that was not
+// shipped yet is not returned.
+func getOrderShipments(ctx context.Context, q queryer, orderIDs []int64) (map[int64][]OrderShipment, error) {
+	query := `
+		SELECT s.order_id, s.shipment_id, s.status, s.warehouse_id, s.carrier, s.tracking_number, s.shipment_date,
+			oi.order_item_id, oi.furniture_id, oi.variant_id, f.name, si.quantity
+		FROM shipment s
+		JOIN shipment_item si ON s.shipment_id = si.shipment_id
+		JOIN order_item oi ON si.order_item_id = oi.order_item_id
+		JOIN furniture f ON oi.furniture_id = f.furniture_id
+		WHERE s.order_id = ANY($1)
+		ORDER BY s.order_id, s.shipment_id, oi.order_item_id`
+
+	rows, err := q.QueryContext(ctx, query, orderIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shipments := make(map[int64][]OrderShipment)
+	for _, orderID := range orderIDs {
+		shipments[orderID] = []OrderShipment{}
+	}
+
+	for rows.Next() {
+		var orderID int64
+		var shipment OrderShipment
+		var item ShipmentItem
+		err = rows.Scan(
+			&orderID,
+			&shipment.ShipmentID,
+			&shipment.Status,
+			&shipment.WarehouseID,
+			&shipment.Carrier,
+			&shipment.TrackingNumber,
+			&shipment.ShipmentDate,
+			&item.OrderItemID,
+			&item.FurnitureID,
+			&item.VariantID,
+			&item.Name,
+			&item.Quantity,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		// The rows of a shipment are consecutive, one per item.
+		n := len(shipments[orderID])
+		if n == 0 || shipments[orderID][n-1].ShipmentID != shipment.ShipmentID {
+			shipments[orderID] = append(shipments[orderID], shipment)
+			n++
+		}
+		shipments[orderID][n-1].Items = append(shipments[orderID][n-1].Items, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return shipments, nil
+}
+
+// derefInt returns the value, or zero when it is nil.
+func derefInt(value *int) int {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
+
+// derefID returns the id, or zero when it is nil.
+func derefID(id *int64) int64 {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
@@ -0,0 +1,79 @@
+package data
+
+import (
+	"github.com/hayohtee/fumode/internal/money"
+	"github.com/hayohtee/fumode/internal/validator"
+	"time"
+)
+
+// The statuses a price schedule goes through. A scheduled sale becomes
+// active at its start time and ends at its end time, unless it is
+// cancelled first.
+const (
+	PriceScheduleStatusScheduled = "scheduled"
+	PriceScheduleStatusActive    = "active"
+	PriceScheduleStatusEnded     = "ended"
+	PriceScheduleStatusCancelled = "cancelled"
+)
+
+// The reasons the price of a furniture changes.
+const (
+	PriceChangeCreated           = "created"
+	PriceChangeUpdated           = "updated"
+	PriceChangeSaleStarted       = "sale_started"
+	PriceChangeSaleEnded         = "sale_ended"
+	PriceChangeComponentsChanged = "components_changed"
+)
+
+// MaxPrice is the highest price the DECIMAL(10, 2) columns can hold.
+var MaxPrice = money.New(99_999_999_99, money.DefaultCurrency)
+
+// LowestPriceDays is the number of days before the current price took
+// effect over which the lowest price of a furniture is reported.
+const LowestPriceDays = 30
+
+// PriceSchedule is a struct that holds a sale of a furniture at
+// SalePrice between StartsAt and EndsAt. The regular price of the
+// furniture is restored when the sale ends.
+type PriceSchedule struct {
+	ScheduleID  int64       `json:"schedule_id"`
+	FurnitureID int64       `json:"furniture_id"`
+	SalePrice   money.Money `json:"sale_price"`
+	StartsAt    time.Time   `json:"starts_at"`
+	EndsAt      time.Time   `json:"ends_at"`
+	Status      string      `json:"status"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+// PriceChange is a struct that holds a price a furniture has been sold
+// at, from CreatedAt until the next change. CompareAtPrice is the
+// regular price while a sale is running.
+type PriceChange struct {
+	HistoryID      int64        `json:"history_id"`
+	Price          money.Money  `json:"price"`
+	CompareAtPrice *money.Money `json:"compare_at_price"`
+	Reason         string       `json:"reason"`
+	ScheduleID     *int64       `json:"schedule_id"`
+	CreatedAt      time.Time    `json:"created_at"`
+}
+
+// ValidateMoney checks that the amount is in the currency the prices are
+// stored in.
+func ValidateMoney(v *validator.Validator, key string, amount money.Money) {
+	v.Check(amount.InCurrency(money.DefaultCurrency), key, "must be in "+money.DefaultCurrency)
+}
+
+func ValidatePrice(v *validator.Validator, key string, price money.Money) {
+	ValidateMoney(v, key, price)
+	v.Check(price.IsPositive(), key, "must be greater than zero")
+	v.Check(price.Cmp(MaxPrice) <= 0, key, "must not be more than "+MaxPrice.String())
+}
+
+func ValidatePriceSchedule(v *validator.Validator, schedule PriceSchedule) {
+	ValidatePrice(v, "sale_price", schedule.SalePrice)
+	v.Check(!schedule.StartsAt.IsZero(), "starts_at", "must be provided")
+	v.Check(!schedule.EndsAt.IsZero(), "ends_at", "must be provided")
+	v.Check(schedule.StartsAt.Before(schedule.EndsAt), "ends_at", "must be after starts_at")
+	v.Check(schedule.EndsAt.After(time.Now()), "ends_at", "must be in the future")
+	v.Check(schedule.EndsAt.Sub(schedule.StartsAt) <= 366*24*time.Hour, "ends_at", "must not be more than a year after starts_at")
+}
@@ -0,0 +1,429 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/hayohtee/fumode/internal/money"
+	"time"
+)
+
+// PriceRepository is a type which wraps around a sql.DB connection pool
+// and provide methods for managing the prices of the furniture, their
+// scheduled sales and their price history.
+type PriceRepository struct {
+	DB *sql.DB
+}
+
+// SetPrice sets the regular price of a specific furniture. While a sale
+// is running the sale price is kept and the new price is restored when
+// the sale ends. It returns ErrInvalidPrice if the furniture is a bundle
+// priced from its components, or if the price is not above the sale price
+// of its scheduled sales.
+func (p PriceRepository) SetPrice(furnitureID int64, price money.Money) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := p.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = lockPricedFurniture(ctx, tx, furnitureID); err != nil {
+		return err
+	}
+
+	query := `
+		SELECT COALESCE(MAX(sale_price), 0)
+		FROM price_schedule
+		WHERE furniture_id = $1 AND status IN ('scheduled', 'active')`
+
+	var maxSalePrice money.Money
+	if err = tx.QueryRowContext(ctx, query, furnitureID).Scan(&maxSalePrice); err != nil {
+		return err
+	}
+
+	if price.Cmp(maxSalePrice) <= 0 {
+		return fmt.Errorf("%w: the price must be more than the sale price %s of a scheduled sale", ErrInvalidPrice, maxSalePrice)
+	}
+
+	query = `
+		UPDATE furniture
+		SET price = CASE WHEN compare_at_price IS NULL THEN $1 ELSE price END,
+			compare_at_price = CASE WHEN compare_at_price IS NULL THEN NULL ELSE $1 END,
+			version = version + 1
+		WHERE furniture_id = $2`
+
+	if _, err = tx.ExecContext(ctx, query, price, furnitureID); err != nil {
+		return err
+	}
+
+	if err = recordPriceChange(ctx, tx, furnitureID, PriceChangeUpdated, nil); err != nil {
+		return err
+	}
+
+	if err = refreshBundlePrices(ctx, tx, furnitureID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// InsertSchedule schedules a sale of a furniture. The sale starts on the
+// next run of ApplySchedules after its start time. It returns
+// ErrInvalidPrice if the furniture is a bundle priced from its components
+// or if the sale price is not below the regular price, and
+// ErrScheduleOverlap if another sale of the furniture is scheduled over
+// the same period.
+func (p PriceRepository) InsertSchedule(schedule *PriceSchedule) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := p.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = lockPricedFurniture(ctx, tx, schedule.FurnitureID); err != nil {
+		return err
+	}
+
+	query := `
+		SELECT COALESCE(f.compare_at_price, f.price), EXISTS (
+			SELECT 1 FROM price_schedule s
+			WHERE s.furniture_id = f.furniture_id AND s.status IN ('scheduled', 'active')
+			AND s.starts_at < $3 AND s.ends_at > $2
+		)
+		FROM furniture f
+		WHERE f.furniture_id = $1`
+
+	var regularPrice money.Money
+	var overlap bool
+	err = tx.QueryRowContext(ctx, query, schedule.FurnitureID, schedule.StartsAt, schedule.EndsAt).Scan(&regularPrice, &overlap)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case schedule.SalePrice.Cmp(regularPrice) >= 0:
+		return fmt.Errorf("%w: the sale price must be less than the regular price %s", ErrInvalidPrice, regularPrice)
+	case overlap:
+		return ErrScheduleOverlap
+	}
+
+	query = `
+		INSERT INTO price_schedule(furniture_id, sale_price, starts_at, ends_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING schedule_id, status, created_at`
+
+	args := []any{schedule.FurnitureID, schedule.SalePrice, schedule.StartsAt, schedule.EndsAt}
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&schedule.ScheduleID, &schedule.Status, &schedule.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetSchedules retrieve the sales of a specific furniture, the latest
+// first.
+func (p PriceRepository) GetSchedules(furnitureID int64) ([]PriceSchedule, error) {
+	query := `
+		SELECT schedule_id, furniture_id, sale_price, starts_at, ends_at, status, created_at
+		FROM price_schedule
+		WHERE furniture_id = $1
+		ORDER BY starts_at DESC, schedule_id DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := p.DB.QueryContext(ctx, query, furnitureID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := []PriceSchedule{}
+	for rows.Next() {
+		var s PriceSchedule
+		err = rows.Scan(&s.ScheduleID, &s.FurnitureID, &s.SalePrice, &s.StartsAt, &s.EndsAt, &s.Status, &s.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// CancelSchedule cancels a specific sale of a furniture. A running sale
+// ends immediately and the regular price is restored. It returns
+// ErrInvalidScheduleStatus if the sale has already ended or been
+// cancelled.
+func (p PriceRepository) CancelSchedule(furnitureID, scheduleID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := p.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT status
+		FROM price_schedule
+		WHERE furniture_id = $1 AND schedule_id = $2
+		FOR UPDATE`
+
+	var status string
+	err = tx.QueryRowContext(ctx, query, furnitureID, scheduleID).Scan(&status)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	switch status {
+	case PriceScheduleStatusScheduled:
+		query = `UPDATE price_schedule SET status = $1 WHERE schedule_id = $2`
+		_, err = tx.ExecContext(ctx, query, PriceScheduleStatusCancelled, scheduleID)
+	case PriceScheduleStatusActive:
+		err = endSale(ctx, tx, furnitureID, scheduleID, PriceScheduleStatusCancelled)
+	default:
+		return ErrInvalidScheduleStatus
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ApplySchedules ends the running sales whose end time has passed, then
+// starts the scheduled sales whose start time has passed. A sale whose
+// whole period has passed before it could start is ended without being
+// applied. It returns the number of sales started and ended.
+func (p PriceRepository) ApplySchedules() (started, ended int, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := p.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT schedule_id, furniture_id, sale_price, starts_at, ends_at, status, created_at
+		FROM price_schedule
+		WHERE (status = 'active' AND ends_at <= NOW()) OR (status = 'scheduled' AND starts_at <= NOW())
+		ORDER BY status = 'scheduled', furniture_id, starts_at
+		FOR UPDATE SKIP LOCKED`
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+
+	var schedules []PriceSchedule
+	for rows.Next() {
+		var s PriceSchedule
+		err = rows.Scan(&s.ScheduleID, &s.FurnitureID, &s.SalePrice, &s.StartsAt, &s.EndsAt, &s.Status, &s.CreatedAt)
+		if err != nil {
+			return 0, 0, err
+		}
+		schedules = append(schedules, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return 0, 0, err
+	}
+	rows.Close()
+
+	now := time.Now()
+	for _, s := range schedules {
+		switch {
+		case s.Status == PriceScheduleStatusActive:
+			err = endSale(ctx, tx, s.FurnitureID, s.ScheduleID, PriceScheduleStatusEnded)
+			ended++
+		case !s.EndsAt.After(now):
+			query = `UPDATE price_schedule SET status = $1 WHERE schedule_id = $2`
+			_, err = tx.ExecContext(ctx, query, PriceScheduleStatusEnded, s.ScheduleID)
+			ended++
+		default:
+			err = startSale(ctx, tx, s)
+			started++
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+	return started, ended, nil
+}
+
+// GetHistory retrieve the price changes of a specific furniture over the
+// provided number of days, the latest first, including the change that
+// set the price in effect at the start of the period.
+func (p PriceRepository) GetHistory(furnitureID int64, days int) ([]PriceChange, error) {
+	query := `
+		SELECT history_id, price, compare_at_price, reason, schedule_id, created_at
+		FROM price_history
+		WHERE furniture_id = $1
+		AND created_at >= COALESCE((
+			SELECT MAX(created_at) FROM price_history
+			WHERE furniture_id = $1 AND created_at <= NOW() - $2 * INTERVAL '1 day'
+		), '-infinity')
+		ORDER BY created_at DESC, history_id DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := p.DB.QueryContext(ctx, query, furnitureID, days)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []PriceChange{}
+	for rows.Next() {
+		var c PriceChange
+		err = rows.Scan(&c.HistoryID, &c.Price, &c.CompareAtPrice, &c.Reason, &c.ScheduleID, &c.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// GetLowestPrice returns the lowest price a specific furniture was sold
+// at over the provided number of days before its current price took
+// effect, which is the reference price to display alongside a reduction.
+// It returns nil when the furniture had no price before the current one.
+func (p PriceRepository) GetLowestPrice(furnitureID int64, days int) (*money.Money, error) {
+	query := `
+		WITH current AS (
+			SELECT MAX(created_at) AS since FROM price_history WHERE furniture_id = $1
+		)
+		SELECT MIN(h.price)
+		FROM price_history h, current c
+		WHERE h.furniture_id = $1 AND h.created_at < c.since
+		AND h.created_at >= COALESCE((
+			SELECT MAX(created_at) FROM price_history
+			WHERE furniture_id = $1 AND created_at <= c.since - $2 * INTERVAL '1 day'
+		), '-infinity')`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var lowest *money.Money
+	err := p.DB.QueryRowContext(ctx, query, furnitureID, days).Scan(&lowest)
+	return lowest, err
+}
+
+// lockPricedFurniture locks a specific furniture before changing its
+// price. It returns ErrInvalidPrice if it is a bundle priced from its
+// components, as its price follows theirs.
+func lockPricedFurniture(ctx context.Context, q queryer, furnitureID int64) error {
+	query := `
+		SELECT bundle_discount_percent IS NOT NULL
+		FROM furniture
+		WHERE furniture_id = $1
+		FOR UPDATE`
+
+	var discounted bool
+	err := q.QueryRowContext(ctx, query, furnitureID).Scan(&discounted)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	if discounted {
+		return fmt.Errorf("%w: the price of the bundle follows the prices of its components", ErrInvalidPrice)
+	}
+	return nil
+}
+
+// startSale sets the price of the furniture to the sale price of the
+// schedule, keeping its regular price as the compare at price.
+func startSale(ctx context.Context, q queryer, schedule PriceSchedule) error {
+	query := `
+		UPDATE furniture
+		SET compare_at_price = COALESCE(compare_at_price, price), price = $1, version = version + 1
+		WHERE furniture_id = $2`
+
+	if _, err := q.ExecContext(ctx, query, schedule.SalePrice, schedule.FurnitureID); err != nil {
+		return err
+	}
+
+	query = `UPDATE price_schedule SET status = $1 WHERE schedule_id = $2`
+	if _, err := q.ExecContext(ctx, query, PriceScheduleStatusActive, schedule.ScheduleID); err != nil {
+		return err
+	}
+
+	if err := recordPriceChange(ctx, q, schedule.FurnitureID, PriceChangeSaleStarted, &schedule.ScheduleID); err != nil {
+		return err
+	}
+
+	return refreshBundlePrices(ctx, q, schedule.FurnitureID)
+}
+
+// endSale restores the regular price of the furniture and gives the
+// schedule of the sale the provided status.
+func endSale(ctx context.Context, q queryer, furnitureID, scheduleID int64, status string) error {
+	query := `
+		UPDATE furniture
+		SET price = compare_at_price, compare_at_price = NULL, version = version + 1
+		WHERE furniture_id = $1 AND compare_at_price IS NOT NULL`
+
+	if _, err := q.ExecContext(ctx, query, furnitureID); err != nil {
+		return err
+	}
+
+	query = `UPDATE price_schedule SET status = $1 WHERE schedule_id = $2`
+	if _, err := q.ExecContext(ctx, query, status, scheduleID); err != nil {
+		return err
+	}
+
+	if err := recordPriceChange(ctx, q, furnitureID, PriceChangeSaleEnded, &scheduleID); err != nil {
+		return err
+	}
+
+	return refreshBundlePrices(ctx, q, furnitureID)
+}
+
+// recordPriceChange records the current price of the furniture in its
+// price history.
+func recordPriceChange(ctx context.Context, q queryer, furnitureID int64, reason string, scheduleID *int64) error {
+	query := `
+		INSERT INTO price_history(furniture_id, price, compare_at_price, reason, schedule_id)
+		SELECT furniture_id, price, compare_at_price, $2, $3
+		FROM furniture
+		WHERE furniture_id = $1`
+
+	_, err := q.ExecContext(ctx, query, furnitureID, reason, scheduleID)
+	return err
+}
@@ -17,12 +17,164 @@ var (
 	// ErrDuplicateEmail is a custom error that is returned when there
 	// is a duplicate email in the database.
 	ErrDuplicateEmail = errors.New("duplicate email")
+
+	// ErrDuplicateName is a custom error that is returned when there
+	// is a duplicate name in the database.
+	ErrDuplicateName = errors.New("duplicate name")
+
+	// ErrDuplicateSlug is a custom error that is returned when there
+	// is a duplicate slug in the database.
+	ErrDuplicateSlug = errors.New("duplicate slug")
+
+	// ErrInvalidParentCategory is a custom error that is returned when
+	// a category references a parent category that does not exist.
+	ErrInvalidParentCategory = errors.New("invalid parent category")
+
+	// ErrCategoryCycle is a custom error that is returned when a category
+	// would become a descendant of itself.
+	ErrCategoryCycle = errors.New("category cycle")
+
+	// ErrCategoryInUse is a custom error that is returned when deleting
+	// a category that still has sub categories or furniture.
+	ErrCategoryInUse = errors.New("category in use")
+
+	// ErrDuplicateSKU is a custom error that is returned when there
+	// is a duplicate variant SKU in the database.
+	ErrDuplicateSKU = errors.New("duplicate sku")
+
+	// ErrDuplicateVariantOptions is a custom error that is returned when
+	// a furniture already has a variant with the same options.
+	ErrDuplicateVariantOptions = errors.New("duplicate variant options")
+
+	// ErrNegativeVariantPrice is a custom error that is returned when the
+	// price delta of a variant takes its price below zero.
+	ErrNegativeVariantPrice = errors.New("negative variant price")
+
+	// ErrEmptyCart is a custom error that is returned when checking out
+	// a cart without any item.
+	ErrEmptyCart = errors.New("empty cart")
+
+	// ErrInsufficientStock is a custom error that is returned when the
+	// available stock of an item is lower than the requested quantity.
+	ErrInsufficientStock = errors.New("insufficient stock")
+
+	// ErrInvalidOrderStatus is a custom error that is returned when an
+	// order is not in the status required by the operation.
+	ErrInvalidOrderStatus = errors.New("invalid order status")
+
+	// ErrInvalidShipmentItems is a custom error that is returned when
+	// shipping items that are not in the order or beyond the quantity
+	// left to ship.
+	ErrInvalidShipmentItems = errors.New("invalid shipment items")
+
+	// ErrInvalidReturnItems is a custom error that is returned when
+	// returning items that are not in the order or beyond the quantity
+	// shipped and not returned yet.
+	ErrInvalidReturnItems = errors.New("invalid return items")
+
+	// ErrReservationExpired is a custom error that is returned when paying
+	// an order that has expired or whose stock reservations have run out.
+	ErrReservationExpired = errors.New("reservation expired")
+
+	// ErrInvalidWarehouse is a custom error that is returned when a stock
+	// movement references a warehouse that does not exist.
+	ErrInvalidWarehouse = errors.New("invalid warehouse")
+
+	// ErrNoWarehouse is a custom error that is returned when an order
+	// needs a warehouse to be fulfilled from but none exists.
+	ErrNoWarehouse = errors.New("no warehouse")
+
+	// ErrInvalidBundle is a custom error that is returned when a furniture
+	// cannot be made a bundle of the provided components.
+	ErrInvalidBundle = errors.New("invalid bundle")
+
+	// ErrVariantInUse is a custom error that is returned when deleting
+	// a variant that is a component of a bundle.
+	ErrVariantInUse = errors.New("variant in use")
+
+	// ErrDuplicateCode is a custom error that is returned when there
+	// is a duplicate coupon, shipping zone or shipping method code.
+	ErrDuplicateCode = errors.New("duplicate code")
+
+	// ErrCouponInUse is a custom error that is returned when deleting
+	// a coupon that has been redeemed.
+	ErrCouponInUse = errors.New("coupon in use")
+
+	// ErrInvalidCoupon is a custom error that is returned when checking
+	// out a cart with a coupon that can no longer be used.
+	ErrInvalidCoupon = errors.New("invalid coupon")
+
+	// ErrInvalidPrice is a custom error that is returned when the price
+	// of a furniture cannot be changed or put on sale as requested.
+	ErrInvalidPrice = errors.New("invalid price")
+
+	// ErrScheduleOverlap is a custom error that is returned when a sale
+	// is scheduled over the period of another sale of the furniture.
+	ErrScheduleOverlap = errors.New("schedule overlap")
+
+	// ErrInvalidScheduleStatus is a custom error that is returned when
+	// cancelling a sale that has already ended or been cancelled.
+	ErrInvalidScheduleStatus = errors.New("invalid schedule status")
+
+	// ErrDuplicateTaxRate is a custom error that is returned when there
+	// is already a rate for the tax class in the same region.
+	ErrDuplicateTaxRate = errors.New("duplicate tax rate")
+
+	// ErrDuplicateRegion is a custom error that is returned when a
+	// region already belongs to another shipping zone.
+	ErrDuplicateRegion = errors.New("duplicate region")
+
+	// ErrInvalidZone is a custom error that is returned when a shipping
+	// rate or a delivery slot references a zone that does not exist.
+	ErrInvalidZone = errors.New("invalid zone")
+
+	// ErrInvalidShippingMethod is a custom error that is returned when
+	// checking out without a shipping method offered for the cart and
+	// the shipping address.
+	ErrInvalidShippingMethod = errors.New("invalid shipping method")
+
+	// ErrDuplicateSlot is a custom error that is returned when a zone
+	// already has a delivery slot starting at the same time.
+	ErrDuplicateSlot = errors.New("duplicate slot")
+
+	// ErrSlotBooked is a custom error that is returned when deleting a
+	// delivery slot that has bookings, or reducing its capacity below them.
+	ErrSlotBooked = errors.New("slot booked")
+
+	// ErrSlotUnavailable is a custom error that is returned when booking a
+	// delivery slot that is full, closed or not in the zone of the shipping
+	// address, or when checking out a delivery that needs a slot without
+	// one.
+	ErrSlotUnavailable = errors.New("slot unavailable")
+
+	// ErrNotReschedulable is a custom error that is returned when
+	// rescheduling the delivery of an order that has no delivery slot, that
+	// is neither pending payment nor paid, or whose slot is past the cutoff.
+	ErrNotReschedulable = errors.New("not reschedulable")
 )
 
 // Repositories is a container that holds all the database repositories for this project.
 type Repositories struct {
-	Users     UserRepository
-	Furniture FurnitureRepository
+	Users         UserRepository
+	Furniture     FurnitureRepository
+	Categories    CategoryRepository
+	Variants      VariantRepository
+	Search        SearchRepository
+	Cart          CartRepository
+	Wishlist      WishlistRepository
+	Orders        OrderRepository
+	Inventory     InventoryRepository
+	Warehouses    WarehouseRepository
+	Alerts        StockAlertRepository
+	Bundles       BundleRepository
+	Coupons       CouponRepository
+	Prices        PriceRepository
+	ExchangeRates ExchangeRateRepository
+	TaxRates      TaxRateRepository
+	Shipping      ShippingRepository
+	DeliverySlots DeliverySlotRepository
+	Shipments     ShipmentRepository
+	Returns       ReturnRepository
 }
 
 // NewRepositories returns a Repositories which contains all initialized repositories for
@@ -30,7 +182,25 @@ type Repositories struct {
 // for the project.
 func NewRepositories(db *sql.DB) Repositories {
 	return Repositories{
-		Users:     UserRepository{DB: db},
-		Furniture: FurnitureRepository{DB: db},
+		Users:         UserRepository{DB: db},
+		Furniture:     FurnitureRepository{DB: db},
+		Categories:    CategoryRepository{DB: db},
+		Variants:      VariantRepository{DB: db},
+		Search:        SearchRepository{DB: db},
+		Cart:          CartRepository{DB: db},
+		Wishlist:      WishlistRepository{DB: db},
+		Orders:        OrderRepository{DB: db},
+		Inventory:     InventoryRepository{DB: db},
+		Warehouses:    WarehouseRepository{DB: db},
+		Alerts:        StockAlertRepository{DB: db},
+		Bundles:       BundleRepository{DB: db},
+		Coupons:       CouponRepository{DB: db},
+		Prices:        PriceRepository{DB: db},
+		ExchangeRates: ExchangeRateRepository{DB: db},
+		TaxRates:      TaxRateRepository{DB: db},
+		Shipping:      ShippingRepository{DB: db},
+		DeliverySlots: DeliverySlotRepository{DB: db},
+		Shipments:     ShipmentRepository{DB: db},
+		Returns:       ReturnRepository{DB: db},
 	}
 }
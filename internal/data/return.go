@@ -0,0 +1,106 @@
+package data
+
+import (
+	"github.com/hayohtee/fumode/internal/money"
+	"github.com/hayohtee/fumode/internal/validator"
+	"time"
+)
+
+// The statuses a return request goes through. A requested return is
+// approved or rejected by an admin, and an approved return is refunding
+// once its items are back, until the payment provider refunded it or the
+// refund failed and must be reconciled by hand.
+const (
+	ReturnStatusRequested    = "requested"
+	ReturnStatusApproved     = "approved"
+	ReturnStatusRejected     = "rejected"
+	ReturnStatusRefunding    = "refunding"
+	ReturnStatusRefunded     = "refunded"
+	ReturnStatusRefundFailed = "refund_failed"
+)
+
+// The reasons a customer can return items for.
+const (
+	ReturnReasonDamaged        = "damaged"
+	ReturnReasonDefective      = "defective"
+	ReturnReasonWrongItem      = "wrong_item"
+	ReturnReasonNotAsDescribed = "not_as_described"
+	ReturnReasonChangedMind    = "changed_mind"
+)
+
+// ReturnReasons holds every reason of the return requests.
+var ReturnReasons = []string{
+	ReturnReasonDamaged,
+	ReturnReasonDefective,
+	ReturnReasonWrongItem,
+	ReturnReasonNotAsDescribed,
+	ReturnReasonChangedMind,
+}
+
+// What becomes of a returned item once received: it is either put back in
+// stock or written off, such as when it arrived damaged.
+const (
+	DispositionRestock  = "restock"
+	DispositionWriteOff = "write_off"
+)
+
+// MaxReturnPhotos is how many photos can be attached to a return request.
+const MaxReturnPhotos = 5
+
+// ReturnRequest is a struct that holds a request of a customer to return
+// items of an order. PhotoURLs are the photos the customer attached, such
+// as of the damage. ResolutionNote is the note of the admin who reviewed
+// the request, and RefundAmount what was refunded for it, in the base
+// currency.
+type ReturnRequest struct {
+	ReturnID       int64        `json:"return_id"`
+	OrderID        int64        `json:"order_id"`
+	UserID         int64        `json:"user_id"`
+	Status         string       `json:"status"`
+	Reason         string       `json:"reason"`
+	Description    string       `json:"description"`
+	PhotoURLs      []string     `json:"photo_urls"`
+	ResolutionNote string       `json:"resolution_note"`
+	RefundAmount   money.Money  `json:"refund_amount"`
+	Items          []ReturnItem `json:"items"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+	Version        int          `json:"version"`
+}
+
+// ReturnItem is a struct that holds the quantity of an order item returned.
+// Disposition is set once the item is received.
+type ReturnItem struct {
+	OrderItemID int64   `json:"order_item_id"`
+	FurnitureID int64   `json:"furniture_id"`
+	VariantID   *int64  `json:"variant_id"`
+	Name        string  `json:"name"`
+	Quantity    int     `json:"quantity"`
+	Disposition *string `json:"disposition"`
+}
+
+func ValidateReturnRequest(v *validator.Validator, ret ReturnRequest) {
+	v.Check(validator.PermittedValue(ret.Reason, ReturnReasons...), "reason", "invalid reason")
+	v.Check(len(ret.Description) <= 2000, "description", "must not be more than 2000 bytes long")
+	v.Check(len(ret.Items) > 0, "items", "must contain at least one item")
+	v.Check(len(ret.Items) <= 100, "items", "must not contain more than 100 items")
+
+	seen := make(map[int64]bool)
+	for _, item := range ret.Items {
+		v.Check(item.OrderItemID > 0, "items", "must reference valid order items")
+		v.Check(item.Quantity > 0, "items", "must have quantities greater than zero")
+		v.Check(!seen[item.OrderItemID], "items", "must not repeat an order item")
+		seen[item.OrderItemID] = true
+	}
+}
+
+// RefundableAmount returns what was paid for a quantity of an item of the
+// order, which is its share of the discounted price of the item, with the
+// tax when it was added to the price. The shipping cost is not refunded.
+func (order Order) RefundableAmount(item OrderItem, quantity int) money.Money {
+	paid := item.Price.Mul(item.Quantity).Sub(item.Discount)
+	if !order.PricesIncludeTax {
+		paid = paid.Add(item.Tax)
+	}
+	return paid.Allocate([]int64{int64(quantity), int64(item.Quantity - quantity)})[0]
+}
@@ -0,0 +1,508 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// returnColumns is the list of columns selected for a return request, in
+// the order expected by ReturnRequest.scanDest.
+const returnColumns = `return_id, order_id, user_id, status, reason, description, photo_urls, resolution_note,
+	refund_amount, created_at, updated_at, version`
+
+// scanDest returns the destinations for scanning the return request columns.
+func (ret *ReturnRequest) scanDest() []any {
+	return []any{
+		&ret.ReturnID,
+		&ret.OrderID,
+		&ret.UserID,
+		&ret.Status,
+		&ret.Reason,
+		&ret.Description,
+		&ret.PhotoURLs,
+		&ret.ResolutionNote,
+		&ret.RefundAmount,
+		&ret.CreatedAt,
+		&ret.UpdatedAt,
+		&ret.Version,
+	}
+}
+
+// ReturnRepository is a type which wraps around a sql.DB connection pool
+// and provide methods for managing the return requests of the orders, and
+// refunding them, to and from the database.
+type ReturnRepository struct {
+	DB *sql.DB
+}
+
+// Insert a return request of items of an order to the database. Only the
+// items shipped and not returned yet can be returned. It returns
+// ErrInvalidOrderStatus if nothing of the order was shipped yet and
+// ErrInvalidReturnItems if an item is not in the order or its quantity is
+// beyond what can be returned.
+func (rr ReturnRepository) Insert(ret *ReturnRequest) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := rr.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock the order, so that concurrent requests cannot return the same
+	// items twice.
+	query := `SELECT status FROM orders WHERE order_id = $1 FOR UPDATE`
+
+	var status string
+	err = tx.QueryRowContext(ctx, query, ret.OrderID).Scan(&status)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	switch status {
+	case OrderStatusPartiallyShipped, OrderStatusShipped, OrderStatusPartiallyDelivered, OrderStatusDelivered:
+	default:
+		return ErrInvalidOrderStatus
+	}
+
+	items, err := getOrderItems(ctx, tx, []int64{ret.OrderID})
+	if err != nil {
+		return err
+	}
+
+	returnable := make(map[int64]OrderItem)
+	for _, item := range items[ret.OrderID] {
+		returnable[item.OrderItemID] = item
+	}
+
+	for i, item := range ret.Items {
+		orderItem, ok := returnable[item.OrderItemID]
+		left := orderItem.ShippedQuantity - orderItem.ReturnedQuantity
+		switch {
+		case !ok:
+			return fmt.Errorf("%w: item %d is not in the order", ErrInvalidReturnItems, item.OrderItemID)
+		case item.Quantity > left:
+			return fmt.Errorf("%w: only %d of item %d can be returned", ErrInvalidReturnItems, left, item.OrderItemID)
+		}
+
+		ret.Items[i].FurnitureID = orderItem.FurnitureID
+		ret.Items[i].VariantID = orderItem.VariantID
+		ret.Items[i].Name = orderItem.Name
+		ret.Items[i].Disposition = nil
+	}
+
+	query = `
+		INSERT INTO return_request(order_id, user_id, reason, description)
+		VALUES ($1, $2, $3, $4)
+		RETURNING return_id, status, photo_urls, resolution_note, refund_amount, created_at, updated_at, version`
+
+	args := []any{ret.OrderID, ret.UserID, ret.Reason, ret.Description}
+	dest := []any{&ret.ReturnID, &ret.Status, &ret.PhotoURLs, &ret.ResolutionNote, &ret.RefundAmount, &ret.CreatedAt, &ret.UpdatedAt, &ret.Version}
+	if err = tx.QueryRowContext(ctx, query, args...).Scan(dest...); err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO return_item(return_id, order_item_id, quantity)
+		VALUES ($1, $2, $3)`
+
+	for _, item := range ret.Items {
+		if _, err = tx.ExecContext(ctx, query, ret.ReturnID, item.OrderItemID, item.Quantity); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetByID retrieve a specific return request, with its items, from the
+// database given the id.
+func (rr ReturnRepository) GetByID(id int64) (ReturnRequest, error) {
+	query := `
+		SELECT ` + returnColumns + `
+		FROM return_request
+		WHERE return_id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var ret ReturnRequest
+	err := rr.DB.QueryRowContext(ctx, query, id).Scan(ret.scanDest()...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ReturnRequest{}, ErrRecordNotFound
+		default:
+			return ReturnRequest{}, err
+		}
+	}
+
+	items, err := getReturnItems(ctx, rr.DB, []int64{ret.ReturnID})
+	if err != nil {
+		return ReturnRequest{}, err
+	}
+
+	ret.Items = items[ret.ReturnID]
+	return ret, nil
+}
+
+// GetAll retrieve the return requests in a status, or in every status when
+// it is empty, with their items alongside the pagination metadata.
+func (rr ReturnRepository) GetAll(status string, filters Filters) ([]ReturnRequest, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER(), %s
+		FROM return_request
+		WHERE ($1 = '' OR status = $1)
+		ORDER BY %s %s, return_id ASC
+		LIMIT $2 OFFSET $3`, returnColumns, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := rr.DB.QueryContext(ctx, query, status, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	returns := []ReturnRequest{}
+	returnIDs := []int64{}
+
+	for rows.Next() {
+		var ret ReturnRequest
+		err = rows.Scan(append([]any{&totalRecords}, ret.scanDest()...)...)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		returns = append(returns, ret)
+		returnIDs = append(returnIDs, ret.ReturnID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	items, err := getReturnItems(ctx, rr.DB, returnIDs)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	for i := range returns {
+		returns[i].Items = items[returns[i].ReturnID]
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return returns, metadata, nil
+}
+
+// GetAllForOrder retrieve the return requests of a specific order, with
+// their items, from the oldest.
+func (rr ReturnRepository) GetAllForOrder(orderID int64) ([]ReturnRequest, error) {
+	query := `
+		SELECT ` + returnColumns + `
+		FROM return_request
+		WHERE order_id = $1
+		ORDER BY return_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := rr.DB.QueryContext(ctx, query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	returns := []ReturnRequest{}
+	returnIDs := []int64{}
+
+	for rows.Next() {
+		var ret ReturnRequest
+		if err = rows.Scan(ret.scanDest()...); err != nil {
+			return nil, err
+		}
+		returns = append(returns, ret)
+		returnIDs = append(returnIDs, ret.ReturnID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	items, err := getReturnItems(ctx, rr.DB, returnIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range returns {
+		returns[i].Items = items[returns[i].ReturnID]
+	}
+	return returns, nil
+}
+
+// AddPhotos attaches the photos to a return request that was not reviewed
+// yet. It returns ErrEditConflict if the request was changed since it was
+// read.
+func (rr ReturnRepository) AddPhotos(ret *ReturnRequest, urls []string) error {
+	query := `
+		UPDATE return_request
+		SET photo_urls = photo_urls || $1::TEXT[], updated_at = NOW(), version = version + 1
+		WHERE return_id = $2 AND version = $3 AND status = 'requested'
+		RETURNING photo_urls, updated_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := rr.DB.QueryRowContext(ctx, query, urls, ret.ReturnID, ret.Version).Scan(&ret.PhotoURLs, &ret.UpdatedAt, &ret.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// Review records that a requested return was approved or rejected, with
+// the status and resolution note of the return request. It returns
+// ErrEditConflict if the request was changed since it was read.
+func (rr ReturnRepository) Review(ret *ReturnRequest) error {
+	query := `
+		UPDATE return_request
+		SET status = $1, resolution_note = $2, updated_at = NOW(), version = version + 1
+		WHERE return_id = $3 AND version = $4 AND status = 'requested'
+		RETURNING updated_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []any{ret.Status, ret.ResolutionNote, ret.ReturnID, ret.Version}
+	err := rr.DB.QueryRowContext(ctx, query, args...).Scan(&ret.UpdatedAt, &ret.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// Receive records that the items of an approved return were received in the
+// warehouse, and moves the return request to refunding for its RefundAmount,
+// so that it is only ever refunded once. The items to restock are put back
+// in stock, and the items to write off are recorded as received and then
+// damaged, so that the inventory ledger shows both. The result of the refund
+// is recorded with RecordRefund. It returns ErrEditConflict if the request
+// was changed since it was read.
+func (rr ReturnRepository) Receive(ret *ReturnRequest, warehouseID, actorID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := rr.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `SELECT 1 FROM orders WHERE order_id = $1 FOR UPDATE`
+	if _, err = tx.ExecContext(ctx, query, ret.OrderID); err != nil {
+		return err
+	}
+
+	query = `
+		UPDATE return_request
+		SET status = $1, refund_amount = $2, updated_at = NOW(), version = version + 1
+		WHERE return_id = $3 AND version = $4 AND status = 'approved'
+		RETURNING updated_at, version`
+
+	args := []any{ReturnStatusRefunding, ret.RefundAmount, ret.ReturnID, ret.Version}
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&ret.UpdatedAt, &ret.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	ret.Status = ReturnStatusRefunding
+
+	returned := make([]stockLine, 0, len(ret.Items))
+	for _, item := range ret.Items {
+		returned = append(returned, stockLine{item.FurnitureID, item.VariantID, item.Name, item.Quantity})
+	}
+
+	// Bundles are returned as their components.
+	lines, err := expandBundles(ctx, tx, returned)
+	if err != nil {
+		return err
+	}
+
+	query = `
+		UPDATE return_item SET disposition = $1
+		WHERE return_id = $2 AND order_item_id = $3`
+
+	for i, item := range ret.Items {
+		if _, err = tx.ExecContext(ctx, query, item.Disposition, ret.ReturnID, item.OrderItemID); err != nil {
+			return err
+		}
+
+		for _, line := range lines[i] {
+			movement := InventoryMovement{
+				FurnitureID: line.furnitureID,
+				VariantID:   line.variantID,
+				WarehouseID: warehouseID,
+				Quantity:    line.quantity,
+				Reason:      MovementReasonReturn,
+				ActorID:     &actorID,
+				Reference:   fmt.Sprintf("return:%d", ret.ReturnID),
+			}
+			if err = recordMovement(ctx, tx, &movement); err != nil {
+				return err
+			}
+
+			if *item.Disposition != DispositionWriteOff {
+				continue
+			}
+
+			movement = InventoryMovement{
+				FurnitureID: line.furnitureID,
+				VariantID:   line.variantID,
+				WarehouseID: warehouseID,
+				Quantity:    -line.quantity,
+				Reason:      MovementReasonDamage,
+				ActorID:     &actorID,
+				Reference:   fmt.Sprintf("return:%d", ret.ReturnID),
+				Note:        "Written off on return",
+			}
+			if err = recordMovement(ctx, tx, &movement); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// RecordRefund records the result of the refund of a return request that
+// is refunding. When the refund succeeded, the return is refunded, its
+// RefundAmount is added to the payment of the order, and the order is
+// returned once every item was returned and refunded. The return is left
+// refund failed otherwise, to be reconciled by hand.
+func (rr ReturnRepository) RecordRefund(ret *ReturnRequest, succeeded bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := rr.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `SELECT 1 FROM orders WHERE order_id = $1 FOR UPDATE`
+	if _, err = tx.ExecContext(ctx, query, ret.OrderID); err != nil {
+		return err
+	}
+
+	query = `
+		UPDATE return_request
+		SET status = $1, updated_at = NOW(), version = version + 1
+		WHERE return_id = $2 AND status = 'refunding'
+		RETURNING updated_at, version`
+
+	status := ReturnStatusRefundFailed
+	if succeeded {
+		status = ReturnStatusRefunded
+	}
+
+	err = tx.QueryRowContext(ctx, query, status, ret.ReturnID).Scan(&ret.UpdatedAt, &ret.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	ret.Status = status
+
+	if !succeeded {
+		return tx.Commit()
+	}
+
+	query = `
+		UPDATE payment p
+		SET refunded_amount = p.refunded_amount + $1,
+			status = CASE WHEN p.refunded_amount + $1 >= p.amount THEN $2 ELSE $3 END
+		FROM orders o
+		WHERE o.order_id = $4 AND o.payment_id = p.payment_id AND $1 > 0`
+
+	args := []any{ret.RefundAmount, PaymentStatusRefunded, PaymentStatusPartiallyRefunded, ret.OrderID}
+	if _, err = tx.ExecContext(ctx, query, args...); err != nil {
+		return err
+	}
+
+	query = `
+		UPDATE orders o SET status = $1, version = version + 1
+		WHERE o.order_id = $2
+		AND (SELECT SUM(quantity) FROM order_item WHERE order_id = o.order_id) = (
+			SELECT SUM(ri.quantity)
+			FROM return_item ri
+			JOIN return_request r ON ri.return_id = r.return_id
+			WHERE r.order_id = o.order_id AND r.status = 'refunded'
+		)`
+
+	if _, err = tx.ExecContext(ctx, query, OrderStatusReturned, ret.OrderID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// getReturnItems retrieve the items of the return requests, grouped by
+// return id.
+func getReturnItems(ctx context.Context, q queryer, returnIDs []int64) (map[int64][]ReturnItem, error) {
+	query := `
+		SELECT ri.return_id, ri.order_item_id, oi.furniture_id, oi.variant_id, f.name, ri.quantity, ri.disposition
+		FROM return_item ri
+		JOIN order_item oi ON ri.order_item_id = oi.order_item_id
+		JOIN furniture f ON oi.furniture_id = f.furniture_id
+		WHERE ri.return_id = ANY($1)
+		ORDER BY ri.order_item_id`
+
+	rows, err := q.QueryContext(ctx, query, returnIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make(map[int64][]ReturnItem)
+	for rows.Next() {
+		var returnID int64
+		var item ReturnItem
+		err = rows.Scan(&returnID, &item.OrderItemID, &item.FurnitureID, &item.VariantID, &item.Name, &item.Quantity, &item.Disposition)
+		if err != nil {
+			return nil, err
+		}
+		items[returnID] = append(items[returnID], item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,64 @@
+package data
+
+import (
+	"github.com/hayohtee/fumode/internal/validator"
+	"strings"
+	"time"
+)
+
+// SearchResult is a struct that holds a furniture matching a search
+// query, alongside its relevance rank and a snippet of its description
+// with the matching terms wrapped in <mark> tags.
+type SearchResult struct {
+	Furniture Furniture `json:"furniture"`
+	Rank      float64   `json:"rank"`
+	Snippet   string    `json:"snippet"`
+}
+
+func ValidateSearchQuery(v *validator.Validator, query string) {
+	v.Check(query != "", "q", "must be provided")
+	v.Check(len(query) <= 200, "q", "must not be more than 200 bytes long")
+}
+
+// SearchQuery is a struct that holds the anonymized statistics of a
+// search query, aggregated by its normalized text.
+type SearchQuery struct {
+	Query           string    `json:"query"`
+	SearchCount     int64     `json:"search_count"`
+	LastResultCount int       `json:"last_result_count"`
+	LastSearchedAt  time.Time `json:"last_searched_at"`
+}
+
+// Suggestions is a struct that holds the suggestions for a partially
+// typed search query.
+type Suggestions struct {
+	Products   []ProductSuggestion  `json:"products"`
+	Categories []CategorySuggestion `json:"categories"`
+	Queries    []string             `json:"queries"`
+}
+
+// ProductSuggestion is a furniture whose name matches a partially
+// typed search query.
+type ProductSuggestion struct {
+	FurnitureID int64  `json:"furniture_id"`
+	Name        string `json:"name"`
+}
+
+// CategorySuggestion is a category whose name matches a partially
+// typed search query.
+type CategorySuggestion struct {
+	CategoryID int64  `json:"category_id"`
+	Name       string `json:"name"`
+	Slug       string `json:"slug"`
+}
+
+// NormalizeSearchQuery lowercases the query and collapses its whitespace,
+// so that "Oak  Table" and "oak table" are recorded as the same query.
+func NormalizeSearchQuery(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
+
+func ValidateSuggestQuery(v *validator.Validator, query string) {
+	v.Check(len(query) >= 2, "q", "must be at least 2 bytes long")
+	v.Check(len(query) <= 100, "q", "must not be more than 100 bytes long")
+}
@@ -0,0 +1,281 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// suggestionLimit is the maximum number of suggestions returned for
+// each kind of suggestion.
+const suggestionLimit = 5
+
+// likeEscaper escapes the LIKE wildcard characters in user input.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// minSimilarity is the minimum word similarity between the search
+// query and a furniture name for the typo tolerant fallback search.
+const minSimilarity = 0.4
+
+// SearchRepository is a type which wraps around a sql.DB connection pool
+// and provide methods for searching the catalog.
+type SearchRepository struct {
+	DB *sql.DB
+}
+
+// Search retrieve the furniture matching the query using PostgreSQL full-text
+// search, ranked by relevance. If nothing matches, it falls back to trigram
+// similarity on the furniture names so that typos like "sofia" still find
+// "sofa".
+func (s SearchRepository) Search(query string, filters Filters) ([]SearchResult, Metadata, error) {
+	fullText := fmt.Sprintf(`
+		SELECT
+			COUNT(*) OVER(),
+			%s,
+			ts_rank(f.search_vector, q) AS rank,
+			ts_headline('english', f.description, q, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2')
+		FROM
+		    furniture f
+		JOIN category c ON f.category_id = c.category_id,
+		    websearch_to_tsquery('english', $1) q
+		WHERE f.search_vector @@ q
+		ORDER BY rank DESC, f.furniture_id ASC
+		LIMIT $2 OFFSET $3`, furnitureColumns)
+
+	results, metadata, err := s.search(fullText, query, filters)
+	if err != nil || len(results) > 0 {
+		return results, metadata, err
+	}
+
+	// An empty page past the first one does not mean that nothing matched,
+	// only fall back when the full-text search has no match at all.
+	if filters.Page > 1 {
+		matched, err := s.hasFullTextMatch(query)
+		if err != nil || matched {
+			return results, metadata, err
+		}
+	}
+
+	similarity := fmt.Sprintf(`
+		SELECT
+			COUNT(*) OVER(),
+			%s,
+			word_similarity($1, f.name) AS rank,
+			ts_headline('english', f.description, plainto_tsquery('english', $1),
+				'StartSel=<mark>, StopSel=</mark>, MaxFragments=2')
+		FROM
+		    furniture f
+		JOIN category c ON f.category_id = c.category_id
+		WHERE word_similarity($1, f.name) >= %v
+		ORDER BY rank DESC, f.furniture_id ASC
+		LIMIT $2 OFFSET $3`, furnitureColumns, minSimilarity)
+
+	return s.search(similarity, query, filters)
+}
+
+// search runs one of the search queries and scans its results.
+func (s SearchRepository) search(statement, query string, filters Filters) ([]SearchResult, Metadata, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := s.DB.QueryContext(ctx, statement, query, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	results := []SearchResult{}
+
+	for rows.Next() {
+		var result SearchResult
+		dest := append([]any{&totalRecords}, result.Furniture.scanDest()...)
+		err = rows.Scan(append(dest, &result.Rank, &result.Snippet)...)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		results = append(results, result)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return results, metadata, nil
+}
+
+// hasFullTextMatch reports whether any furniture matches the query using
+// full-text search.
+func (s SearchRepository) hasFullTextMatch(query string) (bool, error) {
+	statement := `
+		SELECT EXISTS (
+			SELECT 1 FROM furniture
+			WHERE search_vector @@ websearch_to_tsquery('english', $1)
+		)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var matched bool
+	err := s.DB.QueryRowContext(ctx, statement, query).Scan(&matched)
+	return matched, err
+}
+
+// RecordQuery records that the normalized query was searched for and how
+// many results it returned.
+func (s SearchRepository) RecordQuery(query string, resultCount int) error {
+	statement := `
+		INSERT INTO search_query(query, last_result_count)
+		VALUES ($1, $2)
+		ON CONFLICT (query) DO UPDATE
+		SET search_count = search_query.search_count + 1,
+			last_result_count = EXCLUDED.last_result_count,
+			last_searched_at = NOW()`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := s.DB.ExecContext(ctx, statement, NormalizeSearchQuery(query), resultCount)
+	return err
+}
+
+// Suggest retrieve the product names, categories and popular past queries
+// that match the partially typed query. The lookups are kept to indexed
+// prefix matches and a short timeout, as they run on every keystroke.
+func (s SearchRepository) Suggest(query string) (Suggestions, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	suggestions := Suggestions{
+		Products:   []ProductSuggestion{},
+		Categories: []CategorySuggestion{},
+		Queries:    []string{},
+	}
+
+	// Match the start of any word of the name, e.g. "so" matches "Modern sofa".
+	prefix := likeEscaper.Replace(query)
+
+	statement := `
+		SELECT furniture_id, name
+		FROM furniture
+		WHERE name ILIKE $1 || '%' OR name ILIKE '% ' || $1 || '%'
+		ORDER BY name ILIKE $1 || '%' DESC, name
+		LIMIT $2`
+
+	rows, err := s.DB.QueryContext(ctx, statement, prefix, suggestionLimit)
+	if err != nil {
+		return Suggestions{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var product ProductSuggestion
+		if err = rows.Scan(&product.FurnitureID, &product.Name); err != nil {
+			return Suggestions{}, err
+		}
+		suggestions.Products = append(suggestions.Products, product)
+	}
+	if err = rows.Err(); err != nil {
+		return Suggestions{}, err
+	}
+
+	statement = `
+		SELECT category_id, name, slug
+		FROM category
+		WHERE name ILIKE $1 || '%' OR name ILIKE '% ' || $1 || '%'
+		ORDER BY name ILIKE $1 || '%' DESC, name
+		LIMIT $2`
+
+	rows, err = s.DB.QueryContext(ctx, statement, prefix, suggestionLimit)
+	if err != nil {
+		return Suggestions{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var category CategorySuggestion
+		if err = rows.Scan(&category.CategoryID, &category.Name, &category.Slug); err != nil {
+			return Suggestions{}, err
+		}
+		suggestions.Categories = append(suggestions.Categories, category)
+	}
+	if err = rows.Err(); err != nil {
+		return Suggestions{}, err
+	}
+
+	// Only suggest past queries that returned results.
+	statement = `
+		SELECT query
+		FROM search_query
+		WHERE query LIKE $1 || '%' AND last_result_count > 0
+		ORDER BY search_count DESC, query
+		LIMIT $2`
+
+	rows, err = s.DB.QueryContext(ctx, statement, likeEscaper.Replace(NormalizeSearchQuery(query)), suggestionLimit)
+	if err != nil {
+		return Suggestions{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var pastQuery string
+		if err = rows.Scan(&pastQuery); err != nil {
+			return Suggestions{}, err
+		}
+		suggestions.Queries = append(suggestions.Queries, pastQuery)
+	}
+	if err = rows.Err(); err != nil {
+		return Suggestions{}, err
+	}
+
+	return suggestions, nil
+}
+
+// GetAllQueries retrieve the recorded search queries alongside the pagination
+// metadata. When zeroResults is true, only the queries whose last search
+// returned nothing are included.
+func (s SearchRepository) GetAllQueries(zeroResults bool, filters Filters) ([]SearchQuery, Metadata, error) {
+	statement := fmt.Sprintf(`
+		SELECT COUNT(*) OVER(), query, search_count, last_result_count, last_searched_at
+		FROM search_query
+		WHERE (NOT $1 OR last_result_count = 0)
+		ORDER BY %s %s, query ASC
+		LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := s.DB.QueryContext(ctx, statement, zeroResults, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	queries := []SearchQuery{}
+
+	for rows.Next() {
+		var query SearchQuery
+		err = rows.Scan(
+			&totalRecords,
+			&query.Query,
+			&query.SearchCount,
+			&query.LastResultCount,
+			&query.LastSearchedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		queries = append(queries, query)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return queries, metadata, nil
+}
@@ -0,0 +1,376 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/hayohtee/fumode/internal/carrier"
+	"time"
+)
+
+// ShipmentRepository is a type which wraps around a sql.DB connection pool
+// and provide methods for shipping the orders and tracking their shipments
+// to and from the database.
+type ShipmentRepository struct {
+	DB *sql.DB
+}
+
+// ShipItems records that some items of an order were handed over to the
+// carrier in a parcel with the label, and moves the order to partially
+// shipped or shipped. The first parcel is shipped as the shipment created
+// at checkout, the others as new shipments to the same address. The
+// creation of the label is the first event of the shipment. The stock of
+// the backordered quantities, which was never reserved, is taken from the
+// warehouse of the order with a sale in the inventory ledger. It returns
+// ErrInvalidOrderStatus if the order is not paid or partially shipped,
+// ErrInvalidShipmentItems if an item is not in the order or its quantity is
+// beyond what is left to ship, and ErrInsufficientStock if the warehouse
+// does not hold the backordered stock yet.
+func (s ShipmentRepository) ShipItems(orderID, actorID int64, items []ShipmentItem, label carrier.Label) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT o.status, s.shipment_id, s.status, s.warehouse_id
+		FROM orders o
+		JOIN shipment s ON o.shipment_id = s.shipment_id
+		WHERE o.order_id = $1
+		FOR UPDATE OF o`
+
+	var status, primaryStatus string
+	var primaryID int64
+	var warehouseID *int64
+	err = tx.QueryRowContext(ctx, query, orderID).Scan(&status, &primaryID, &primaryStatus, &warehouseID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	if status != OrderStatusPaid && status != OrderStatusPartiallyShipped && status != OrderStatusPartiallyDelivered {
+		return ErrInvalidOrderStatus
+	}
+
+	remaining, err := getUnshippedQuantities(ctx, tx, orderID)
+	if err != nil {
+		return err
+	}
+
+	if len(items) == 0 {
+		return fmt.Errorf("%w: no item to ship", ErrInvalidShipmentItems)
+	}
+	for _, item := range items {
+		left, ok := remaining[item.OrderItemID]
+		switch {
+		case !ok:
+			return fmt.Errorf("%w: item %d is not in the order", ErrInvalidShipmentItems, item.OrderItemID)
+		case item.Quantity <= 0 || item.Quantity > left:
+			return fmt.Errorf("%w: only %d of item %d left to ship", ErrInvalidShipmentItems, left, item.OrderItemID)
+		}
+		remaining[item.OrderItemID] -= item.Quantity
+	}
+
+	if err = shipBackorders(ctx, tx, orderID, actorID, warehouseID, items); err != nil {
+		return err
+	}
+
+	shipmentID := primaryID
+	if primaryStatus == ShipmentStatusPending {
+		query = `
+			UPDATE shipment
+			SET carrier = $1, tracking_number = $2, shipment_date = $3, status = $4
+			WHERE shipment_id = $5`
+
+		args := []any{label.Carrier, label.TrackingNumber, label.CreatedAt, ShipmentStatusShipped, primaryID}
+		if _, err = tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	} else {
+		query = `
+			INSERT INTO shipment(address, city, state, country, zip_code, user_id, warehouse_id, shipping_method_id,
+				shipping_method, order_id, carrier, tracking_number, shipment_date, status)
+			SELECT address, city, state, country, zip_code, user_id, warehouse_id, shipping_method_id,
+				shipping_method, order_id, $1, $2, $3, $4
+			FROM shipment
+			WHERE shipment_id = $5
+			RETURNING shipment_id`
+
+		args := []any{label.Carrier, label.TrackingNumber, label.CreatedAt, ShipmentStatusShipped, primaryID}
+		if err = tx.QueryRowContext(ctx, query, args...).Scan(&shipmentID); err != nil {
+			return err
+		}
+	}
+
+	query = `
+		INSERT INTO shipment_item(shipment_id, order_item_id, quantity)
+		VALUES ($1, $2, $3)`
+
+	for _, item := range items {
+		if _, err = tx.ExecContext(ctx, query, shipmentID, item.OrderItemID, item.Quantity); err != nil {
+			return err
+		}
+	}
+
+	event := carrier.Event{
+		Status:      carrier.StatusLabelCreated,
+		Description: "Shipping label created",
+		OccurredAt:  label.CreatedAt,
+	}
+	if err = insertShipmentEvents(ctx, tx, shipmentID, []carrier.Event{event}); err != nil {
+		return err
+	}
+
+	if err = updateFulfilmentStatus(ctx, tx, orderID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// shipBackorders records the sale of the backordered quantities of the
+// items shipped in a parcel, from the warehouse of the order. The shipped
+// items are taken from the reserved stock before the backordered one, as
+// the reserved stock was committed when the order was paid. Bundles are
+// shipped as their components.
+func shipBackorders(ctx context.Context, q queryer, orderID, actorID int64, warehouseID *int64, items []ShipmentItem) error {
+	orderItems, err := getOrderItems(ctx, q, []int64{orderID})
+	if err != nil {
+		return err
+	}
+
+	shipped := make(map[int64]int, len(items))
+	for _, item := range items {
+		shipped[item.OrderItemID] += item.Quantity
+	}
+
+	var backordered []stockLine
+	for _, item := range orderItems[orderID] {
+		reserved := item.Quantity - item.BackorderedQuantity
+		before := max(item.ShippedQuantity-reserved, 0)
+		after := max(item.ShippedQuantity+shipped[item.OrderItemID]-reserved, 0)
+		if after > before {
+			backordered = append(backordered, stockLine{item.FurnitureID, item.VariantID, item.Name, after - before})
+		}
+	}
+
+	if len(backordered) == 0 {
+		return nil
+	}
+
+	if warehouseID == nil {
+		return fmt.Errorf("%w: the order has no warehouse to ship the backordered items from", ErrInvalidWarehouse)
+	}
+
+	lines, err := expandBundles(ctx, q, backordered)
+	if err != nil {
+		return err
+	}
+
+	for _, expanded := range lines {
+		for _, line := range expanded {
+			movement := InventoryMovement{
+				FurnitureID: line.furnitureID,
+				VariantID:   line.variantID,
+				WarehouseID: *warehouseID,
+				Quantity:    -line.quantity,
+				Reason:      MovementReasonSale,
+				ActorID:     &actorID,
+				Reference:   fmt.Sprintf("order:%d", orderID),
+			}
+
+			err = recordMovement(ctx, q, &movement)
+			if err != nil {
+				switch {
+				case errors.Is(err, ErrInsufficientStock):
+					return fmt.Errorf("%w: not enough stock of %s to ship its backorder", ErrInsufficientStock, line.name)
+				default:
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// RecordEvents records the events reported by the carrier for a shipment
+// of an order that were not recorded yet. The shipment is delivered once
+// the carrier reports the delivery, and the order is moved to partially
+// delivered or delivered with it.
+func (s ShipmentRepository) RecordEvents(orderID, shipmentID int64, events []carrier.Event) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = insertShipmentEvents(ctx, tx, shipmentID, events); err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		if event.Status != carrier.StatusDelivered {
+			continue
+		}
+
+		query := `
+			UPDATE shipment SET status = $1
+			WHERE shipment_id = $2 AND order_id = $3 AND status = $4`
+
+		if _, err = tx.ExecContext(ctx, query, ShipmentStatusDelivered, shipmentID, orderID, ShipmentStatusShipped); err != nil {
+			return err
+		}
+
+		if err = updateFulfilmentStatus(ctx, tx, orderID); err != nil {
+			return err
+		}
+		break
+	}
+
+	return tx.Commit()
+}
+
+// GetEvents retrieve the timelines of the shipments of an order from the
+// database, from the oldest event, grouped by shipment id.
+func (s ShipmentRepository) GetEvents(orderID int64) (map[int64][]ShipmentEvent, error) {
+	query := `
+		SELECT e.shipment_id, e.event_id, e.status, e.description, e.location, e.occurred_at
+		FROM shipment_event e
+		JOIN shipment s ON e.shipment_id = s.shipment_id
+		WHERE s.order_id = $1
+		ORDER BY e.occurred_at, e.event_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := s.DB.QueryContext(ctx, query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make(map[int64][]ShipmentEvent)
+	for rows.Next() {
+		var shipmentID int64
+		var event ShipmentEvent
+		err = rows.Scan(&shipmentID, &event.EventID, &event.Status, &event.Description, &event.Location, &event.OccurredAt)
+		if err != nil {
+			return nil, err
+		}
+		events[shipmentID] = append(events[shipmentID], event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// insertShipmentEvents inserts the events of a shipment, skipping those
+// already recorded.
+func insertShipmentEvents(ctx context.Context, q queryer, shipmentID int64, events []carrier.Event) error {
+	query := `
+		INSERT INTO shipment_event(shipment_id, status, description, location, occurred_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (shipment_id, status, occurred_at) DO NOTHING`
+
+	for _, event := range events {
+		args := []any{shipmentID, event.Status, event.Description, event.Location, event.OccurredAt}
+		if _, err := q.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getUnshippedQuantities returns the quantity of every item of an order
+// that is left to ship, by order item id.
+func getUnshippedQuantities(ctx context.Context, q queryer, orderID int64) (map[int64]int, error) {
+	query := `
+		SELECT oi.order_item_id, oi.quantity - COALESCE(SUM(si.quantity), 0)
+		FROM order_item oi
+		LEFT JOIN shipment_item si ON oi.order_item_id = si.order_item_id
+		WHERE oi.order_id = $1
+		GROUP BY oi.order_item_id`
+
+	rows, err := q.QueryContext(ctx, query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	remaining := make(map[int64]int)
+	for rows.Next() {
+		var orderItemID int64
+		var quantity int
+		if err = rows.Scan(&orderItemID, &quantity); err != nil {
+			return nil, err
+		}
+		remaining[orderItemID] = quantity
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return remaining, nil
+}
+
+// updateFulfilmentStatus moves a shipped order to the status matching the
+// quantities of its items shipped and delivered so far, unless it was
+// returned or cancelled. The order is locked first, so that concurrent
+// deliveries of its shipments see each other.
+func updateFulfilmentStatus(ctx context.Context, q queryer, orderID int64) error {
+	query := `SELECT 1 FROM orders WHERE order_id = $1 FOR UPDATE`
+	if _, err := q.ExecContext(ctx, query, orderID); err != nil {
+		return err
+	}
+
+	query = `
+		SELECT
+			(SELECT SUM(quantity) FROM order_item WHERE order_id = o.order_id),
+			COALESCE(SUM(si.quantity), 0),
+			COALESCE(SUM(si.quantity) FILTER (WHERE s.status = 'delivered'), 0)
+		FROM orders o
+		LEFT JOIN shipment s ON s.order_id = o.order_id
+		LEFT JOIN shipment_item si ON s.shipment_id = si.shipment_id
+		WHERE o.order_id = $1
+		GROUP BY o.order_id`
+
+	var ordered, shipped, delivered int
+	if err := q.QueryRowContext(ctx, query, orderID).Scan(&ordered, &shipped, &delivered); err != nil {
+		return err
+	}
+
+	var status string
+	switch {
+	case delivered >= ordered:
+		status = OrderStatusDelivered
+	case delivered > 0:
+		status = OrderStatusPartiallyDelivered
+	case shipped >= ordered:
+		status = OrderStatusShipped
+	case shipped > 0:
+		status = OrderStatusPartiallyShipped
+	default:
+		status = OrderStatusPaid
+	}
+
+	query = `
+		UPDATE orders SET status = $1, version = version + 1
+		WHERE order_id = $2 AND status NOT IN ($1, 'returned', 'cancelled')`
+
+	_, err := q.ExecContext(ctx, query, status, orderID)
+	return err
+}
@@ -0,0 +1,493 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/hayohtee/fumode/internal/money"
+	"github.com/hayohtee/fumode/internal/shipping"
+	"strings"
+	"time"
+)
+
+// ShippingRepository is a type which wraps around a sql.DB connection pool
+// and provide methods for managing the shipping zones and methods, and for
+// quoting the shipping of a cart, to and from the database.
+type ShippingRepository struct {
+	DB *sql.DB
+}
+
+// InsertZone inserts a shipping zone record, with its regions, to the
+// database. It returns ErrDuplicateCode if the code is taken and
+// ErrDuplicateRegion if a region already belongs to a zone.
+func (s ShippingRepository) InsertZone(zone *shipping.Zone) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO shipping_zone(code, name)
+		VALUES ($1, $2)
+		RETURNING zone_id, created_at, version`
+
+	err = tx.QueryRowContext(ctx, query, zone.Code, zone.Name).Scan(&zone.ZoneID, &zone.CreatedAt, &zone.Version)
+	if err != nil {
+		return shippingWriteError(err)
+	}
+
+	if err = setZoneRegions(ctx, tx, *zone); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetZone retrieve a specific shipping zone, with its regions, from the
+// database given the id.
+func (s ShippingRepository) GetZone(id int64) (shipping.Zone, error) {
+	zones, err := s.getZones(id)
+	if err != nil {
+		return shipping.Zone{}, err
+	}
+	if len(zones) == 0 {
+		return shipping.Zone{}, ErrRecordNotFound
+	}
+	return zones[0], nil
+}
+
+// GetZones retrieve every shipping zone, with its regions, from the
+// database.
+func (s ShippingRepository) GetZones() ([]shipping.Zone, error) {
+	return s.getZones(0)
+}
+
+// getZones retrieve the shipping zone with the id, or every zone when id
+// is zero.
+func (s ShippingRepository) getZones(id int64) ([]shipping.Zone, error) {
+	query := `
+		SELECT z.zone_id, z.code, z.name, z.created_at, z.version,
+			COALESCE(ARRAY_AGG(r.country ORDER BY r.country, r.state) FILTER (WHERE r.zone_id IS NOT NULL), '{}'),
+			COALESCE(ARRAY_AGG(r.state ORDER BY r.country, r.state) FILTER (WHERE r.zone_id IS NOT NULL), '{}')
+		FROM shipping_zone z
+		LEFT JOIN shipping_zone_region r ON r.zone_id = z.zone_id
+		WHERE $1::BIGINT = 0 OR z.zone_id = $1
+		GROUP BY z.zone_id
+		ORDER BY z.zone_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := s.DB.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	zones := []shipping.Zone{}
+	for rows.Next() {
+		var zone shipping.Zone
+		var countries, states []string
+		err = rows.Scan(&zone.ZoneID, &zone.Code, &zone.Name, &zone.CreatedAt, &zone.Version, &countries, &states)
+		if err != nil {
+			return nil, err
+		}
+
+		zone.Regions = make([]shipping.Region, len(countries))
+		for i := range countries {
+			zone.Regions[i] = shipping.Region{Country: countries[i], State: states[i]}
+		}
+		zones = append(zones, zone)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return zones, nil
+}
+
+// UpdateZone updates a specific shipping zone record in the database and
+// replaces its regions.
+func (s ShippingRepository) UpdateZone(zone *shipping.Zone) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE shipping_zone
+		SET code = $1, name = $2, version = version + 1
+		WHERE zone_id = $3 AND version = $4
+		RETURNING version`
+
+	err = tx.QueryRowContext(ctx, query, zone.Code, zone.Name, zone.ZoneID, zone.Version).Scan(&zone.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return shippingWriteError(err)
+		}
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM shipping_zone_region WHERE zone_id = $1`, zone.ZoneID); err != nil {
+		return err
+	}
+
+	if err = setZoneRegions(ctx, tx, *zone); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteZone removes a specific shipping zone from the database, with the
+// rates of every method in it.
+func (s ShippingRepository) DeleteZone(id int64) error {
+	return s.delete(`DELETE FROM shipping_zone WHERE zone_id = $1`, id)
+}
+
+// InsertMethod inserts a shipping method record, with its rate tables, to
+// the database. It returns ErrDuplicateCode if the code is taken and
+// ErrInvalidZone if a rate references a zone that does not exist.
+func (s ShippingRepository) InsertMethod(method *shipping.Method) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO shipping_method(code, name, kind, volumetric_divisor, free_shipping_threshold, active)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING method_id, created_at, version`
+
+	args := []any{
+		method.Code,
+		method.Name,
+		method.Kind,
+		method.VolumetricDivisor,
+		method.FreeShippingThreshold,
+		method.Active,
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&method.MethodID, &method.CreatedAt, &method.Version)
+	if err != nil {
+		return shippingWriteError(err)
+	}
+
+	if err = setMethodRates(ctx, tx, *method); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetMethod retrieve a specific shipping method, with its rate tables,
+// from the database given the id.
+func (s ShippingRepository) GetMethod(id int64) (shipping.Method, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	methods, err := getShippingMethods(ctx, s.DB, id, false)
+	if err != nil {
+		return shipping.Method{}, err
+	}
+	if len(methods) == 0 {
+		return shipping.Method{}, ErrRecordNotFound
+	}
+	return methods[0], nil
+}
+
+// GetMethods retrieve every shipping method, with its rate tables, from
+// the database.
+func (s ShippingRepository) GetMethods() ([]shipping.Method, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return getShippingMethods(ctx, s.DB, 0, false)
+}
+
+// UpdateMethod updates a specific shipping method record in the database
+// and replaces its rate tables.
+func (s ShippingRepository) UpdateMethod(method *shipping.Method) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE shipping_method
+		SET code = $1, name = $2, kind = $3, volumetric_divisor = $4, free_shipping_threshold = $5, active = $6,
+			version = version + 1
+		WHERE method_id = $7 AND version = $8
+		RETURNING version`
+
+	args := []any{
+		method.Code,
+		method.Name,
+		method.Kind,
+		method.VolumetricDivisor,
+		method.FreeShippingThreshold,
+		method.Active,
+		method.MethodID,
+		method.Version,
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&method.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return shippingWriteError(err)
+		}
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM shipping_rate WHERE method_id = $1`, method.MethodID); err != nil {
+		return err
+	}
+
+	if err = setMethodRates(ctx, tx, *method); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteMethod removes a specific shipping method from the database. The
+// orders shipped with it keep its name and cost.
+func (s ShippingRepository) DeleteMethod(id int64) error {
+	return s.delete(`DELETE FROM shipping_method WHERE method_id = $1`, id)
+}
+
+// delete runs a query deleting the record with the id, and returns
+// ErrRecordNotFound when there was none.
+func (s ShippingRepository) delete(query string, id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := s.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// Quote returns the shipping options for the cart of a user shipped to the
+// region, from the cheapest.
+func (s ShippingRepository) Quote(userID int64, region shipping.Region) ([]shipping.Option, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cart, err := getCart(ctx, s.DB, userID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cart.Items) == 0 {
+		return nil, ErrEmptyCart
+	}
+
+	methods, err := getShippingMethods(ctx, s.DB, 0, true)
+	if err != nil {
+		return nil, err
+	}
+
+	zoneID, err := findShippingZone(ctx, s.DB, region)
+	if err != nil {
+		return nil, err
+	}
+	return quoteShipping(ctx, s.DB, methods, userID, cart.Total, zoneID)
+}
+
+// findShippingZone returns the id of the shipping zone of the region, the
+// zone of its state taking precedence over the zone of its country. It
+// returns nil when the region is in no zone.
+func findShippingZone(ctx context.Context, q queryer, region shipping.Region) (*int64, error) {
+	query := `
+		SELECT zone_id
+		FROM shipping_zone_region
+		WHERE UPPER(TRIM(country)) = UPPER(TRIM($1))
+		AND (state = '' OR UPPER(TRIM(state)) = UPPER(TRIM($2)))
+		ORDER BY state = ''
+		LIMIT 1`
+
+	var zoneID *int64
+	err := q.QueryRowContext(ctx, query, region.Country, region.State).Scan(&zoneID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	return zoneID, nil
+}
+
+// quoteShipping returns the options of the methods for the cart of a user
+// shipped to the zone, the cart being discounted to subtotal.
+func quoteShipping(ctx context.Context, q queryer, methods []shipping.Method, userID int64, subtotal money.Money, zoneID *int64) ([]shipping.Option, error) {
+	query := `
+		SELECT ct.quantity, f.width_cm, f.depth_cm, f.height_cm, f.weight_kg
+		FROM cart ct
+		JOIN furniture f ON ct.furniture_id = f.furniture_id
+		WHERE ct.user_id = $1`
+
+	rows, err := q.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []shipping.Item
+	for rows.Next() {
+		var item shipping.Item
+		err = rows.Scan(&item.Quantity, &item.WidthCm, &item.DepthCm, &item.HeightCm, &item.WeightKg)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return shipping.Quote(methods, zoneID, items, subtotal), nil
+}
+
+// getShippingMethods returns the shipping method with the id, or every
+// method when id is zero, with their rate tables. Only the active methods
+// are returned when activeOnly is set.
+func getShippingMethods(ctx context.Context, q queryer, id int64, activeOnly bool) ([]shipping.Method, error) {
+	query := `
+		SELECT method_id, code, name, kind, volumetric_divisor, free_shipping_threshold, active, created_at, version
+		FROM shipping_method
+		WHERE ($1::BIGINT = 0 OR method_id = $1) AND (active OR NOT $2::BOOLEAN)
+		ORDER BY method_id`
+
+	rows, err := q.QueryContext(ctx, query, id, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	methods := []shipping.Method{}
+	indexes := make(map[int64]int)
+	for rows.Next() {
+		var method shipping.Method
+		err = rows.Scan(
+			&method.MethodID,
+			&method.Code,
+			&method.Name,
+			&method.Kind,
+			&method.VolumetricDivisor,
+			&method.FreeShippingThreshold,
+			&method.Active,
+			&method.CreatedAt,
+			&method.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		method.Rates = []shipping.Rate{}
+		indexes[method.MethodID] = len(methods)
+		methods = append(methods, method)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	query = `
+		SELECT r.method_id, r.zone_id, r.max_weight_kg, r.price
+		FROM shipping_rate r
+		JOIN shipping_method m ON r.method_id = m.method_id
+		WHERE ($1::BIGINT = 0 OR m.method_id = $1) AND (m.active OR NOT $2::BOOLEAN)
+		ORDER BY r.method_id, r.zone_id, r.max_weight_kg NULLS LAST`
+
+	rows, err = q.QueryContext(ctx, query, id, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var methodID int64
+		var rate shipping.Rate
+		if err = rows.Scan(&methodID, &rate.ZoneID, &rate.MaxWeightKg, &rate.Price); err != nil {
+			return nil, err
+		}
+
+		// A method created after the first query is skipped.
+		if i, ok := indexes[methodID]; ok {
+			methods[i].Rates = append(methods[i].Rates, rate)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return methods, nil
+}
+
+// setZoneRegions inserts the regions of the zone.
+func setZoneRegions(ctx context.Context, q queryer, zone shipping.Zone) error {
+	query := `
+		INSERT INTO shipping_zone_region(zone_id, country, state)
+		VALUES ($1, TRIM($2), TRIM($3))`
+
+	for _, region := range zone.Regions {
+		if _, err := q.ExecContext(ctx, query, zone.ZoneID, region.Country, region.State); err != nil {
+			return shippingWriteError(err)
+		}
+	}
+	return nil
+}
+
+// setMethodRates inserts the rate tables of the method.
+func setMethodRates(ctx context.Context, q queryer, method shipping.Method) error {
+	query := `
+		INSERT INTO shipping_rate(method_id, zone_id, max_weight_kg, price)
+		VALUES ($1, $2, $3, $4)`
+
+	for _, rate := range method.Rates {
+		if _, err := q.ExecContext(ctx, query, method.MethodID, rate.ZoneID, rate.MaxWeightKg, rate.Price); err != nil {
+			return shippingWriteError(err)
+		}
+	}
+	return nil
+}
+
+// shippingWriteError maps the constraint violations of a shipping zone or
+// method write to the repository errors.
+func shippingWriteError(err error) error {
+	switch {
+	case strings.Contains(err.Error(), `duplicate key value violates unique constraint "shipping_zone_code_key"`),
+		strings.Contains(err.Error(), `duplicate key value violates unique constraint "shipping_method_code_key"`):
+		return ErrDuplicateCode
+	case strings.Contains(err.Error(), `duplicate key value violates unique constraint "shipping_zone_region_idx"`):
+		return ErrDuplicateRegion
+	case strings.Contains(err.Error(), `violates foreign key constraint "shipping_rate_zone_id_fkey"`):
+		return ErrInvalidZone
+	default:
+		return err
+	}
+}
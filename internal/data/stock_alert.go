@@ -0,0 +1,37 @@
+package data
+
+import "time"
+
+// LowStockItem is a struct that holds a furniture, or a specific variant
+// of it, whose stock is at or below the reorder threshold of the furniture.
+type LowStockItem struct {
+	FurnitureID      int64  `json:"furniture_id"`
+	VariantID        *int64 `json:"variant_id"`
+	Name             string `json:"name"`
+	Stock            int    `json:"stock"`
+	AvailableStock   int    `json:"available_stock"`
+	ReorderThreshold int    `json:"reorder_threshold"`
+}
+
+// StockNotification is a struct that holds the subscription of a customer
+// to be notified when a furniture, or a specific variant of it, is back
+// in stock. NotifiedAt is set once the notification has been sent.
+type StockNotification struct {
+	NotificationID int64      `json:"notification_id"`
+	UserID         int64      `json:"user_id"`
+	FurnitureID    int64      `json:"furniture_id"`
+	VariantID      *int64     `json:"variant_id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	NotifiedAt     *time.Time `json:"notified_at"`
+}
+
+// RestockNotice is a struct that holds the data for emailing a customer
+// that an item they subscribed to is back in stock.
+type RestockNotice struct {
+	NotificationID int64
+	Email          string
+	UserName       string
+	FurnitureID    int64
+	VariantID      *int64
+	Name           string
+}
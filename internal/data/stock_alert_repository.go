@@ -0,0 +1,201 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// StockAlertRepository is a type which wraps around a sql.DB connection pool
+// and provide methods for the low stock reports and the back in stock
+// notifications.
+type StockAlertRepository struct {
+	DB *sql.DB
+}
+
+// GetLowStock retrieve the furniture without variants, and the variants,
+// whose stock is at or below the reorder threshold of the furniture.
+// Discontinued furniture and bundles, which are made of the stock of
+// their components, are left out. The items out of stock are listed
+// first.
+func (s StockAlertRepository) GetLowStock() ([]LowStockItem, error) {
+	query := fmt.Sprintf(`
+		SELECT furniture_id, variant_id, name, stock, available_stock, reorder_threshold
+		FROM (
+			SELECT f.furniture_id, NULL::BIGINT AS variant_id, f.name, f.stock,
+				%s AS available_stock, f.reorder_threshold
+			FROM furniture f
+			WHERE NOT EXISTS (SELECT 1 FROM furniture_variant v WHERE v.furniture_id = f.furniture_id)
+			AND f.availability <> 'discontinued' AND f.product_type = 'standard'
+			UNION ALL
+			SELECT v.furniture_id, v.variant_id, f.name || ' (' || v.sku || ')', v.stock,
+				%s, f.reorder_threshold
+			FROM furniture_variant v
+			JOIN furniture f ON v.furniture_id = f.furniture_id
+			WHERE f.availability <> 'discontinued'
+		) item
+		WHERE stock <= reorder_threshold
+		ORDER BY stock, furniture_id, variant_id NULLS FIRST`, furnitureAvailableStock, variantAvailableStock)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := s.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []LowStockItem{}
+	for rows.Next() {
+		var item LowStockItem
+		err = rows.Scan(
+			&item.FurnitureID,
+			&item.VariantID,
+			&item.Name,
+			&item.Stock,
+			&item.AvailableStock,
+			&item.ReorderThreshold,
+		)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// SetReorderThreshold sets the reorder threshold of a specific furniture.
+func (s StockAlertRepository) SetReorderThreshold(furnitureID int64, threshold int) error {
+	query := `
+		UPDATE furniture
+		SET reorder_threshold = $1, version = version + 1
+		WHERE furniture_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := s.DB.ExecContext(ctx, query, threshold, furnitureID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// Subscribe subscribes a user to be notified when a furniture, or a
+// specific variant of it, is back in stock. Subscribing again while the
+// previous subscription is pending returns that subscription.
+func (s StockAlertRepository) Subscribe(notification *StockNotification) error {
+	query := `
+		INSERT INTO stock_notification(user_id, furniture_id, variant_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, furniture_id, COALESCE(variant_id, 0)) WHERE notified_at IS NULL
+		DO UPDATE SET user_id = EXCLUDED.user_id
+		RETURNING notification_id, created_at`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []any{notification.UserID, notification.FurnitureID, notification.VariantID}
+	return s.DB.QueryRowContext(ctx, query, args...).Scan(&notification.NotificationID, &notification.CreatedAt)
+}
+
+// Unsubscribe deletes a pending subscription of a user.
+func (s StockAlertRepository) Unsubscribe(userID, notificationID int64) error {
+	query := `
+		DELETE FROM stock_notification
+		WHERE notification_id = $1 AND user_id = $2 AND notified_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := s.DB.ExecContext(ctx, query, notificationID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetRestocked retrieve the pending subscriptions whose item is available
+// again, with what is needed to email the subscribers.
+func (s StockAlertRepository) GetRestocked() ([]RestockNotice, error) {
+	query := fmt.Sprintf(`
+		SELECT n.notification_id, u.email, u.name, n.furniture_id, n.variant_id,
+			f.name || COALESCE(' (' || v.sku || ')', '')
+		FROM stock_notification n
+		JOIN users u ON n.user_id = u.user_id
+		JOIN furniture f ON n.furniture_id = f.furniture_id
+		LEFT JOIN furniture_variant v ON n.variant_id = v.variant_id
+		WHERE n.notified_at IS NULL
+		AND CASE WHEN n.variant_id IS NULL THEN %s ELSE %s END > 0
+		ORDER BY n.notification_id`, furnitureAvailableStock, variantAvailableStock)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := s.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notices := []RestockNotice{}
+	for rows.Next() {
+		var notice RestockNotice
+		err = rows.Scan(
+			&notice.NotificationID,
+			&notice.Email,
+			&notice.UserName,
+			&notice.FurnitureID,
+			&notice.VariantID,
+			&notice.Name,
+		)
+		if err != nil {
+			return nil, err
+		}
+		notices = append(notices, notice)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return notices, nil
+}
+
+// MarkNotified records that the notification of a specific subscription
+// has been sent.
+func (s StockAlertRepository) MarkNotified(notificationID int64) error {
+	query := `
+		UPDATE stock_notification
+		SET notified_at = NOW()
+		WHERE notification_id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := s.DB.ExecContext(ctx, query, notificationID)
+	return err
+}
@@ -0,0 +1,196 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/hayohtee/fumode/internal/tax"
+	"strings"
+	"time"
+)
+
+// taxRateColumns is the list of columns selected for a tax rate, in the
+// order expected by taxRateScanDest.
+const taxRateColumns = `rate_id, country, state, tax_class, percent, name, created_at, version`
+
+// taxRateScanDest returns the destinations for scanning the tax rate
+// columns.
+func taxRateScanDest(rate *tax.Rate) []any {
+	return []any{
+		&rate.RateID,
+		&rate.Country,
+		&rate.State,
+		&rate.TaxClass,
+		&rate.Percent,
+		&rate.Name,
+		&rate.CreatedAt,
+		&rate.Version,
+	}
+}
+
+// TaxRateRepository is a type which wraps around a sql.DB connection pool
+// and provide methods for creating and managing the tax rates to and from
+// the database.
+type TaxRateRepository struct {
+	DB *sql.DB
+}
+
+// Insert a tax rate record to the database. It returns ErrDuplicateTaxRate
+// if the tax class already has a rate in the same region.
+func (t TaxRateRepository) Insert(rate *tax.Rate) error {
+	query := `
+		INSERT INTO tax_rate(country, state, tax_class, percent, name)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING rate_id, created_at, version`
+
+	args := []any{rate.Country, rate.State, rate.TaxClass, rate.Percent, rate.Name}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := t.DB.QueryRowContext(ctx, query, args...).Scan(&rate.RateID, &rate.CreatedAt, &rate.Version)
+	if err != nil {
+		return taxRateWriteError(err)
+	}
+	return nil
+}
+
+// GetByID retrieve a specific tax rate from the database given the id.
+func (t TaxRateRepository) GetByID(id int64) (tax.Rate, error) {
+	query := fmt.Sprintf(`SELECT %s FROM tax_rate WHERE rate_id = $1`, taxRateColumns)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var rate tax.Rate
+	err := t.DB.QueryRowContext(ctx, query, id).Scan(taxRateScanDest(&rate)...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return tax.Rate{}, ErrRecordNotFound
+		default:
+			return tax.Rate{}, err
+		}
+	}
+	return rate, nil
+}
+
+// GetAll retrieve the tax rates from the database, only those of a
+// specific country when country is not empty.
+func (t TaxRateRepository) GetAll(country string) ([]tax.Rate, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM tax_rate
+		WHERE $1 = '' OR UPPER(TRIM(country)) = UPPER(TRIM($1))
+		ORDER BY UPPER(country), UPPER(state), tax_class`, taxRateColumns)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return queryTaxRates(ctx, t.DB, query, country)
+}
+
+// Update a specific tax rate record in the database.
+func (t TaxRateRepository) Update(rate *tax.Rate) error {
+	query := `
+		UPDATE tax_rate
+		SET country = $1, state = $2, tax_class = $3, percent = $4, name = $5, version = version + 1
+		WHERE rate_id = $6 AND version = $7
+		RETURNING version`
+
+	args := []any{
+		rate.Country,
+		rate.State,
+		rate.TaxClass,
+		rate.Percent,
+		rate.Name,
+		rate.RateID,
+		rate.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := t.DB.QueryRowContext(ctx, query, args...).Scan(&rate.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return taxRateWriteError(err)
+		}
+	}
+	return nil
+}
+
+// Delete a specific tax rate record from the database. The orders already
+// placed keep the tax they were charged.
+func (t TaxRateRepository) Delete(id int64) error {
+	query := `DELETE FROM tax_rate WHERE rate_id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := t.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// getTaxRates returns the tax rates of the country of the region, for the
+// whole country or for the state of the region.
+func getTaxRates(ctx context.Context, q queryer, region tax.Region) ([]tax.Rate, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM tax_rate
+		WHERE UPPER(TRIM(country)) = UPPER(TRIM($1))
+		AND (state = '' OR UPPER(TRIM(state)) = UPPER(TRIM($2)))`, taxRateColumns)
+
+	return queryTaxRates(ctx, q, query, region.Country, region.State)
+}
+
+// queryTaxRates runs a query selecting the tax rate columns and scans
+// every rate it returns.
+func queryTaxRates(ctx context.Context, q queryer, query string, args ...any) ([]tax.Rate, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := []tax.Rate{}
+	for rows.Next() {
+		var rate tax.Rate
+		if err = rows.Scan(taxRateScanDest(&rate)...); err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// taxRateWriteError maps the constraint violations of a tax rate write to
+// the repository errors.
+func taxRateWriteError(err error) error {
+	switch {
+	case strings.Contains(err.Error(), `duplicate key value violates unique constraint "tax_rate_region_idx"`):
+		return ErrDuplicateTaxRate
+	default:
+		return err
+	}
+}
@@ -112,3 +112,36 @@ func (u UserRepository) GetByEmail(email string) (User, error) {
 	}
 	return user, nil
 }
+
+// GetEmailsByRole retrieve the email addresses of every user with
+// the provided role.
+func (u UserRepository) GetEmailsByRole(role string) ([]string, error) {
+	query := `
+		SELECT email
+		FROM users
+		WHERE role = $1
+		ORDER BY user_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := u.DB.QueryContext(ctx, query, role)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	emails := []string{}
+	for rows.Next() {
+		var email string
+		if err = rows.Scan(&email); err != nil {
+			return nil, err
+		}
+		emails = append(emails, email)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return emails, nil
+}
@@ -0,0 +1,71 @@
+package data
+
+import (
+	"github.com/hayohtee/fumode/internal/money"
+	"github.com/hayohtee/fumode/internal/validator"
+	"slices"
+)
+
+// VariantOptionTypes holds the option types a furniture variant
+// can be described with.
+var VariantOptionTypes = []string{"color", "fabric", "finish", "dimensions"}
+
+// Variant is a struct that holds information about a specific
+// variant of a furniture (e.g. the oak or walnut version of a chair).
+// The price of a variant is either PriceOverride when it is set, or
+// the furniture price plus PriceDelta. AvailableStock is the stock
+// minus the quantities reserved by pending checkouts.
+type Variant struct {
+	VariantID      int64             `json:"variant_id"`
+	FurnitureID    int64             `json:"furniture_id"`
+	SKU            string            `json:"sku"`
+	Options        map[string]string `json:"options"`
+	PriceDelta     *money.Money      `json:"price_delta,omitempty"`
+	PriceOverride  *money.Money      `json:"price_override,omitempty"`
+	Price          money.Money       `json:"price"`
+	Stock          int               `json:"stock"`
+	AvailableStock int               `json:"available_stock"`
+	ImageURLs      []string          `json:"image_urls"`
+	Version        int               `json:"version"`
+}
+
+func ValidateVariant(v *validator.Validator, variant Variant) {
+	v.Check(variant.SKU != "", "sku", "must be provided")
+	v.Check(len(variant.SKU) <= 64, "sku", "must not be more than 64 bytes long")
+
+	v.Check(len(variant.Options) > 0, "options", "must contain at least one option")
+	for optionType, value := range variant.Options {
+		v.Check(validator.PermittedValue(optionType, VariantOptionTypes...), optionType, "invalid option type")
+		v.Check(value != "", optionType, "must not be empty")
+		v.Check(len(value) <= 100, optionType, "must not be more than 100 bytes long")
+	}
+
+	v.Check(variant.PriceDelta == nil || variant.PriceOverride == nil, "price_override", "must not be provided together with price_delta")
+	if variant.PriceDelta != nil {
+		ValidateMoney(v, "price_delta", *variant.PriceDelta)
+	}
+	if variant.PriceOverride != nil {
+		ValidateMoney(v, "price_override", *variant.PriceOverride)
+		v.Check(!variant.PriceOverride.IsNegative(), "price_override", "must not be negative")
+	}
+
+	v.Check(variant.Stock >= 0, "stock", "must not be negative")
+}
+
+// OptionMatrix returns the distinct values of every option type used by
+// the variants, e.g. {"color": ["oak", "walnut"]}.
+func OptionMatrix(variants []Variant) map[string][]string {
+	matrix := make(map[string][]string)
+	for _, variant := range variants {
+		for optionType, value := range variant.Options {
+			if !slices.Contains(matrix[optionType], value) {
+				matrix[optionType] = append(matrix[optionType], value)
+			}
+		}
+	}
+
+	for optionType := range matrix {
+		slices.Sort(matrix[optionType])
+	}
+	return matrix
+}
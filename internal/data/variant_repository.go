@@ -0,0 +1,303 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// variantAvailableStock is the SQL expression for the stock of the variant
+// aliased as v, minus the quantities held by active stock reservations.
+const variantAvailableStock = `(v.stock - COALESCE((
+				SELECT SUM(r.quantity) FROM stock_reservation r
+				WHERE r.variant_id = v.variant_id AND r.status = 'active' AND r.expires_at > NOW()
+			), 0))::INTEGER`
+
+// VariantRepository is a type which wraps around a sql.DB connection pool
+// and provide methods for creating and managing furniture variants to and
+// from the database.
+type VariantRepository struct {
+	DB *sql.DB
+}
+
+// Insert a variant record to the database. The initial stock is recorded
+// as a receipt in the inventory ledger of the warehouse. It returns
+// ErrNegativeVariantPrice if the price delta takes the price of the
+// furniture below zero.
+func (v VariantRepository) Insert(variant *Variant, warehouseID int64) error {
+	options, err := json.Marshal(variant.Options)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO furniture_variant(furniture_id, sku, options, price_delta, price_override, stock, image_urls)
+		VALUES ($1, $2, $3, $4, $5, 0, $6)
+		RETURNING variant_id, version,
+			COALESCE($5, (SELECT price FROM furniture WHERE furniture_id = $1) + COALESCE($4, 0))`
+
+	args := []any{
+		variant.FurnitureID,
+		variant.SKU,
+		string(options),
+		variant.PriceDelta,
+		variant.PriceOverride,
+		variant.ImageURLs,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := v.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&variant.VariantID, &variant.Version, &variant.Price)
+	if err != nil {
+		return variantWriteError(err)
+	}
+
+	if variant.Price.IsNegative() {
+		return ErrNegativeVariantPrice
+	}
+
+	if variant.Stock > 0 {
+		movement := InventoryMovement{
+			FurnitureID: variant.FurnitureID,
+			VariantID:   &variant.VariantID,
+			WarehouseID: warehouseID,
+			Quantity:    variant.Stock,
+			Reason:      MovementReasonReceipt,
+			Note:        "initial stock",
+		}
+		if err = recordMovement(ctx, tx, &movement); err != nil {
+			return err
+		}
+		variant.Version++
+	}
+
+	variant.AvailableStock = variant.Stock
+	return tx.Commit()
+}
+
+// GetByID retrieve a specific variant of a furniture from the database.
+func (v VariantRepository) GetByID(furnitureID, variantID int64) (Variant, error) {
+	query := `
+		SELECT
+			v.variant_id,
+			v.furniture_id,
+			v.sku,
+			v.options,
+			v.price_delta,
+			v.price_override,
+			COALESCE(v.price_override, f.price + COALESCE(v.price_delta, 0)),
+			v.stock,
+			` + variantAvailableStock + `,
+			v.image_urls,
+			v.version
+		FROM furniture_variant v
+		JOIN furniture f ON v.furniture_id = f.furniture_id
+		WHERE v.furniture_id = $1 AND v.variant_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	variant, err := scanVariant(v.DB.QueryRowContext(ctx, query, furnitureID, variantID))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return Variant{}, ErrRecordNotFound
+		default:
+			return Variant{}, err
+		}
+	}
+	return variant, nil
+}
+
+// GetAllForFurniture retrieve every variant of a specific furniture.
+func (v VariantRepository) GetAllForFurniture(furnitureID int64) ([]Variant, error) {
+	query := `
+		SELECT
+			v.variant_id,
+			v.furniture_id,
+			v.sku,
+			v.options,
+			v.price_delta,
+			v.price_override,
+			COALESCE(v.price_override, f.price + COALESCE(v.price_delta, 0)),
+			v.stock,
+			` + variantAvailableStock + `,
+			v.image_urls,
+			v.version
+		FROM furniture_variant v
+		JOIN furniture f ON v.furniture_id = f.furniture_id
+		WHERE v.furniture_id = $1
+		ORDER BY v.variant_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := v.DB.QueryContext(ctx, query, furnitureID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	variants := []Variant{}
+	for rows.Next() {
+		variant, err := scanVariant(rows)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, variant)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return variants, nil
+}
+
+// Update a specific variant record in the database. The stock is not
+// updated, as it only changes through the inventory ledger. It returns
+// ErrNegativeVariantPrice if the price delta takes the price of the
+// furniture below zero.
+func (v VariantRepository) Update(variant *Variant) error {
+	options, err := json.Marshal(variant.Options)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE furniture_variant
+		SET sku = $1, options = $2, price_delta = $3, price_override = $4,
+			image_urls = $5, version = version + 1
+		WHERE variant_id = $6 AND version = $7 AND furniture_id = $8
+		RETURNING version,
+			COALESCE(price_override, (SELECT price FROM furniture WHERE furniture_id = $8) + COALESCE(price_delta, 0))`
+
+	args := []any{
+		variant.SKU,
+		string(options),
+		variant.PriceDelta,
+		variant.PriceOverride,
+		variant.ImageURLs,
+		variant.VariantID,
+		variant.Version,
+		variant.FurnitureID,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := v.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&variant.Version, &variant.Price)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return variantWriteError(err)
+		}
+	}
+
+	if variant.Price.IsNegative() {
+		return ErrNegativeVariantPrice
+	}
+
+	// The price of the variant may have changed, and with it the price
+	// of the bundles discounted on it.
+	if err = refreshBundlePrices(ctx, tx, variant.FurnitureID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete a specific variant of a furniture from the database. It returns
+// ErrVariantInUse if the variant is a component of a bundle.
+func (v VariantRepository) Delete(furnitureID, variantID int64) error {
+	query := `
+		DELETE FROM furniture_variant
+		WHERE furniture_id = $1 AND variant_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := v.DB.ExecContext(ctx, query, furnitureID, variantID)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), `violates foreign key constraint "bundle_component_variant_id_fkey"`):
+			return ErrVariantInUse
+		default:
+			return err
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanVariant scans a variant row, decoding the JSONB options column.
+func scanVariant(row rowScanner) (Variant, error) {
+	var variant Variant
+	var options []byte
+
+	err := row.Scan(
+		&variant.VariantID,
+		&variant.FurnitureID,
+		&variant.SKU,
+		&options,
+		&variant.PriceDelta,
+		&variant.PriceOverride,
+		&variant.Price,
+		&variant.Stock,
+		&variant.AvailableStock,
+		&variant.ImageURLs,
+		&variant.Version,
+	)
+	if err != nil {
+		return Variant{}, err
+	}
+
+	err = json.Unmarshal(options, &variant.Options)
+	if err != nil {
+		return Variant{}, err
+	}
+	return variant, nil
+}
+
+// variantWriteError converts the constraint violations raised when
+// inserting or updating a variant to the matching custom error.
+func variantWriteError(err error) error {
+	switch {
+	case strings.Contains(err.Error(), `duplicate key value violates unique constraint "furniture_variant_sku_key"`):
+		return ErrDuplicateSKU
+	case strings.Contains(err.Error(), `duplicate key value violates unique constraint "furniture_variant_options_idx"`):
+		return ErrDuplicateVariantOptions
+	default:
+		return err
+	}
+}
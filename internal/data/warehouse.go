@@ -0,0 +1,70 @@
+package data
+
+import (
+	"github.com/hayohtee/fumode/internal/validator"
+	"time"
+)
+
+// Warehouse is a struct that holds information about a specific
+// warehouse the furniture is stocked in and shipped from.
+type Warehouse struct {
+	WarehouseID int64     `json:"warehouse_id"`
+	Name        string    `json:"name"`
+	Address     string    `json:"address"`
+	City        string    `json:"city"`
+	State       string    `json:"state"`
+	Country     string    `json:"country"`
+	ZipCode     string    `json:"zip_code"`
+	CreatedAt   time.Time `json:"created_at"`
+	Version     int       `json:"version"`
+}
+
+func ValidateWarehouse(v *validator.Validator, warehouse Warehouse) {
+	v.Check(warehouse.Name != "", "name", "must be provided")
+	v.Check(len(warehouse.Name) <= 100, "name", "must not be more than 100 bytes long")
+	v.Check(warehouse.Address != "", "address", "must be provided")
+	v.Check(warehouse.City != "", "city", "must be provided")
+	v.Check(len(warehouse.City) <= 100, "city", "must not be more than 100 bytes long")
+	v.Check(warehouse.State != "", "state", "must be provided")
+	v.Check(len(warehouse.State) <= 100, "state", "must not be more than 100 bytes long")
+	v.Check(warehouse.Country != "", "country", "must be provided")
+	v.Check(len(warehouse.Country) <= 100, "country", "must not be more than 100 bytes long")
+	v.Check(warehouse.ZipCode != "", "zip_code", "must be provided")
+	v.Check(len(warehouse.ZipCode) <= 10, "zip_code", "must not be more than 10 bytes long")
+}
+
+// WarehouseStock is a struct that holds the stock level of a furniture,
+// or of a specific variant of it, in a warehouse. AvailableStock is the
+// stock minus the quantities reserved by pending checkouts.
+type WarehouseStock struct {
+	WarehouseID    int64  `json:"warehouse_id"`
+	FurnitureID    int64  `json:"furniture_id"`
+	VariantID      *int64 `json:"variant_id"`
+	Name           string `json:"name"`
+	Stock          int    `json:"stock"`
+	AvailableStock int    `json:"available_stock"`
+}
+
+// StockTransfer is a struct that holds the quantity of a furniture, or
+// of a specific variant of it, moved from a warehouse to another.
+type StockTransfer struct {
+	FurnitureID     int64
+	VariantID       *int64
+	FromWarehouseID int64
+	ToWarehouseID   int64
+	Quantity        int
+	ActorID         *int64
+	Reference       string
+	Note            string
+}
+
+func ValidateStockTransfer(v *validator.Validator, transfer StockTransfer) {
+	v.Check(transfer.FurnitureID > 0, "furniture_id", "must be provided")
+	v.Check(transfer.FromWarehouseID > 0, "from_warehouse_id", "must be provided")
+	v.Check(transfer.ToWarehouseID > 0, "to_warehouse_id", "must be provided")
+	v.Check(transfer.FromWarehouseID != transfer.ToWarehouseID, "to_warehouse_id", "must be different from from_warehouse_id")
+	v.Check(transfer.Quantity > 0, "quantity", "must be greater than zero")
+	v.Check(transfer.Quantity <= 100_000, "quantity", "must be a maximum of 100000")
+	v.Check(len(transfer.Reference) <= 100, "reference", "must not be more than 100 bytes long")
+	v.Check(len(transfer.Note) <= 1000, "note", "must not be more than 1000 bytes long")
+}
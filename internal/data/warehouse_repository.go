@@ -0,0 +1,280 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// warehouseAvailableStock is the SQL expression for the stock level aliased
+// as ws, minus the quantities held by active stock reservations on it.
+const warehouseAvailableStock = `(ws.stock - COALESCE((
+				SELECT SUM(r.quantity) FROM stock_reservation r
+				WHERE r.warehouse_id = ws.warehouse_id AND r.furniture_id = ws.furniture_id
+				AND r.variant_id IS NOT DISTINCT FROM ws.variant_id
+				AND r.status = 'active' AND r.expires_at > NOW()
+			), 0))::INTEGER`
+
+// WarehouseRepository is a type which wraps around a sql.DB connection pool
+// and provide methods for creating and managing warehouses to and from the
+// database.
+type WarehouseRepository struct {
+	DB *sql.DB
+}
+
+// Insert a warehouse record to the database.
+func (w WarehouseRepository) Insert(warehouse *Warehouse) error {
+	query := `
+		INSERT INTO warehouse(name, address, city, state, country, zip_code)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING warehouse_id, created_at, version`
+
+	args := []any{
+		warehouse.Name,
+		warehouse.Address,
+		warehouse.City,
+		warehouse.State,
+		warehouse.Country,
+		warehouse.ZipCode,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := w.DB.QueryRowContext(ctx, query, args...).Scan(&warehouse.WarehouseID, &warehouse.CreatedAt, &warehouse.Version)
+	if err != nil {
+		return warehouseWriteError(err)
+	}
+	return nil
+}
+
+// GetByID retrieve a specific warehouse from the database given the id.
+func (w WarehouseRepository) GetByID(id int64) (Warehouse, error) {
+	query := `
+		SELECT warehouse_id, name, address, city, state, country, zip_code, created_at, version
+		FROM warehouse
+		WHERE warehouse_id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var warehouse Warehouse
+	err := w.DB.QueryRowContext(ctx, query, id).Scan(
+		&warehouse.WarehouseID,
+		&warehouse.Name,
+		&warehouse.Address,
+		&warehouse.City,
+		&warehouse.State,
+		&warehouse.Country,
+		&warehouse.ZipCode,
+		&warehouse.CreatedAt,
+		&warehouse.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return Warehouse{}, ErrRecordNotFound
+		default:
+			return Warehouse{}, err
+		}
+	}
+	return warehouse, nil
+}
+
+// GetAll retrieve every warehouse from the database.
+func (w WarehouseRepository) GetAll() ([]Warehouse, error) {
+	query := `
+		SELECT warehouse_id, name, address, city, state, country, zip_code, created_at, version
+		FROM warehouse
+		ORDER BY warehouse_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := w.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	warehouses := []Warehouse{}
+	for rows.Next() {
+		var warehouse Warehouse
+		err = rows.Scan(
+			&warehouse.WarehouseID,
+			&warehouse.Name,
+			&warehouse.Address,
+			&warehouse.City,
+			&warehouse.State,
+			&warehouse.Country,
+			&warehouse.ZipCode,
+			&warehouse.CreatedAt,
+			&warehouse.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		warehouses = append(warehouses, warehouse)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return warehouses, nil
+}
+
+// Update a specific warehouse record in the database.
+func (w WarehouseRepository) Update(warehouse *Warehouse) error {
+	query := `
+		UPDATE warehouse
+		SET name = $1, address = $2, city = $3, state = $4, country = $5, zip_code = $6, version = version + 1
+		WHERE warehouse_id = $7 AND version = $8
+		RETURNING version`
+
+	args := []any{
+		warehouse.Name,
+		warehouse.Address,
+		warehouse.City,
+		warehouse.State,
+		warehouse.Country,
+		warehouse.ZipCode,
+		warehouse.WarehouseID,
+		warehouse.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := w.DB.QueryRowContext(ctx, query, args...).Scan(&warehouse.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return warehouseWriteError(err)
+		}
+	}
+	return nil
+}
+
+// GetStock retrieve the stock levels of a specific warehouse.
+func (w WarehouseRepository) GetStock(warehouseID int64) ([]WarehouseStock, error) {
+	query := fmt.Sprintf(`
+		SELECT ws.warehouse_id, ws.furniture_id, ws.variant_id,
+			f.name || COALESCE(' (' || v.sku || ')', ''), ws.stock, %s
+		FROM warehouse_stock ws
+		JOIN furniture f ON ws.furniture_id = f.furniture_id
+		LEFT JOIN furniture_variant v ON ws.variant_id = v.variant_id
+		WHERE ws.warehouse_id = $1
+		ORDER BY ws.furniture_id, ws.variant_id NULLS FIRST`, warehouseAvailableStock)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := w.DB.QueryContext(ctx, query, warehouseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	levels := []WarehouseStock{}
+	for rows.Next() {
+		var level WarehouseStock
+		err = rows.Scan(
+			&level.WarehouseID,
+			&level.FurnitureID,
+			&level.VariantID,
+			&level.Name,
+			&level.Stock,
+			&level.AvailableStock,
+		)
+		if err != nil {
+			return nil, err
+		}
+		levels = append(levels, level)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return levels, nil
+}
+
+// lockWarehouseStock locks the stock levels of a furniture, or of a
+// specific variant of it, for the rest of the transaction and returns the
+// available stock in every warehouse that holds it.
+func lockWarehouseStock(ctx context.Context, q queryer, furnitureID int64, variantID *int64) (map[int64]int, error) {
+	query := fmt.Sprintf(`
+		SELECT ws.warehouse_id, %s
+		FROM warehouse_stock ws
+		WHERE ws.furniture_id = $1 AND ws.variant_id IS NOT DISTINCT FROM $2::BIGINT
+		ORDER BY ws.warehouse_id
+		FOR UPDATE`, warehouseAvailableStock)
+
+	rows, err := q.QueryContext(ctx, query, furnitureID, variantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	available := make(map[int64]int)
+	for rows.Next() {
+		var warehouseID int64
+		var stock int
+		if err = rows.Scan(&warehouseID, &stock); err != nil {
+			return nil, err
+		}
+		available[warehouseID] = stock
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return available, nil
+}
+
+// nearestWarehouse returns the warehouse among the candidates that is the
+// closest to the shipping address: in the same state first, then in the
+// same country, the lowest id breaking ties. Every warehouse is a
+// candidate when candidates is empty. It returns ErrNoWarehouse when
+// there is no warehouse at all.
+func nearestWarehouse(ctx context.Context, q queryer, candidates []int64, shipment Shipment) (int64, error) {
+	query := `
+		SELECT warehouse_id
+		FROM warehouse
+		WHERE COALESCE(CARDINALITY($1::BIGINT[]), 0) = 0 OR warehouse_id = ANY($1)
+		ORDER BY
+			CASE
+				WHEN LOWER(country) = LOWER($2) AND LOWER(state) = LOWER($3) THEN 0
+				WHEN LOWER(country) = LOWER($2) THEN 1
+				ELSE 2
+			END,
+			warehouse_id
+		LIMIT 1`
+
+	var warehouseID int64
+	err := q.QueryRowContext(ctx, query, candidates, shipment.Country, shipment.State).Scan(&warehouseID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrNoWarehouse
+		default:
+			return 0, err
+		}
+	}
+	return warehouseID, nil
+}
+
+// warehouseWriteError converts the constraint violations raised when
+// inserting or updating a warehouse to the matching custom error.
+func warehouseWriteError(err error) error {
+	switch {
+	case strings.Contains(err.Error(), `duplicate key value violates unique constraint "warehouse_name_key"`):
+		return ErrDuplicateName
+	default:
+		return err
+	}
+}
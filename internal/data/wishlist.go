@@ -0,0 +1,15 @@
+package data
+
+import "github.com/hayohtee/fumode/internal/money"
+
+// WishlistItem is a struct that holds a furniture, or a specific variant
+// of it, saved to the wishlist of a user with its current price and
+// availability.
+type WishlistItem struct {
+	WishlistItemID int64       `json:"wishlist_item_id"`
+	FurnitureID    int64       `json:"furniture_id"`
+	VariantID      *int64      `json:"variant_id"`
+	Name           string      `json:"name"`
+	Price          money.Money `json:"price"`
+	Availability   string      `json:"availability"`
+}
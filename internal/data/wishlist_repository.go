@@ -0,0 +1,102 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// WishlistRepository is a type which wraps around a sql.DB connection pool
+// and provide methods for managing the wishlist of the users.
+type WishlistRepository struct {
+	DB *sql.DB
+}
+
+// GetAll retrieve the items in the wishlist of a specific user, from the
+// oldest, with their current price and availability.
+func (wr WishlistRepository) GetAll(userID int64) ([]WishlistItem, error) {
+	query := `
+		SELECT
+			w.wishlist_id,
+			w.furniture_id,
+			w.variant_id,
+			f.name,
+			COALESCE(v.price_override, f.price + COALESCE(v.price_delta, 0)),
+			f.availability
+		FROM wishlist w
+		JOIN furniture f ON w.furniture_id = f.furniture_id
+		LEFT JOIN furniture_variant v ON w.variant_id = v.variant_id
+		WHERE w.user_id = $1
+		ORDER BY w.wishlist_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := wr.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []WishlistItem{}
+	for rows.Next() {
+		var item WishlistItem
+		err = rows.Scan(
+			&item.WishlistItemID,
+			&item.FurnitureID,
+			&item.VariantID,
+			&item.Name,
+			&item.Price,
+			&item.Availability,
+		)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// AddItem adds a furniture, or a specific variant of it, to the wishlist of
+// a user. Adding an item already in the wishlist does nothing.
+func (wr WishlistRepository) AddItem(userID, furnitureID int64, variantID *int64) error {
+	query := `
+		INSERT INTO wishlist(user_id, furniture_id, variant_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, furniture_id, COALESCE(variant_id, 0)) DO NOTHING`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := wr.DB.ExecContext(ctx, query, userID, furnitureID, variantID)
+	return err
+}
+
+// DeleteItem removes a specific item from the wishlist of a user.
+func (wr WishlistRepository) DeleteItem(userID, wishlistItemID int64) error {
+	query := `
+		DELETE FROM wishlist
+		WHERE user_id = $1 AND wishlist_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := wr.DB.ExecContext(ctx, query, userID, wishlistItemID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,279 @@
+// Package invoice renders the invoices of the orders as PDF documents. It
+// is written in pure Go, with a minimal PDF writer using the standard fonts
+// of the PDF readers, and like the tax package it holds no state: the
+// invoice is provided by the caller.
+//
+// The amounts of an invoice are in the base currency, like those of the
+// orders. The total charged in another currency is shown alongside.
+package invoice
+
+import (
+	"cmp"
+	"fmt"
+	"github.com/hayohtee/fumode/internal/money"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Number returns the invoice number n as printed on the invoice, such as
+// "INV-000042".
+func Number(n int64) string {
+	return fmt.Sprintf("INV-%06d", n)
+}
+
+// Party is the seller or the customer of an invoice, with the lines of its
+// address.
+type Party struct {
+	Name    string
+	Address []string
+	TaxID   string
+}
+
+// Line is an item of an invoice. Discount is the part of the discount of
+// the order taken off the item, and Tax the tax due on it at TaxPercent.
+type Line struct {
+	Name       string
+	Quantity   int
+	UnitPrice  money.Money
+	Discount   money.Money
+	TaxClass   string
+	TaxPercent float64
+	Tax        money.Money
+}
+
+// Invoice is the invoice of an order, issued at IssuedAt. Tax is part of
+// the prices when TaxIncluded is set and added to the total otherwise.
+// Charged is the total charged to the customer, at ExchangeRate.
+type Invoice struct {
+	Number       string
+	IssuedAt     time.Time
+	OrderID      int64
+	Seller       Party
+	Customer     Party
+	Lines        []Line
+	Discount     money.Money
+	Shipping     money.Money
+	Tax          money.Money
+	TaxIncluded  bool
+	Total        money.Money
+	Charged      money.Money
+	ExchangeRate float64
+}
+
+// The layout of the pages, in points.
+const (
+	margin    = 50.0
+	rowHeight = 18.0
+	// The lowest baseline of the rows of the items, below which they are
+	// continued on a new page.
+	lastRow = pageHeight - 90
+)
+
+// The right edges of the columns of the items, the name being written
+// from the margin.
+const (
+	quantityColumn  = 310.0
+	unitPriceColumn = 385.0
+	discountColumn  = 450.0
+	taxColumn       = 490.0
+	amountColumn    = pageWidth - margin
+)
+
+// Render returns the invoice as a PDF document.
+func Render(inv Invoice) []byte {
+	doc := &document{}
+	p := doc.addPage()
+
+	// The header, with the seller on the right.
+	p.text(margin, margin+20, bold, 22, "INVOICE")
+	y := margin + 12
+	p.textRight(amountColumn, y, bold, 11, inv.Seller.Name)
+	for _, line := range sellerLines(inv.Seller) {
+		y += 13
+		p.textRight(amountColumn, y, regular, 9, line)
+	}
+
+	y = max(y, margin+40) + 30
+	details := [][2]string{
+		{"Invoice number", inv.Number},
+		{"Invoice date", inv.IssuedAt.Format("2 January 2006")},
+		{"Order", "#" + strconv.FormatInt(inv.OrderID, 10)},
+	}
+	for _, detail := range details {
+		p.text(margin, y, bold, 9, detail[0])
+		p.text(margin+90, y, regular, 9, detail[1])
+		y += 13
+	}
+
+	// The customer, who is billed at the address the order is shipped to.
+	y += 15
+	p.text(margin, y, bold, 9, "BILLED AND SHIPPED TO")
+	y += 15
+	p.text(margin, y, bold, 10, inv.Customer.Name)
+	for _, line := range inv.Customer.Address {
+		y += 13
+		p.text(margin, y, regular, 9, line)
+	}
+	if inv.Customer.TaxID != "" {
+		y += 13
+		p.text(margin, y, regular, 9, "Tax ID: "+inv.Customer.TaxID)
+	}
+
+	y += 30
+	y = itemsHeader(p, y)
+
+	subtotal := money.Zero(inv.Total.Currency)
+	for _, line := range inv.Lines {
+		if y > lastRow {
+			p = doc.addPage()
+			y = itemsHeader(p, margin+20)
+		}
+
+		amount := line.UnitPrice.Mul(line.Quantity).Sub(line.Discount)
+		subtotal = subtotal.Add(line.UnitPrice.Mul(line.Quantity))
+
+		p.text(margin+5, y, regular, 9, fit(regular, 9, line.Name, quantityColumn-margin-40))
+		p.textRight(quantityColumn, y, regular, 9, strconv.Itoa(line.Quantity))
+		p.textRight(unitPriceColumn, y, regular, 9, line.UnitPrice.String())
+		p.textRight(discountColumn, y, regular, 9, line.Discount.String())
+		p.textRight(taxColumn, y, regular, 9, percent(line.TaxPercent))
+		p.textRight(amountColumn, y, regular, 9, amount.String())
+		y += rowHeight
+	}
+	p.line(margin, y-rowHeight+6, amountColumn, y-rowHeight+6)
+
+	taxLabel := "Tax"
+	if inv.TaxIncluded {
+		taxLabel = "Tax included"
+	}
+
+	discount := inv.Discount.String()
+	if inv.Discount.IsPositive() {
+		discount = "-" + discount
+	}
+
+	totals := [][2]string{
+		{"Subtotal", subtotal.String()},
+		{"Discount", discount},
+		{"Shipping", inv.Shipping.String()},
+		{taxLabel, inv.Tax.String()},
+	}
+
+	breakdown := taxBreakdown(inv)
+
+	// The totals and the tax breakdown are kept together, on a new page
+	// when they do not fit below the items.
+	if y+rowHeight*float64(len(totals)+len(breakdown)+6) > pageHeight-margin {
+		p = doc.addPage()
+		y = margin + 20
+	}
+
+	y += 10
+	for _, total := range totals {
+		p.text(discountColumn-60, y, regular, 9, total[0])
+		p.textRight(amountColumn, y, regular, 9, total[1])
+		y += 15
+	}
+	p.line(discountColumn-60, y-9, amountColumn, y-9)
+	y += 4
+	p.text(discountColumn-60, y, bold, 11, "Total "+inv.Total.Currency)
+	p.textRight(amountColumn, y, bold, 11, inv.Total.String())
+
+	if inv.Charged.Currency != inv.Total.Currency {
+		y += 15
+		p.textRight(amountColumn, y, regular, 8, fmt.Sprintf("Charged %s %s at 1 %s = %s %s", inv.Charged.String(),
+			inv.Charged.Currency, inv.Total.Currency, strconv.FormatFloat(inv.ExchangeRate, 'f', -1, 64), inv.Charged.Currency))
+	}
+
+	y += 35
+	p.text(margin, y, bold, 9, "TAX BREAKDOWN")
+	y += 16
+	p.text(margin+5, y, bold, 8, "Tax class")
+	p.textRight(unitPriceColumn, y, bold, 8, "Rate")
+	p.textRight(discountColumn+40, y, bold, 8, "Taxable amount")
+	p.textRight(amountColumn, y, bold, 8, "Tax")
+	for _, rate := range breakdown {
+		y += 14
+		p.text(margin+5, y, regular, 9, rate.class)
+		p.textRight(unitPriceColumn, y, regular, 9, percent(rate.percent))
+		p.textRight(discountColumn+40, y, regular, 9, rate.taxable.String())
+		p.textRight(amountColumn, y, regular, 9, rate.tax.String())
+	}
+
+	// The footer of every page, once the number of pages is known.
+	for i, p := range doc.pages {
+		footer := fmt.Sprintf("%s - %s", inv.Seller.Name, inv.Number)
+		p.line(margin, pageHeight-margin+5, amountColumn, pageHeight-margin+5)
+		p.text(margin, pageHeight-margin+18, regular, 8, footer)
+		p.textRight(amountColumn, pageHeight-margin+18, regular, 8, fmt.Sprintf("Page %d of %d", i+1, len(doc.pages)))
+	}
+
+	return doc.bytes()
+}
+
+// sellerLines returns the lines written below the name of the seller.
+func sellerLines(seller Party) []string {
+	lines := slices.Clone(seller.Address)
+	if seller.TaxID != "" {
+		lines = append(lines, "Tax ID: "+seller.TaxID)
+	}
+	return lines
+}
+
+// itemsHeader writes the header of the table of the items with its top at
+// y, and returns the baseline of its first row.
+func itemsHeader(p *page, y float64) float64 {
+	p.fillRect(margin, y, amountColumn-margin, rowHeight, 0.9)
+	baseline := y + 12
+	p.text(margin+5, baseline, bold, 8, "Item")
+	p.textRight(quantityColumn, baseline, bold, 8, "Qty")
+	p.textRight(unitPriceColumn, baseline, bold, 8, "Unit price")
+	p.textRight(discountColumn, baseline, bold, 8, "Discount")
+	p.textRight(taxColumn, baseline, bold, 8, "Tax %")
+	p.textRight(amountColumn, baseline, bold, 8, "Amount")
+	return baseline + rowHeight + 2
+}
+
+// taxRate is the tax of the items of an invoice due at a rate of a tax
+// class, and the amount it is due on.
+type taxRate struct {
+	class   string
+	percent float64
+	taxable money.Money
+	tax     money.Money
+}
+
+// taxBreakdown returns the tax of the invoice by tax class and rate, in the
+// order of the classes and then of the rates. The taxable amount excludes
+// the tax when it is part of the prices.
+func taxBreakdown(inv Invoice) []taxRate {
+	var rates []taxRate
+	for _, line := range inv.Lines {
+		taxable := line.UnitPrice.Mul(line.Quantity).Sub(line.Discount)
+		if inv.TaxIncluded {
+			taxable = taxable.Sub(line.Tax)
+		}
+
+		i := slices.IndexFunc(rates, func(rate taxRate) bool {
+			return rate.class == line.TaxClass && rate.percent == line.TaxPercent
+		})
+		if i < 0 {
+			rates = append(rates, taxRate{line.TaxClass, line.TaxPercent, taxable, line.Tax})
+			continue
+		}
+		rates[i].taxable = rates[i].taxable.Add(taxable)
+		rates[i].tax = rates[i].tax.Add(line.Tax)
+	}
+
+	slices.SortFunc(rates, func(a, b taxRate) int {
+		return cmp.Or(cmp.Compare(a.class, b.class), cmp.Compare(a.percent, b.percent))
+	})
+	return rates
+}
+
+// percent returns the tax percentage p as printed on the invoice, such as
+// "7.5%".
+func percent(p float64) string {
+	return strconv.FormatFloat(p, 'f', -1, 64) + "%"
+}
@@ -0,0 +1,189 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// The size of an A4 page, in points.
+const (
+	pageWidth  = 595.28
+	pageHeight = 841.89
+)
+
+// font is one of the standard Helvetica fonts, which every PDF reader
+// provides so that they do not have to be embedded.
+type font int
+
+const (
+	regular font = iota
+	bold
+)
+
+// The widths of the printable ASCII characters of the fonts, from space to
+// tilde, in thousandths of the font size. The other characters are assumed
+// to be as wide as a digit.
+var widths = [2][95]int{
+	regular: {
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	},
+	bold: {
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	},
+}
+
+// winAnsi maps the characters of WinAnsiEncoding outside of Latin-1 to
+// their code.
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, '‘': 0x91, '’': 0x92,
+	'“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '™': 0x99,
+}
+
+// encode returns s in WinAnsiEncoding, the encoding of the text of the
+// pages, replacing the characters it cannot encode by a question mark.
+func encode(s string) []byte {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r < 0x20:
+			b = append(b, ' ')
+		case r < 0x7f, r >= 0xa0 && r <= 0xff:
+			b = append(b, byte(r))
+		default:
+			c, ok := winAnsi[r]
+			if !ok {
+				c = '?'
+			}
+			b = append(b, c)
+		}
+	}
+	return b
+}
+
+// textWidth returns the width of s, in points, when written in the font at
+// the size.
+func textWidth(f font, size float64, s string) float64 {
+	total := 0
+	for _, c := range encode(s) {
+		if c >= 0x20 && c < 0x7f {
+			total += widths[f][c-0x20]
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// fit returns s shortened with an ellipsis, if needed, so that it is at
+// most maxWidth points wide when written in the font at the size.
+func fit(f font, size float64, s string, maxWidth float64) string {
+	if textWidth(f, size, s) <= maxWidth {
+		return s
+	}
+
+	runes := []rune(s)
+	for len(runes) > 0 && textWidth(f, size, string(runes)+"…") > maxWidth {
+		runes = runes[:len(runes)-1]
+	}
+	return strings.TrimSpace(string(runes)) + "…"
+}
+
+// page is a page of a document. Its positions are measured in points from
+// the top left corner, rather than from the bottom left one as in PDF.
+type page struct {
+	content bytes.Buffer
+}
+
+// text writes s in the font at the size, with its baseline starting at x,
+// y.
+func (p *page) text(x, y float64, f font, size float64, s string) {
+	var escaped bytes.Buffer
+	for _, c := range encode(s) {
+		if c == '(' || c == ')' || c == '\\' {
+			escaped.WriteByte('\\')
+		}
+		escaped.WriteByte(c)
+	}
+
+	fmt.Fprintf(&p.content, "BT /F%d %.2f Tf %.2f %.2f Td (%s) Tj ET\n", f+1, size, x, pageHeight-y, escaped.Bytes())
+}
+
+// textRight writes s like text, but ending at x.
+func (p *page) textRight(x, y float64, f font, size float64, s string) {
+	p.text(x-textWidth(f, size, s), y, f, size, s)
+}
+
+// line draws a thin line from x1, y1 to x2, y2.
+func (p *page) line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(&p.content, "0.5 w %.2f %.2f m %.2f %.2f l S\n", x1, pageHeight-y1, x2, pageHeight-y2)
+}
+
+// fillRect fills the rectangle whose top left corner is at x, y in the
+// gray level, from 0 for black to 1 for white.
+func (p *page) fillRect(x, y, width, height, gray float64) {
+	fmt.Fprintf(&p.content, "%.2f g %.2f %.2f %.2f %.2f re f 0 g\n", gray, x, pageHeight-y-height, width, height)
+}
+
+// document is a PDF document made of A4 pages.
+type document struct {
+	pages []*page
+}
+
+// addPage appends an empty page to the document and returns it.
+func (d *document) addPage() *page {
+	p := &page{}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// bytes returns the document encoded as a PDF file.
+func (d *document) bytes() []byte {
+	var buf bytes.Buffer
+	var offsets []int
+
+	// The objects are numbered from 1 in the order they are written: the
+	// catalog, the page tree, the two fonts and then every page followed
+	// by its content.
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, p := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", pageWidth, pageHeight, 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", p.content.Len(), p.content.Bytes()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return buf.Bytes()
+}
@@ -40,10 +40,19 @@ func New(client *mail.Client, sender string) Mailer {
 	return Mailer{client: client, sender: sender}
 }
 
+// Attachment is a file attached to an email, such as the invoice of an
+// order, with the content type of its Data.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
 // Send is a method that takes the recipient email address as the first
-// parameter, the name of the file containing the templates, and any dynamic
-// data for the templates as an any parameter.
-func (m *Mailer) Send(recipient, templateFile string, data any) error {
+// parameter, the name of the file containing the templates, any dynamic
+// data for the templates as an any parameter and the files to attach to
+// the email, if any.
+func (m *Mailer) Send(recipient, templateFile string, data any, attachments ...Attachment) error {
 	tmpl, err := template.New("email").ParseFS(templateFS, fmt.Sprintf("templates/%s", templateFile))
 	if err != nil {
 		return err
@@ -80,6 +89,14 @@ func (m *Mailer) Send(recipient, templateFile string, data any) error {
 	msg.SetBodyString(mail.TypeTextPlain, plainBody.String())
 	msg.AddAlternativeString(mail.TypeTextHTML, htmlBody.String())
 
+	for _, attachment := range attachments {
+		contentType := mail.WithFileContentType(mail.ContentType(attachment.ContentType))
+		err = msg.AttachReader(attachment.Filename, bytes.NewReader(attachment.Data), contentType)
+		if err != nil {
+			return err
+		}
+	}
+
 	err = m.client.DialAndSend(msg)
 	if err != nil {
 		return err
@@ -0,0 +1,411 @@
+// Package money provides an exact representation of monetary amounts, so
+// that prices, totals and discounts never drift by a cent the way floating
+// point numbers do.
+//
+// Amounts are held as an integer number of the minor units of their
+// currency, such as cents. The only operations that can produce fractions
+// of a minor unit are Percent, PercentIncluded, Convert and the parsing of
+// values read from the database, and they all round half away from zero,
+// which is how PostgreSQL rounds NUMERIC values. Allocate never rounds, it
+// hands out the minor units so that the shares add up to the amount.
+// Parse, used for client input, never rounds and rejects values more
+// precise than the currency.
+package money
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is the currency the prices are stored in.
+const DefaultCurrency = "USD"
+
+// ErrInvalid is returned when parsing a value which is not a decimal
+// number, and ErrTooPrecise when it has more decimal places than the
+// minor units of its currency.
+var (
+	ErrInvalid    = errors.New("invalid amount")
+	ErrTooPrecise = errors.New("too many decimal places")
+)
+
+// CurrencyRX is a regular expression pattern for ISO 4217 currency codes.
+var CurrencyRX = regexp.MustCompile("^[A-Z]{3}$")
+
+// zeroDecimalCurrencies holds the currencies without minor units. Every
+// other currency is assumed to have two decimal places.
+var zeroDecimalCurrencies = map[string]bool{
+	"BIF": true, "CLP": true, "DJF": true, "GNF": true, "ISK": true, "JPY": true, "KMF": true, "KRW": true,
+	"PYG": true, "RWF": true, "UGX": true, "VND": true, "VUV": true, "XAF": true, "XOF": true, "XPF": true,
+}
+
+// Money is an amount in the minor units of a currency. The zero Money has
+// no currency and takes the currency of the amounts it is combined with.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// New returns the amount of minor units of the currency.
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// Zero returns no amount of the currency.
+func Zero(currency string) Money {
+	return Money{Currency: currency}
+}
+
+// Decimals returns the number of decimal places of the currency.
+func Decimals(currency string) int {
+	if zeroDecimalCurrencies[currency] {
+		return 0
+	}
+	return 2
+}
+
+// Parse parses a decimal number of major units of the currency, such as
+// "19.99". It returns ErrTooPrecise if the number has more decimal places
+// than the currency, rather than rounding it.
+func Parse(s, currency string) (Money, error) {
+	amount, exact, err := parse(s, Decimals(currency))
+	if err != nil {
+		return Money{}, err
+	}
+	if !exact {
+		return Money{}, ErrTooPrecise
+	}
+	return New(amount, currency), nil
+}
+
+// parse converts the decimal number to minor units with the provided
+// number of decimal places, rounding half away from zero. It reports
+// whether the conversion was exact.
+func parse(s string, decimals int) (amount int64, exact bool, err error) {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	if negative || strings.HasPrefix(s, "+") {
+		s = s[1:]
+	}
+
+	whole, fraction, _ := strings.Cut(s, ".")
+	if whole == "" && fraction == "" || strings.Trim(whole+fraction, "0123456789") != "" {
+		return 0, false, ErrInvalid
+	}
+
+	exact = true
+	roundUp := false
+	if len(fraction) > decimals {
+		dropped := fraction[decimals:]
+		exact = strings.Trim(dropped, "0") == ""
+		roundUp = dropped[0] >= '5'
+		fraction = fraction[:decimals]
+	}
+	fraction += strings.Repeat("0", decimals-len(fraction))
+
+	digits := strings.TrimLeft(whole+fraction, "0")
+	if digits == "" {
+		digits = "0"
+	}
+	amount, err = strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, false, ErrInvalid
+	}
+
+	if roundUp {
+		amount++
+	}
+	if negative {
+		amount = -amount
+	}
+	return amount, exact, nil
+}
+
+// currency returns the currency shared by m and o. It panics if they are
+// in different currencies, as they cannot be combined without converting
+// one of them.
+func (m Money) currency(o Money) string {
+	switch {
+	case m.Currency == o.Currency, o.Currency == "":
+		return m.Currency
+	case m.Currency == "":
+		return o.Currency
+	default:
+		panic(fmt.Sprintf("money: mismatched currencies %s and %s", m.Currency, o.Currency))
+	}
+}
+
+// Add returns the sum of m and o.
+func (m Money) Add(o Money) Money {
+	return New(m.Amount+o.Amount, m.currency(o))
+}
+
+// Sub returns the difference of m and o.
+func (m Money) Sub(o Money) Money {
+	return New(m.Amount-o.Amount, m.currency(o))
+}
+
+// Mul returns m multiplied by the quantity.
+func (m Money) Mul(quantity int) Money {
+	return New(m.Amount*int64(quantity), m.Currency)
+}
+
+// Percent returns the percentage of m, rounded half away from zero to the
+// minor unit. The percentage is taken to two decimal places.
+func (m Money) Percent(percent float64) Money {
+	hundredths := int64(math.Round(percent * 100))
+	return New(divRound(m.Amount*hundredths, 10_000), m.Currency)
+}
+
+// PercentIncluded returns the part of m which is the percentage of the
+// rest of m, such as the tax included in a price, rounded half away from
+// zero to the minor unit. The percentage is taken to two decimal places.
+func (m Money) PercentIncluded(percent float64) Money {
+	hundredths := int64(math.Round(percent * 100))
+	return New(divRound(m.Amount*hundredths, 10_000+hundredths), m.Currency)
+}
+
+// Allocate splits m between the weights in proportion to them, so that
+// the shares add up to m exactly. The minor units left over once every
+// share is rounded towards zero are handed out one at a time, to the
+// shares with the largest fractional parts first and to the earlier
+// shares on ties. Every share is zero when the weights add up to zero.
+func (m Money) Allocate(weights []int64) []Money {
+	shares := make([]Money, len(weights))
+	total := new(big.Int)
+	for _, weight := range weights {
+		total.Add(total, big.NewInt(weight))
+	}
+	if total.Sign() == 0 {
+		for i := range shares {
+			shares[i] = Zero(m.Currency)
+		}
+		return shares
+	}
+
+	remainders := make([]*big.Int, len(weights))
+	allocated := int64(0)
+	for i, weight := range weights {
+		product := new(big.Int).Mul(big.NewInt(m.Amount), big.NewInt(weight))
+		quotient, remainder := new(big.Int).QuoRem(product, total, new(big.Int))
+		shares[i] = New(quotient.Int64(), m.Currency)
+		remainders[i] = remainder.Abs(remainder)
+		allocated += quotient.Int64()
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return remainders[b].Cmp(remainders[a])
+	})
+
+	unit := int64(1)
+	if m.Amount < 0 {
+		unit = -1
+	}
+	for i := 0; allocated != m.Amount; i++ {
+		shares[order[i%len(order)]].Amount += unit
+		allocated += unit
+	}
+	return shares
+}
+
+// divRound returns n divided by the positive d, rounded half away from
+// zero.
+func divRound(n, d int64) int64 {
+	quotient, remainder := n/d, n%d
+	switch {
+	case remainder*2 >= d:
+		quotient++
+	case remainder*2 <= -d:
+		quotient--
+	}
+	return quotient
+}
+
+// Convert returns m converted to the currency at the exchange rate, which
+// is the amount of the currency worth one major unit of the currency of m.
+// The result is rounded half away from zero to the minor unit.
+func (m Money) Convert(currency string, rate float64) Money {
+	if currency == m.Currency {
+		return m
+	}
+
+	// The rate is taken from its shortest decimal representation, which is
+	// how it was written, rather than from its binary approximation.
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(rate, 'f', -1, 64))
+	if !ok {
+		panic(fmt.Sprintf("money: invalid exchange rate %v", rate))
+	}
+
+	scale := new(big.Rat).SetFrac(
+		new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(Decimals(currency))), nil),
+		new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(Decimals(m.Currency))), nil),
+	)
+	r.Mul(r, scale)
+	r.Mul(r, new(big.Rat).SetInt64(m.Amount))
+
+	amount, remainder := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if remainder.Abs(remainder).Lsh(remainder, 1).Cmp(r.Denom()) >= 0 {
+		if r.Sign() < 0 {
+			amount.Sub(amount, big.NewInt(1))
+		} else {
+			amount.Add(amount, big.NewInt(1))
+		}
+	}
+	return New(amount.Int64(), currency)
+}
+
+// Cmp compares m and o and returns -1 if m is less than o, 0 if they are
+// equal and +1 if m is more than o.
+func (m Money) Cmp(o Money) int {
+	m.currency(o)
+	switch {
+	case m.Amount < o.Amount:
+		return -1
+	case m.Amount > o.Amount:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Min returns the lesser of m and o.
+func (m Money) Min(o Money) Money {
+	if m.Cmp(o) <= 0 {
+		return New(m.Amount, m.currency(o))
+	}
+	return New(o.Amount, m.currency(o))
+}
+
+// InCurrency reports whether m is in the currency. The zero Money, which
+// has no currency, is in every currency.
+func (m Money) InCurrency(currency string) bool {
+	return m.Currency == currency || m.Currency == ""
+}
+
+// IsZero reports whether m is no amount.
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// IsPositive reports whether m is more than zero.
+func (m Money) IsPositive() bool {
+	return m.Amount > 0
+}
+
+// IsNegative reports whether m is less than zero.
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// String returns m as a decimal number of major units, such as "19.99".
+func (m Money) String() string {
+	decimals := Decimals(m.Currency)
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	s := strconv.FormatInt(amount, 10)
+	if decimals == 0 {
+		return sign + s
+	}
+	if len(s) <= decimals {
+		s = strings.Repeat("0", decimals-len(s)+1) + s
+	}
+	return sign + s[:len(s)-decimals] + "." + s[len(s)-decimals:]
+}
+
+// jsonMoney is how Money is encoded to JSON.
+type jsonMoney struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// MarshalJSON encodes m as an object holding the amount in minor units
+// and the currency, such as {"amount": 1999, "currency": "USD"}.
+func (m Money) MarshalJSON() ([]byte, error) {
+	currency := m.Currency
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	return json.Marshal(jsonMoney{Amount: m.Amount, Currency: currency})
+}
+
+// UnmarshalJSON decodes either the object produced by MarshalJSON or a
+// plain JSON number of major units of the DefaultCurrency, such as 19.99,
+// which is parsed exactly from its text.
+func (m *Money) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && b[0] == '{' {
+		var v jsonMoney
+		if err := json.Unmarshal(b, &v); err != nil {
+			return err
+		}
+		if v.Currency == "" {
+			v.Currency = DefaultCurrency
+		}
+		if !CurrencyRX.MatchString(v.Currency) {
+			return fmt.Errorf("invalid currency %q", v.Currency)
+		}
+		*m = New(v.Amount, v.Currency)
+		return nil
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return err
+	}
+
+	parsed, err := Parse(n.String(), DefaultCurrency)
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, n)
+	}
+	*m = parsed
+	return nil
+}
+
+// Scan implements the sql.Scanner interface, converting a NUMERIC column
+// of major units of the DefaultCurrency to Money. Values more precise
+// than the currency are rounded half away from zero.
+func (m *Money) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	case int64:
+		s = strconv.FormatInt(v, 10)
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Errorf("money: cannot scan %T", src)
+	}
+
+	amount, _, err := parse(s, Decimals(DefaultCurrency))
+	if err != nil {
+		return fmt.Errorf("money: cannot scan %q: %w", s, err)
+	}
+	*m = New(amount, DefaultCurrency)
+	return nil
+}
+
+// Value implements the driver.Valuer interface, storing m as a decimal
+// number of major units.
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
@@ -0,0 +1,234 @@
+package money
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		currency string
+		want     Money
+		wantErr  error
+	}{
+		{"whole", "19", "USD", New(1900, "USD"), nil},
+		{"cents", "19.99", "USD", New(1999, "USD"), nil},
+		{"one decimal", "19.9", "USD", New(1990, "USD"), nil},
+		{"no whole part", ".5", "USD", New(50, "USD"), nil},
+		{"no fraction", "7.", "USD", New(700, "USD"), nil},
+		{"trailing zeros", "1.2500", "USD", New(125, "USD"), nil},
+		{"surrounding spaces", " 3.10 ", "USD", New(310, "USD"), nil},
+		{"zero decimal currency", "1500", "JPY", New(1500, "JPY"), nil},
+		{"negative", "-4.75", "USD", New(-475, "USD"), nil},
+		{"plus sign", "+4.75", "USD", New(475, "USD"), nil},
+		{"negative zero", "-0.00", "USD", New(0, "USD"), nil},
+		{"too precise", "19.999", "USD", Money{}, ErrTooPrecise},
+		{"too precise half", "0.005", "USD", Money{}, ErrTooPrecise},
+		{"too precise for currency", "1500.5", "JPY", Money{}, ErrTooPrecise},
+		{"too precise negative", "-1.001", "USD", Money{}, ErrTooPrecise},
+		{"empty", "", "USD", Money{}, ErrInvalid},
+		{"dot", ".", "USD", Money{}, ErrInvalid},
+		{"letters", "abc", "USD", Money{}, ErrInvalid},
+		{"exponent", "1e3", "USD", Money{}, ErrInvalid},
+		{"comma", "1,000.00", "USD", Money{}, ErrInvalid},
+		{"two dots", "1.2.3", "USD", Money{}, ErrInvalid},
+		{"overflow", "999999999999999999999", "USD", Money{}, ErrInvalid},
+		{"sign only", "-", "USD", Money{}, ErrInvalid},
+		{"double minus", "--1", "USD", Money{}, ErrInvalid},
+		{"minus plus", "-+1", "USD", Money{}, ErrInvalid},
+		{"plus minus", "+-1", "USD", Money{}, ErrInvalid},
+		{"trailing sign", "1-", "USD", Money{}, ErrInvalid},
+		{"space after sign", "- 1", "USD", Money{}, ErrInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.s, tt.currency)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Parse(%q) error = %v, want %v", tt.s, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		name string
+		src  any
+		want int64
+	}{
+		{"string", "19.99", 1999},
+		{"bytes", []byte("5.10"), 510},
+		{"int64", int64(12), 1200},
+		{"float64", 2.5, 250},
+		{"half rounds up", "1.005", 101},
+		{"below half rounds down", "1.0049", 100},
+		{"above half rounds up", "1.0051", 101},
+		{"negative half rounds down", "-1.005", -101},
+		{"negative below half rounds up", "-1.0049", -100},
+		{"negative smallest half", "-0.005", -1},
+		{"half carries", "9.995", 1000},
+		{"negative", "-42.00", -4200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m Money
+			if err := m.Scan(tt.src); err != nil {
+				t.Fatalf("Scan(%v) error = %v", tt.src, err)
+			}
+			if want := New(tt.want, DefaultCurrency); m != want {
+				t.Errorf("Scan(%v) = %+v, want %+v", tt.src, m, want)
+			}
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		var m Money
+		if err := m.Scan("abc"); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Scan(%q) error = %v, want %v", "abc", err, ErrInvalid)
+		}
+	})
+
+	t.Run("unsupported type", func(t *testing.T) {
+		var m Money
+		if err := m.Scan(true); err == nil {
+			t.Error("Scan(true) error = nil, want an error")
+		}
+	})
+}
+
+func TestPercent(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  int64
+		percent float64
+		want    int64
+	}{
+		{"whole percent", 10000, 10, 1000},
+		{"fractional percent", 10000, 7.25, 725},
+		{"half rounds up", 50, 1, 1},
+		{"below half rounds down", 49, 1, 0},
+		{"negative half rounds down", -50, 1, -1},
+		{"zero percent", 1999, 0, 0},
+		{"hundred percent", 1999, 100, 1999},
+		{"percent rounded to hundredths", 10000, 7.255, 726},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := New(tt.amount, "USD").Percent(tt.percent)
+			if want := New(tt.want, "USD"); got != want {
+				t.Errorf("Percent(%v) of %d = %+v, want %+v", tt.percent, tt.amount, got, want)
+			}
+		})
+	}
+}
+
+func TestPercentIncluded(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  int64
+		percent float64
+		want    int64
+	}{
+		{"exact", 11000, 10, 1000},
+		{"vat", 12000, 20, 2000},
+		{"half rounds up", 1, 100, 1},
+		{"rounds up", 1000, 7.5, 70},
+		{"rounds down", 1000, 8, 74},
+		{"negative half rounds down", -1, 100, -1},
+		{"zero percent", 1999, 0, 0},
+		{"negative", -11000, 10, -1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := New(tt.amount, "USD").PercentIncluded(tt.percent)
+			if want := New(tt.want, "USD"); got != want {
+				t.Errorf("PercentIncluded(%v) of %d = %+v, want %+v", tt.percent, tt.amount, got, want)
+			}
+		})
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     Money
+		currency string
+		rate     float64
+		want     Money
+	}{
+		{"same currency", New(1999, "USD"), "USD", 2, New(1999, "USD")},
+		{"two to two decimals", New(1000, "USD"), "EUR", 0.92, New(920, "EUR")},
+		{"rounds half up", New(1, "USD"), "EUR", 0.5, New(1, "EUR")},
+		{"rounds below half down", New(1, "USD"), "EUR", 0.49, New(0, "EUR")},
+		{"negative rounds half down", New(-1, "USD"), "EUR", 0.5, New(-1, "EUR")},
+		{"two to zero decimals", New(1999, "USD"), "JPY", 151.37, New(3026, "JPY")},
+		{"two to zero decimals half", New(150, "USD"), "JPY", 1, New(2, "JPY")},
+		{"zero to two decimals", New(1000, "JPY"), "USD", 0.0066, New(660, "USD")},
+		{"zero to zero decimals", New(1000, "JPY"), "KRW", 9.05, New(9050, "KRW")},
+		{"rate read as written", New(100000, "USD"), "GBP", 0.79123457, New(79123, "GBP")},
+		{"zero", Zero("USD"), "EUR", 0.92, New(0, "EUR")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.from.Convert(tt.currency, tt.rate)
+			if got != tt.want {
+				t.Errorf("%+v.Convert(%s, %v) = %+v, want %+v", tt.from, tt.currency, tt.rate, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  int64
+		weights []int64
+		want    []int64
+	}{
+		{"even", 900, []int64{1, 1, 1}, []int64{300, 300, 300}},
+		{"remainder to earlier shares on ties", 100, []int64{1, 1, 1}, []int64{34, 33, 33}},
+		{"remainder to largest fraction", 100, []int64{1, 2, 3}, []int64{17, 33, 50}},
+		{"proportional", 1000, []int64{2500, 7500}, []int64{250, 750}},
+		{"zero weight", 100, []int64{0, 3, 1}, []int64{0, 75, 25}},
+		{"negative amount", -100, []int64{1, 1, 1}, []int64{-34, -33, -33}},
+		{"single weight", 1999, []int64{7}, []int64{1999}},
+		{"amount smaller than weights", 2, []int64{1, 1, 1}, []int64{1, 1, 0}},
+		{"zero weights", 100, []int64{0, 0}, []int64{0, 0}},
+		{"no weights", 100, nil, []int64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shares := New(tt.amount, "USD").Allocate(tt.weights)
+			if len(shares) != len(tt.want) {
+				t.Fatalf("Allocate(%v) returned %d shares, want %d", tt.weights, len(shares), len(tt.want))
+			}
+
+			sum := Zero("USD")
+			for i, share := range shares {
+				if share != New(tt.want[i], "USD") {
+					t.Errorf("Allocate(%v)[%d] = %+v, want %d USD", tt.weights, i, share, tt.want[i])
+				}
+				sum = sum.Add(share)
+			}
+
+			total := int64(0)
+			for _, weight := range tt.weights {
+				total += weight
+			}
+			if total != 0 && sum.Amount != tt.amount {
+				t.Errorf("Allocate(%v) shares add up to %d, want %d", tt.weights, sum.Amount, tt.amount)
+			}
+		})
+	}
+}
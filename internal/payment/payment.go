@@ -0,0 +1,47 @@
+package payment
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/hayohtee/fumode/internal/money"
+)
+
+// ErrDeclined is a custom error that is returned when the payment
+// provider declines a charge.
+var ErrDeclined = errors.New("payment declined")
+
+// Provider is the interface implemented by the payment providers the
+// orders are charged with. Charge returns the reference of the charge
+// at the provider, which is needed to refund it.
+type Provider interface {
+	Charge(ctx context.Context, amount money.Money, token string) (string, error)
+	Refund(ctx context.Context, reference string, amount money.Money) error
+}
+
+// DeclinedToken is the token that FakeProvider always declines.
+const DeclinedToken = "tok_declined"
+
+// FakeProvider is a Provider which accepts every charge, except the ones
+// made with DeclinedToken. It is used until a real payment provider is
+// integrated, and lets the checkout flow be exercised end to end.
+type FakeProvider struct{}
+
+// Charge accepts the charge unless the token is DeclinedToken.
+func (FakeProvider) Charge(ctx context.Context, amount money.Money, token string) (string, error) {
+	if token == DeclinedToken {
+		return "", ErrDeclined
+	}
+
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "ch_" + hex.EncodeToString(b), nil
+}
+
+// Refund always succeeds.
+func (FakeProvider) Refund(ctx context.Context, reference string, amount money.Money) error {
+	return nil
+}
@@ -0,0 +1,92 @@
+package promotions
+
+import (
+	"github.com/hayohtee/fumode/internal/money"
+	"github.com/hayohtee/fumode/internal/validator"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// The types of discount a coupon gives.
+const (
+	DiscountPercentage = "percentage"
+	DiscountFixed      = "fixed"
+)
+
+// CodeRX is a regular expression pattern for the coupon codes, made of
+// uppercase letters, digits, hyphens and underscores.
+var CodeRX = regexp.MustCompile("^[A-Z0-9_-]+$")
+
+// Coupon is a struct that holds a promotion code and the rules of its
+// discount. PercentOff is only set for percentage discounts and AmountOff
+// for fixed ones. The coupon only discounts the furniture in CategoryIDs,
+// or their sub categories, and in FurnitureIDs, every furniture when both
+// are empty. The validity window and the usage limits are ignored when nil.
+// A coupon that is not Stackable cannot be combined with any other coupon.
+type Coupon struct {
+	CouponID     int64        `json:"coupon_id"`
+	Code         string       `json:"code"`
+	Description  string       `json:"description"`
+	DiscountType string       `json:"discount_type"`
+	PercentOff   *float64     `json:"percent_off,omitempty"`
+	AmountOff    *money.Money `json:"amount_off,omitempty"`
+	MinSubtotal  money.Money  `json:"min_subtotal"`
+	CategoryIDs  []int64      `json:"category_ids"`
+	FurnitureIDs []int64      `json:"furniture_ids"`
+	StartsAt     *time.Time   `json:"starts_at"`
+	EndsAt       *time.Time   `json:"ends_at"`
+	UsageLimit   *int         `json:"usage_limit"`
+	PerUserLimit *int         `json:"per_user_limit"`
+	Stackable    bool         `json:"stackable"`
+	CreatedAt    time.Time    `json:"created_at"`
+	Version      int          `json:"version"`
+}
+
+// NormalizeCode returns the code as it is stored, so that the codes
+// entered by the customers are matched regardless of case and spacing.
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func ValidateCoupon(v *validator.Validator, coupon Coupon) {
+	v.Check(coupon.Code != "", "code", "must be provided")
+	v.Check(len(coupon.Code) <= 50, "code", "must not be more than 50 bytes long")
+	v.Check(validator.Matches(coupon.Code, CodeRX), "code", "must only contain letters, digits, hyphens and underscores")
+	v.Check(len(coupon.Description) <= 500, "description", "must not be more than 500 bytes long")
+
+	v.Check(validator.PermittedValue(coupon.DiscountType, DiscountPercentage, DiscountFixed), "discount_type", "must be percentage or fixed")
+	switch coupon.DiscountType {
+	case DiscountPercentage:
+		v.Check(coupon.PercentOff != nil, "percent_off", "must be provided for a percentage")
+		v.Check(coupon.AmountOff == nil, "amount_off", "must only be provided for a fixed discount")
+	case DiscountFixed:
+		v.Check(coupon.AmountOff != nil, "amount_off", "must be provided for a fixed discount")
+		v.Check(coupon.PercentOff == nil, "percent_off", "must only be provided for a percentage")
+	}
+	if coupon.PercentOff != nil {
+		v.Check(*coupon.PercentOff > 0 && *coupon.PercentOff <= 100, "percent_off", "must be more than 0 and at most 100")
+	}
+	if coupon.AmountOff != nil {
+		v.Check(coupon.AmountOff.InCurrency(money.DefaultCurrency), "amount_off", "must be in "+money.DefaultCurrency)
+		v.Check(coupon.AmountOff.IsPositive(), "amount_off", "must be greater than zero")
+	}
+	v.Check(coupon.MinSubtotal.InCurrency(money.DefaultCurrency), "min_subtotal", "must be in "+money.DefaultCurrency)
+	v.Check(!coupon.MinSubtotal.IsNegative(), "min_subtotal", "must not be negative")
+
+	v.Check(len(coupon.CategoryIDs) <= 50, "category_ids", "must not contain more than 50 categories")
+	v.Check(validator.Unique(coupon.CategoryIDs), "category_ids", "must not contain duplicate values")
+	v.Check(len(coupon.FurnitureIDs) <= 200, "furniture_ids", "must not contain more than 200 furniture")
+	v.Check(validator.Unique(coupon.FurnitureIDs), "furniture_ids", "must not contain duplicate values")
+
+	if coupon.StartsAt != nil && coupon.EndsAt != nil {
+		v.Check(coupon.StartsAt.Before(*coupon.EndsAt), "ends_at", "must be after starts_at")
+	}
+
+	if coupon.UsageLimit != nil {
+		v.Check(*coupon.UsageLimit > 0, "usage_limit", "must be greater than zero")
+	}
+	if coupon.PerUserLimit != nil {
+		v.Check(*coupon.PerUserLimit > 0, "per_user_limit", "must be greater than zero")
+	}
+}
@@ -0,0 +1,228 @@
+// Package promotions computes the discounts the coupons give on a cart.
+// It holds no state, the coupons, the cart and how many times the coupons
+// have been used are provided by the caller.
+package promotions
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hayohtee/fumode/internal/money"
+	"slices"
+	"time"
+)
+
+// The reasons a coupon cannot be used.
+var (
+	ErrNotStarted          = errors.New("the coupon is not valid yet")
+	ErrExpired             = errors.New("the coupon has expired")
+	ErrUsageLimitReached   = errors.New("the coupon has been used the maximum number of times")
+	ErrPerUserLimitReached = errors.New("you have used the coupon the maximum number of times")
+	ErrMinSubtotalNotMet   = errors.New("the subtotal of the eligible items is below the minimum")
+	ErrNotApplicable       = errors.New("the coupon does not apply to any item in the cart")
+	ErrNotStackable        = errors.New("the coupon cannot be combined with other coupons")
+)
+
+// Line is a line of the cart the coupons are applied to. CategoryIDs holds
+// the category of the furniture and every ancestor of it.
+type Line struct {
+	FurnitureID int64
+	CategoryIDs []int64
+	Subtotal    money.Money
+}
+
+// Usage is how many times a coupon has been redeemed, by every user and
+// by the user the cart belongs to.
+type Usage struct {
+	Total int
+	User  int
+}
+
+// Discount is a struct that holds the discount a coupon gives on the
+// items it applies to.
+type Discount struct {
+	Code             string      `json:"code"`
+	Description      string      `json:"description"`
+	EligibleSubtotal money.Money `json:"eligible_subtotal"`
+	Amount           money.Money `json:"amount"`
+}
+
+// Rejection is a struct that holds a coupon that cannot be used and why.
+type Rejection struct {
+	Code   string `json:"code"`
+	Reason string `json:"reason"`
+	err    error
+}
+
+// Unwrap returns the reason the coupon was rejected for.
+func (r Rejection) Unwrap() error {
+	return r.err
+}
+
+// Error returns the code of the coupon with the reason.
+func (r Rejection) Error() string {
+	return fmt.Sprintf("%s: %s", r.Code, r.Reason)
+}
+
+// Breakdown is a struct that holds the discounts the coupons give on a
+// cart and its total once discounted. The coupons that cannot be used are
+// listed in Rejected and give no discount. LineDiscounts holds the part of
+// the discount total taken off each line, in the order of the lines.
+type Breakdown struct {
+	Subtotal      money.Money   `json:"subtotal"`
+	Discounts     []Discount    `json:"discounts"`
+	DiscountTotal money.Money   `json:"discount_total"`
+	Total         money.Money   `json:"total"`
+	Rejected      []Rejection   `json:"rejected,omitempty"`
+	LineDiscounts []money.Money `json:"-"`
+}
+
+// Apply computes the discounts the coupons give on the lines, in the
+// currency of the cart, at the time now. The coupons are applied in order,
+// and the discounts never take the total below zero. usage holds how many
+// times each coupon has been used, by coupon id.
+func Apply(coupons []Coupon, lines []Line, usage map[int64]Usage, currency string, now time.Time) Breakdown {
+	breakdown := Breakdown{
+		Subtotal:      money.Zero(currency),
+		Discounts:     []Discount{},
+		DiscountTotal: money.Zero(currency),
+		LineDiscounts: make([]money.Money, len(lines)),
+	}
+	for i, line := range lines {
+		breakdown.Subtotal = breakdown.Subtotal.Add(line.Subtotal)
+		breakdown.LineDiscounts[i] = money.Zero(currency)
+	}
+
+	// A coupon that is not stackable can only be used on its own, which
+	// is checked against the coupons that can be used at all.
+	discounts := make([]Discount, len(coupons))
+	errs := make([]error, len(coupons))
+	valid := 0
+	for i, coupon := range coupons {
+		discounts[i], errs[i] = coupon.discount(lines, usage[coupon.CouponID], now)
+		if errs[i] == nil {
+			valid++
+		}
+	}
+
+	for i, coupon := range coupons {
+		discount, err := discounts[i], errs[i]
+		if err == nil && valid > 1 && !coupon.Stackable {
+			err = ErrNotStackable
+		}
+		if err != nil {
+			breakdown.Rejected = append(breakdown.Rejected, Rejection{Code: coupon.Code, Reason: err.Error(), err: err})
+			continue
+		}
+
+		// The discount is split between the lines it applies to in
+		// proportion to what is left of them, so that no line is
+		// discounted below zero.
+		weights := make([]int64, len(lines))
+		remaining := money.Zero(currency)
+		for i, line := range lines {
+			if coupon.appliesTo(line) {
+				left := line.Subtotal.Sub(breakdown.LineDiscounts[i])
+				weights[i] = left.Amount
+				remaining = remaining.Add(left)
+			}
+		}
+
+		discount.Amount = discount.Amount.Min(remaining)
+		for i, share := range discount.Amount.Allocate(weights) {
+			breakdown.LineDiscounts[i] = breakdown.LineDiscounts[i].Add(share)
+		}
+
+		breakdown.Discounts = append(breakdown.Discounts, discount)
+		breakdown.DiscountTotal = breakdown.DiscountTotal.Add(discount.Amount)
+	}
+
+	breakdown.Total = breakdown.Subtotal.Sub(breakdown.DiscountTotal)
+	return breakdown
+}
+
+// Discount returns the discount given by the coupon with the code, which
+// is zero when it was rejected.
+func (b Breakdown) Discount(code string) Discount {
+	for _, discount := range b.Discounts {
+		if discount.Code == code {
+			return discount
+		}
+	}
+	return Discount{Code: code, Amount: money.Zero(b.Total.Currency)}
+}
+
+// Err returns the rejection of the coupon with the code, or nil when it
+// was not rejected.
+func (b Breakdown) Err(code string) error {
+	for _, rejection := range b.Rejected {
+		if rejection.Code == code {
+			return rejection
+		}
+	}
+	return nil
+}
+
+// discount computes the discount the coupon gives on the lines. It
+// returns the reason when the coupon cannot be used.
+func (c Coupon) discount(lines []Line, usage Usage, now time.Time) (Discount, error) {
+	switch {
+	case c.StartsAt != nil && now.Before(*c.StartsAt):
+		return Discount{}, ErrNotStarted
+	case c.EndsAt != nil && !now.Before(*c.EndsAt):
+		return Discount{}, ErrExpired
+	case c.UsageLimit != nil && usage.Total >= *c.UsageLimit:
+		return Discount{}, ErrUsageLimitReached
+	case c.PerUserLimit != nil && usage.User >= *c.PerUserLimit:
+		return Discount{}, ErrPerUserLimitReached
+	}
+
+	var eligible money.Money
+	matched := false
+	for _, line := range lines {
+		if c.appliesTo(line) {
+			eligible = eligible.Add(line.Subtotal)
+			matched = true
+		}
+	}
+
+	switch {
+	case !matched:
+		return Discount{}, ErrNotApplicable
+	case eligible.Cmp(c.MinSubtotal) < 0:
+		return Discount{}, ErrMinSubtotalNotMet
+	}
+
+	// The percentage is taken of the eligible subtotal as a whole, rather
+	// than of each line, so that it is only rounded once.
+	var amount money.Money
+	switch {
+	case c.PercentOff != nil:
+		amount = eligible.Percent(*c.PercentOff)
+	case c.AmountOff != nil:
+		amount = *c.AmountOff
+	}
+
+	return Discount{
+		Code:             c.Code,
+		Description:      c.Description,
+		EligibleSubtotal: eligible,
+		Amount:           amount.Min(eligible),
+	}, nil
+}
+
+// appliesTo reports whether the coupon discounts the line.
+func (c Coupon) appliesTo(line Line) bool {
+	if len(c.CategoryIDs) == 0 && len(c.FurnitureIDs) == 0 {
+		return true
+	}
+
+	if slices.Contains(c.FurnitureIDs, line.FurnitureID) {
+		return true
+	}
+	for _, categoryID := range line.CategoryIDs {
+		if slices.Contains(c.CategoryIDs, categoryID) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,217 @@
+package promotions
+
+import (
+	"errors"
+	"github.com/hayohtee/fumode/internal/money"
+	"testing"
+	"time"
+)
+
+func percentOff(code string, percent float64) Coupon {
+	return Coupon{Code: code, DiscountType: DiscountPercentage, PercentOff: &percent, Stackable: true}
+}
+
+func amountOff(code string, amount int64) Coupon {
+	off := money.New(amount, "USD")
+	return Coupon{Code: code, DiscountType: DiscountFixed, AmountOff: &off, Stackable: true}
+}
+
+func TestApply(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	yesterday, tomorrow := now.AddDate(0, 0, -1), now.AddDate(0, 0, 1)
+	one := 1
+
+	lines := []Line{
+		{FurnitureID: 1, CategoryIDs: []int64{10, 1}, Subtotal: money.New(10000, "USD")},
+		{FurnitureID: 2, CategoryIDs: []int64{20, 2}, Subtotal: money.New(5000, "USD")},
+	}
+
+	tests := []struct {
+		name          string
+		coupons       []Coupon
+		usage         map[int64]Usage
+		wantDiscounts map[string]int64
+		wantRejected  map[string]error
+		wantLines     []int64
+	}{
+		{
+			name:          "no coupon",
+			wantDiscounts: map[string]int64{},
+			wantLines:     []int64{0, 0},
+		},
+		{
+			name:          "percentage of the whole cart",
+			coupons:       []Coupon{percentOff("TEN", 10)},
+			wantDiscounts: map[string]int64{"TEN": 1500},
+			wantLines:     []int64{1000, 500},
+		},
+		{
+			name:          "fixed split in proportion",
+			coupons:       []Coupon{amountOff("FIVE", 1000)},
+			wantDiscounts: map[string]int64{"FIVE": 1000},
+			wantLines:     []int64{667, 333},
+		},
+		{
+			name:          "fixed capped at the eligible subtotal",
+			coupons:       []Coupon{func() Coupon { c := amountOff("BIG", 100000); c.FurnitureIDs = []int64{2}; return c }()},
+			wantDiscounts: map[string]int64{"BIG": 5000},
+			wantLines:     []int64{0, 5000},
+		},
+		{
+			name:          "scoped to an ancestor category",
+			coupons:       []Coupon{func() Coupon { c := percentOff("CAT", 20); c.CategoryIDs = []int64{1}; return c }()},
+			wantDiscounts: map[string]int64{"CAT": 2000},
+			wantLines:     []int64{2000, 0},
+		},
+		{
+			name:         "not applicable",
+			coupons:      []Coupon{func() Coupon { c := percentOff("NONE", 20); c.FurnitureIDs = []int64{3}; return c }()},
+			wantRejected: map[string]error{"NONE": ErrNotApplicable},
+			wantLines:    []int64{0, 0},
+		},
+		{
+			name:         "minimum subtotal not met",
+			coupons:      []Coupon{func() Coupon { c := percentOff("MIN", 20); c.MinSubtotal = money.New(20000, "USD"); return c }()},
+			wantRejected: map[string]error{"MIN": ErrMinSubtotalNotMet},
+			wantLines:    []int64{0, 0},
+		},
+		{
+			name:         "not started",
+			coupons:      []Coupon{func() Coupon { c := percentOff("SOON", 20); c.StartsAt = &tomorrow; return c }()},
+			wantRejected: map[string]error{"SOON": ErrNotStarted},
+			wantLines:    []int64{0, 0},
+		},
+		{
+			name:         "expired",
+			coupons:      []Coupon{func() Coupon { c := percentOff("OLD", 20); c.EndsAt = &yesterday; return c }()},
+			wantRejected: map[string]error{"OLD": ErrExpired},
+			wantLines:    []int64{0, 0},
+		},
+		{
+			name:         "ends now",
+			coupons:      []Coupon{func() Coupon { c := percentOff("NOW", 20); c.EndsAt = &now; return c }()},
+			wantRejected: map[string]error{"NOW": ErrExpired},
+			wantLines:    []int64{0, 0},
+		},
+		{
+			name: "usage limit reached",
+			coupons: []Coupon{func() Coupon {
+				c := percentOff("ONCE", 20)
+				c.CouponID, c.UsageLimit = 7, &one
+				return c
+			}()},
+			usage:        map[int64]Usage{7: {Total: 1}},
+			wantRejected: map[string]error{"ONCE": ErrUsageLimitReached},
+			wantLines:    []int64{0, 0},
+		},
+		{
+			name: "per user limit reached",
+			coupons: []Coupon{func() Coupon {
+				c := percentOff("MINE", 20)
+				c.CouponID, c.PerUserLimit = 8, &one
+				return c
+			}()},
+			usage:        map[int64]Usage{8: {Total: 3, User: 1}},
+			wantRejected: map[string]error{"MINE": ErrPerUserLimitReached},
+			wantLines:    []int64{0, 0},
+		},
+		{
+			name:          "stacked in order",
+			coupons:       []Coupon{percentOff("TEN", 10), amountOff("FIVE", 500)},
+			wantDiscounts: map[string]int64{"TEN": 1500, "FIVE": 500},
+			wantLines:     []int64{1333, 667},
+		},
+		{
+			name:          "stacked never below zero",
+			coupons:       []Coupon{percentOff("HALF", 50), amountOff("HUGE", 100000)},
+			wantDiscounts: map[string]int64{"HALF": 7500, "HUGE": 7500},
+			wantLines:     []int64{10000, 5000},
+		},
+		{
+			name:          "not stackable alone",
+			coupons:       []Coupon{func() Coupon { c := percentOff("SOLO", 10); c.Stackable = false; return c }()},
+			wantDiscounts: map[string]int64{"SOLO": 1500},
+			wantLines:     []int64{1000, 500},
+		},
+		{
+			name: "not stackable with another usable coupon",
+			coupons: []Coupon{
+				func() Coupon { c := percentOff("SOLO", 10); c.Stackable = false; return c }(),
+				amountOff("FIVE", 500),
+			},
+			wantDiscounts: map[string]int64{"FIVE": 500},
+			wantRejected:  map[string]error{"SOLO": ErrNotStackable},
+			wantLines:     []int64{333, 167},
+		},
+		{
+			name: "not stackable with a rejected coupon",
+			coupons: []Coupon{
+				func() Coupon { c := percentOff("SOLO", 10); c.Stackable = false; return c }(),
+				func() Coupon { c := amountOff("OLD", 500); c.EndsAt = &yesterday; return c }(),
+			},
+			wantDiscounts: map[string]int64{"SOLO": 1500},
+			wantRejected:  map[string]error{"OLD": ErrExpired},
+			wantLines:     []int64{1000, 500},
+		},
+		{
+			name: "two not stackable",
+			coupons: []Coupon{
+				func() Coupon { c := percentOff("A", 10); c.Stackable = false; return c }(),
+				func() Coupon { c := percentOff("B", 10); c.Stackable = false; return c }(),
+			},
+			wantDiscounts: map[string]int64{},
+			wantRejected:  map[string]error{"A": ErrNotStackable, "B": ErrNotStackable},
+			wantLines:     []int64{0, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := Apply(tt.coupons, lines, tt.usage, "USD", now)
+
+			if b.Subtotal != money.New(15000, "USD") {
+				t.Errorf("Subtotal = %+v, want %+v", b.Subtotal, money.New(15000, "USD"))
+			}
+
+			if len(b.Discounts) != len(tt.wantDiscounts) {
+				t.Errorf("got %d discounts, want %d", len(b.Discounts), len(tt.wantDiscounts))
+			}
+			total := money.New(0, "USD")
+			for code, want := range tt.wantDiscounts {
+				if got := b.Discount(code).Amount; got != money.New(want, "USD") {
+					t.Errorf("Discount(%q) = %+v, want %+v", code, got, money.New(want, "USD"))
+				}
+				total = total.Add(money.New(want, "USD"))
+			}
+			if b.DiscountTotal != total {
+				t.Errorf("DiscountTotal = %+v, want %+v", b.DiscountTotal, total)
+			}
+			if b.Total != money.New(15000, "USD").Sub(total) {
+				t.Errorf("Total = %+v, want %+v", b.Total, money.New(15000, "USD").Sub(total))
+			}
+
+			if len(b.Rejected) != len(tt.wantRejected) {
+				t.Errorf("got %d rejections, want %d", len(b.Rejected), len(tt.wantRejected))
+			}
+			for code, want := range tt.wantRejected {
+				if err := b.Err(code); !errors.Is(err, want) {
+					t.Errorf("Err(%q) = %v, want %v", code, err, want)
+				}
+				if got := b.Discount(code).Amount; !got.IsZero() {
+					t.Errorf("Discount(%q) of a rejected coupon = %+v, want zero", code, got)
+				}
+			}
+
+			sum := money.New(0, "USD")
+			for i, want := range tt.wantLines {
+				if b.LineDiscounts[i] != money.New(want, "USD") {
+					t.Errorf("LineDiscounts[%d] = %+v, want %+v", i, b.LineDiscounts[i], money.New(want, "USD"))
+				}
+				sum = sum.Add(b.LineDiscounts[i])
+			}
+			if sum != b.DiscountTotal {
+				t.Errorf("line discounts add up to %+v, want %+v", sum, b.DiscountTotal)
+			}
+		})
+	}
+}
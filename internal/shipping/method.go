@@ -0,0 +1,144 @@
+package shipping
+
+import (
+	"github.com/hayohtee/fumode/internal/money"
+	"github.com/hayohtee/fumode/internal/validator"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// The kinds of shipping method. Standard and white-glove deliveries are
+// priced by the rate tables of the destination zone, while click-and-collect
+// orders are picked up at the warehouse for free.
+const (
+	KindStandard        = "standard"
+	KindWhiteGlove      = "white_glove"
+	KindClickAndCollect = "click_and_collect"
+)
+
+// Kinds holds every kind of shipping method.
+var Kinds = []string{KindStandard, KindWhiteGlove, KindClickAndCollect}
+
+// NeedsDeliverySlot reports whether the deliveries of a kind of shipping
+// method are made on a booked delivery slot, which is the case of the
+// white-glove deliveries made by a two-person crew.
+func NeedsDeliverySlot(kind string) bool {
+	return kind == KindWhiteGlove
+}
+
+// DefaultVolumetricDivisor is the number of cubic centimeters counted as
+// one kilogram of volumetric weight, when the method does not set its own.
+const DefaultVolumetricDivisor = 5000
+
+// CodeRX is a regular expression pattern for the shipping method and zone
+// codes, made of lowercase letters, digits, hyphens and underscores.
+var CodeRX = regexp.MustCompile("^[a-z0-9_-]+$")
+
+// Method is a struct that holds a shipping method and its rate tables.
+// The items are charged by the greater of their actual and volumetric
+// weight, the volume in cubic centimeters divided by VolumetricDivisor.
+// Shipping is free once the discounted subtotal of the cart reaches
+// FreeShippingThreshold, when it is set. Inactive methods are not offered.
+type Method struct {
+	MethodID              int64        `json:"method_id"`
+	Code                  string       `json:"code"`
+	Name                  string       `json:"name"`
+	Kind                  string       `json:"kind"`
+	VolumetricDivisor     int          `json:"volumetric_divisor"`
+	FreeShippingThreshold *money.Money `json:"free_shipping_threshold"`
+	Active                bool         `json:"active"`
+	Rates                 []Rate       `json:"rates"`
+	CreatedAt             time.Time    `json:"created_at"`
+	Version               int          `json:"version"`
+}
+
+// Rate is a bracket of the rate table of a method in a zone: the price of
+// shipping up to MaxWeightKg of chargeable weight, or any weight above the
+// other brackets when it is nil.
+type Rate struct {
+	ZoneID      int64       `json:"zone_id"`
+	MaxWeightKg *float64    `json:"max_weight_kg"`
+	Price       money.Money `json:"price"`
+}
+
+// Zone is a struct that holds a destination zone, made of the countries,
+// or states of them, listed in Regions. A region belongs to one zone only.
+type Zone struct {
+	ZoneID    int64     `json:"zone_id"`
+	Code      string    `json:"code"`
+	Name      string    `json:"name"`
+	Regions   []Region  `json:"regions"`
+	CreatedAt time.Time `json:"created_at"`
+	Version   int       `json:"version"`
+}
+
+// Region is a country, or only a state of it when State is set. They are
+// matched against the shipping address ignoring case.
+type Region struct {
+	Country string `json:"country"`
+	State   string `json:"state"`
+}
+
+func ValidateMethod(v *validator.Validator, method Method) {
+	v.Check(method.Code != "", "code", "must be provided")
+	v.Check(len(method.Code) <= 50, "code", "must not be more than 50 bytes long")
+	v.Check(validator.Matches(method.Code, CodeRX), "code", "must only contain lowercase letters, digits, hyphens and underscores")
+	v.Check(method.Name != "", "name", "must be provided")
+	v.Check(len(method.Name) <= 100, "name", "must not be more than 100 bytes long")
+
+	v.Check(validator.PermittedValue(method.Kind, Kinds...), "kind", "must be standard, white_glove or click_and_collect")
+	v.Check(validator.Between(method.VolumetricDivisor, 1_000, 10_000), "volumetric_divisor", "must be between 1000 and 10000")
+
+	if method.FreeShippingThreshold != nil {
+		v.Check(method.FreeShippingThreshold.InCurrency(money.DefaultCurrency), "free_shipping_threshold", "must be in "+money.DefaultCurrency)
+		v.Check(!method.FreeShippingThreshold.IsNegative(), "free_shipping_threshold", "must not be negative")
+	}
+
+	if method.Kind == KindClickAndCollect {
+		v.Check(len(method.Rates) == 0, "rates", "must not be provided for click_and_collect")
+	}
+	v.Check(len(method.Rates) <= 500, "rates", "must not contain more than 500 rates")
+
+	type bracket struct {
+		zoneID int64
+		max    float64
+	}
+	seen := make(map[bracket]bool)
+	for _, rate := range method.Rates {
+		v.Check(rate.ZoneID > 0, "rates", "must only contain rates with a zone_id")
+		if rate.MaxWeightKg != nil {
+			v.Check(validator.Between(*rate.MaxWeightKg, 0.01, 100_000), "rates", "must only contain a max_weight_kg between 0.01 and 100000")
+		}
+		v.Check(rate.Price.InCurrency(money.DefaultCurrency), "rates", "must only contain prices in "+money.DefaultCurrency)
+		v.Check(!rate.Price.IsNegative(), "rates", "must not contain negative prices")
+
+		b := bracket{zoneID: rate.ZoneID, max: -1}
+		if rate.MaxWeightKg != nil {
+			b.max = *rate.MaxWeightKg
+		}
+		v.Check(!seen[b], "rates", "must not contain the same max_weight_kg twice in a zone")
+		seen[b] = true
+	}
+}
+
+func ValidateZone(v *validator.Validator, zone Zone) {
+	v.Check(zone.Code != "", "code", "must be provided")
+	v.Check(len(zone.Code) <= 50, "code", "must not be more than 50 bytes long")
+	v.Check(validator.Matches(zone.Code, CodeRX), "code", "must only contain lowercase letters, digits, hyphens and underscores")
+	v.Check(zone.Name != "", "name", "must be provided")
+	v.Check(len(zone.Name) <= 100, "name", "must not be more than 100 bytes long")
+
+	v.Check(len(zone.Regions) > 0, "regions", "must contain at least one region")
+	v.Check(len(zone.Regions) <= 500, "regions", "must not contain more than 500 regions")
+
+	keys := make([]string, 0, len(zone.Regions))
+	for _, region := range zone.Regions {
+		v.Check(strings.TrimSpace(region.Country) != "", "regions", "must only contain regions with a country")
+		v.Check(len(region.Country) <= 100 && len(region.State) <= 100, "regions", "must not contain a country or state more than 100 bytes long")
+		keys = append(keys, strings.ToUpper(strings.TrimSpace(region.Country))+"\x00"+strings.ToUpper(strings.TrimSpace(region.State)))
+	}
+	slices.Sort(keys)
+	v.Check(len(slices.Compact(keys)) == len(zone.Regions), "regions", "must not contain duplicate regions")
+}
@@ -0,0 +1,127 @@
+// Package shipping computes what the shipping methods cost for the items
+// of a cart sent to a destination zone. Like the promotions and tax
+// packages, it holds no state, the methods, their rates and the zone are
+// provided by the caller.
+package shipping
+
+import (
+	"cmp"
+	"github.com/hayohtee/fumode/internal/money"
+	"math"
+	"slices"
+)
+
+// Item is a line of the cart that is shipped. The dimensions are in
+// centimeters and the weight in kilograms, they are nil when unknown and
+// the item is then charged by what is known of it.
+type Item struct {
+	Quantity int
+	WidthCm  *float64
+	DepthCm  *float64
+	HeightCm *float64
+	WeightKg *float64
+}
+
+// Option is a shipping method offered for a cart, with what it costs.
+// ChargeableWeightKg is the weight the cost was computed from, and
+// FreeShipping reports whether the cost was waived by the free shipping
+// threshold of the method.
+type Option struct {
+	MethodID           int64       `json:"method_id"`
+	Code               string      `json:"code"`
+	Name               string      `json:"name"`
+	Kind               string      `json:"kind"`
+	ChargeableWeightKg float64     `json:"chargeable_weight_kg"`
+	Cost               money.Money `json:"cost"`
+	FreeShipping       bool        `json:"free_shipping"`
+}
+
+// ChargeableWeight returns the weight the items are charged by, each of
+// them counting for the greater of its actual weight and of its volume
+// divided by the volumetric divisor. It is rounded up to the next tenth
+// of a kilogram, as carriers do.
+func ChargeableWeight(items []Item, volumetricDivisor int) float64 {
+	total := 0.0
+	for _, item := range items {
+		weight := 0.0
+		if item.WeightKg != nil {
+			weight = *item.WeightKg
+		}
+		if item.WidthCm != nil && item.DepthCm != nil && item.HeightCm != nil {
+			volumetric := *item.WidthCm * *item.DepthCm * *item.HeightCm / float64(volumetricDivisor)
+			weight = max(weight, volumetric)
+		}
+		total += weight * float64(item.Quantity)
+	}
+	return math.Ceil(math.Round(total*1000)/100) / 10
+}
+
+// Quote returns the options of the active methods for the items shipped
+// to the zone, from the cheapest. zoneID is nil when the destination is in
+// no zone, in which case only click-and-collect is offered. A delivery
+// method is only offered when its rate table for the zone has a bracket
+// for the chargeable weight, and it is free once subtotal reaches its
+// free shipping threshold.
+func Quote(methods []Method, zoneID *int64, items []Item, subtotal money.Money) []Option {
+	options := []Option{}
+	for _, method := range methods {
+		if !method.Active {
+			continue
+		}
+
+		option := Option{
+			MethodID:           method.MethodID,
+			Code:               method.Code,
+			Name:               method.Name,
+			Kind:               method.Kind,
+			ChargeableWeightKg: ChargeableWeight(items, method.VolumetricDivisor),
+			Cost:               money.Zero(subtotal.Currency),
+		}
+
+		if method.Kind != KindClickAndCollect {
+			if zoneID == nil {
+				continue
+			}
+			rate, ok := method.rate(*zoneID, option.ChargeableWeightKg)
+			if !ok {
+				continue
+			}
+			option.Cost = rate.Price
+		}
+
+		threshold := method.FreeShippingThreshold
+		if threshold != nil && option.Cost.IsPositive() && subtotal.Cmp(*threshold) >= 0 {
+			option.Cost = money.Zero(subtotal.Currency)
+			option.FreeShipping = true
+		}
+
+		options = append(options, option)
+	}
+
+	slices.SortStableFunc(options, func(a, b Option) int {
+		return cmp.Or(a.Cost.Cmp(b.Cost), cmp.Compare(a.MethodID, b.MethodID))
+	})
+	return options
+}
+
+// rate returns the smallest bracket of the rate table of the zone which
+// covers the weight.
+func (m Method) rate(zoneID int64, weightKg float64) (Rate, bool) {
+	var match Rate
+	found := false
+	for _, rate := range m.Rates {
+		if rate.ZoneID != zoneID || (rate.MaxWeightKg != nil && *rate.MaxWeightKg < weightKg) {
+			continue
+		}
+
+		switch {
+		case !found:
+			match, found = rate, true
+		case match.MaxWeightKg == nil:
+			match = rate
+		case rate.MaxWeightKg != nil && *rate.MaxWeightKg < *match.MaxWeightKg:
+			match = rate
+		}
+	}
+	return match, found
+}
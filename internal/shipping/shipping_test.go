@@ -0,0 +1,205 @@
+package shipping
+
+import (
+	"github.com/hayohtee/fumode/internal/money"
+	"testing"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestChargeableWeight(t *testing.T) {
+	tests := []struct {
+		name    string
+		items   []Item
+		divisor int
+		want    float64
+	}{
+		{"actual weight", []Item{{Quantity: 1, WeightKg: ptr(12.3), WidthCm: ptr(10.0), DepthCm: ptr(10.0), HeightCm: ptr(10.0)}}, 5000, 12.3},
+		{"volumetric weight", []Item{{Quantity: 1, WeightKg: ptr(2.0), WidthCm: ptr(100.0), DepthCm: ptr(50.0), HeightCm: ptr(40.0)}}, 5000, 40},
+		{"own divisor", []Item{{Quantity: 1, WeightKg: ptr(2.0), WidthCm: ptr(100.0), DepthCm: ptr(50.0), HeightCm: ptr(40.0)}}, 4000, 50},
+		{"quantity", []Item{{Quantity: 3, WeightKg: ptr(1.5)}}, 5000, 4.5},
+		{"several items", []Item{{Quantity: 1, WeightKg: ptr(1.0)}, {Quantity: 2, WeightKg: ptr(0.25)}}, 5000, 1.5},
+		{"rounded up to a tenth", []Item{{Quantity: 1, WeightKg: ptr(12.31)}}, 5000, 12.4},
+		{"float error not rounded up", []Item{{Quantity: 3, WeightKg: ptr(0.1)}}, 5000, 0.3},
+		{"missing dimension", []Item{{Quantity: 1, WeightKg: ptr(2.0), WidthCm: ptr(100.0), DepthCm: ptr(50.0)}}, 5000, 2},
+		{"unknown weight", []Item{{Quantity: 1, WidthCm: ptr(50.0), DepthCm: ptr(50.0), HeightCm: ptr(20.0)}}, 5000, 10},
+		{"nothing known", []Item{{Quantity: 2}}, 5000, 0},
+		{"no items", nil, 5000, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ChargeableWeight(tt.items, tt.divisor); got != tt.want {
+				t.Errorf("ChargeableWeight() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuote(t *testing.T) {
+	const zone, otherZone = 1, 2
+
+	standard := Method{
+		MethodID:          1,
+		Code:              "standard",
+		Name:              "Standard",
+		Kind:              KindStandard,
+		VolumetricDivisor: DefaultVolumetricDivisor,
+		Active:            true,
+		Rates: []Rate{
+			{ZoneID: zone, MaxWeightKg: nil, Price: money.New(9900, "USD")},
+			{ZoneID: zone, MaxWeightKg: ptr(30.0), Price: money.New(2900, "USD")},
+			{ZoneID: zone, MaxWeightKg: ptr(10.0), Price: money.New(1500, "USD")},
+			{ZoneID: otherZone, MaxWeightKg: ptr(10.0), Price: money.New(500, "USD")},
+		},
+	}
+
+	whiteGlove := Method{
+		MethodID:              2,
+		Code:                  "white-glove",
+		Name:                  "White glove",
+		Kind:                  KindWhiteGlove,
+		VolumetricDivisor:     DefaultVolumetricDivisor,
+		FreeShippingThreshold: ptr(money.New(200000, "USD")),
+		Active:                true,
+		Rates: []Rate{
+			{ZoneID: zone, MaxWeightKg: ptr(50.0), Price: money.New(7900, "USD")},
+		},
+	}
+
+	collect := Method{
+		MethodID:          3,
+		Code:              "collect",
+		Name:              "Click and collect",
+		Kind:              KindClickAndCollect,
+		VolumetricDivisor: DefaultVolumetricDivisor,
+		Active:            true,
+	}
+
+	inactive := standard
+	inactive.MethodID = 4
+	inactive.Active = false
+
+	freeStandard := standard
+	freeStandard.MethodID = 5
+	freeStandard.FreeShippingThreshold = ptr(money.New(10000, "USD"))
+
+	methods := []Method{whiteGlove, standard, collect, inactive}
+
+	type option struct {
+		methodID int64
+		cost     int64
+		free     bool
+	}
+
+	tests := []struct {
+		name     string
+		methods  []Method
+		zoneID   *int64
+		weight   float64
+		subtotal int64
+		want     []option
+	}{
+		{
+			name:     "smallest bracket covering the weight",
+			methods:  methods,
+			zoneID:   ptr(int64(zone)),
+			weight:   8,
+			subtotal: 50000,
+			want:     []option{{3, 0, false}, {1, 1500, false}, {2, 7900, false}},
+		},
+		{
+			name:     "bracket boundary included",
+			methods:  []Method{standard},
+			zoneID:   ptr(int64(zone)),
+			weight:   10,
+			subtotal: 50000,
+			want:     []option{{1, 1500, false}},
+		},
+		{
+			name:     "next bracket",
+			methods:  []Method{standard},
+			zoneID:   ptr(int64(zone)),
+			weight:   10.1,
+			subtotal: 50000,
+			want:     []option{{1, 2900, false}},
+		},
+		{
+			name:     "open bracket above the others",
+			methods:  methods,
+			zoneID:   ptr(int64(zone)),
+			weight:   80,
+			subtotal: 50000,
+			want:     []option{{3, 0, false}, {1, 9900, false}},
+		},
+		{
+			name:     "rates of the zone only",
+			methods:  []Method{standard},
+			zoneID:   ptr(int64(otherZone)),
+			weight:   8,
+			subtotal: 50000,
+			want:     []option{{1, 500, false}},
+		},
+		{
+			name:     "no bracket in the zone",
+			methods:  []Method{standard},
+			zoneID:   ptr(int64(otherZone)),
+			weight:   12,
+			subtotal: 50000,
+			want:     []option{},
+		},
+		{
+			name:     "outside every zone",
+			methods:  methods,
+			zoneID:   nil,
+			weight:   8,
+			subtotal: 50000,
+			want:     []option{{3, 0, false}},
+		},
+		{
+			name:     "free shipping threshold reached",
+			methods:  methods,
+			zoneID:   ptr(int64(zone)),
+			weight:   8,
+			subtotal: 200000,
+			want:     []option{{2, 0, true}, {3, 0, false}, {1, 1500, false}},
+		},
+		{
+			name:     "free shipping threshold not reached",
+			methods:  []Method{freeStandard},
+			zoneID:   ptr(int64(zone)),
+			weight:   8,
+			subtotal: 9999,
+			want:     []option{{5, 1500, false}},
+		},
+		{
+			name:     "ties ordered by method",
+			methods:  []Method{freeStandard, standard},
+			zoneID:   ptr(int64(otherZone)),
+			weight:   8,
+			subtotal: 0,
+			want:     []option{{1, 500, false}, {5, 500, false}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := []Item{{Quantity: 1, WeightKg: ptr(tt.weight)}}
+			got := Quote(tt.methods, tt.zoneID, items, money.New(tt.subtotal, "USD"))
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d options %+v, want %d", len(got), got, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if got[i].MethodID != want.methodID || got[i].Cost != money.New(want.cost, "USD") || got[i].FreeShipping != want.free {
+					t.Errorf("option %d = %+v, want method %d costing %d, free %v", i, got[i], want.methodID, want.cost, want.free)
+				}
+				if got[i].ChargeableWeightKg != tt.weight {
+					t.Errorf("option %d charged by %v kg, want %v", i, got[i].ChargeableWeightKg, tt.weight)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package tax
+
+import (
+	"github.com/hayohtee/fumode/internal/validator"
+	"math"
+	"strings"
+	"time"
+)
+
+// The tax classes of the furniture, set on their category. Standard items
+// are taxed at the standard rate of the region, reduced items at its
+// reduced rate, which falls back to the standard rate when the region has
+// none, and zero rated items are never taxed.
+const (
+	ClassStandard = "standard"
+	ClassReduced  = "reduced"
+	ClassZero     = "zero"
+)
+
+// Classes holds every tax class.
+var Classes = []string{ClassStandard, ClassReduced, ClassZero}
+
+// Rate is a struct that holds the percentage a tax class is taxed at in a
+// country, or only in a state of it when State is set. The country and the
+// state are matched against the shipping address ignoring case.
+type Rate struct {
+	RateID    int64     `json:"rate_id"`
+	Country   string    `json:"country"`
+	State     string    `json:"state"`
+	TaxClass  string    `json:"tax_class"`
+	Percent   float64   `json:"percent"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	Version   int       `json:"version"`
+}
+
+func ValidateRate(v *validator.Validator, rate Rate) {
+	v.Check(strings.TrimSpace(rate.Country) != "", "country", "must be provided")
+	v.Check(len(rate.Country) <= 100, "country", "must not be more than 100 bytes long")
+	v.Check(len(rate.State) <= 100, "state", "must not be more than 100 bytes long")
+
+	v.Check(validator.PermittedValue(rate.TaxClass, ClassStandard, ClassReduced), "tax_class", "must be standard or reduced")
+	v.Check(validator.Between(rate.Percent, 0, 100), "percent", "must be between 0 and 100")
+	v.Check(rate.Percent == math.Round(rate.Percent*100)/100, "percent", "must not have more than 2 decimal places")
+
+	v.Check(len(rate.Name) <= 100, "name", "must not be more than 100 bytes long")
+}
@@ -0,0 +1,108 @@
+// Package tax computes the tax due on the lines of an order from the tax
+// rates of the region it is shipped to. Like the promotions package, it
+// holds no state, the rates are provided by the caller.
+//
+// Prices are either exclusive of tax, the tax being added on top of them,
+// or inclusive of it, the tax being the part of them above the price
+// before tax. The tax of each line is rounded half away from zero to the
+// minor unit, and the tax of the order is the sum of the tax of its lines.
+package tax
+
+import (
+	"github.com/hayohtee/fumode/internal/money"
+	"strings"
+)
+
+// Region is the country, and state, the tax is due in.
+type Region struct {
+	Country string
+	State   string
+}
+
+// Line is a line of an order the tax is computed on. Amount is what is
+// charged for the line, once discounted.
+type Line struct {
+	TaxClass string
+	Amount   money.Money
+}
+
+// LineTax is the tax due on a line and the percentage it was computed at.
+type LineTax struct {
+	Percent float64
+	Amount  money.Money
+}
+
+// Result holds the tax due on every line, in the order of the lines, and
+// their sum.
+type Result struct {
+	Lines []LineTax
+	Total money.Money
+}
+
+// Calculate computes the tax due on the lines in the region, from the
+// rates which apply to it. The prices include the tax when inclusive is
+// set, and it is added on top of them otherwise.
+func Calculate(rates []Rate, region Region, lines []Line, currency string, inclusive bool) Result {
+	result := Result{
+		Lines: make([]LineTax, len(lines)),
+		Total: money.Zero(currency),
+	}
+
+	for i, line := range lines {
+		percent := Lookup(rates, region, line.TaxClass)
+
+		amount := line.Amount.Percent(percent)
+		if inclusive {
+			amount = line.Amount.PercentIncluded(percent)
+		}
+
+		result.Lines[i] = LineTax{Percent: percent, Amount: amount}
+		result.Total = result.Total.Add(amount)
+	}
+	return result
+}
+
+// Lookup returns the percentage the tax class is taxed at in the region.
+// A rate for the state takes precedence over one for the whole country,
+// and the reduced class falls back to the standard rate. It returns zero
+// when no rate applies, or for the zero rated class.
+func Lookup(rates []Rate, region Region, class string) float64 {
+	if class == ClassZero {
+		return 0
+	}
+
+	if rate, ok := lookup(rates, region, class); ok {
+		return rate.Percent
+	}
+	if class != ClassStandard {
+		if rate, ok := lookup(rates, region, ClassStandard); ok {
+			return rate.Percent
+		}
+	}
+	return 0
+}
+
+// lookup returns the most specific rate of the tax class in the region.
+func lookup(rates []Rate, region Region, class string) (Rate, bool) {
+	var match Rate
+	found := false
+	for _, rate := range rates {
+		if rate.TaxClass != class || !matches(rate.Country, region.Country) {
+			continue
+		}
+
+		switch {
+		case rate.State != "" && matches(rate.State, region.State):
+			return rate, true
+		case rate.State == "":
+			match, found = rate, true
+		}
+	}
+	return match, found
+}
+
+// matches reports whether the names are the same, ignoring case and the
+// surrounding spaces.
+func matches(a, b string) bool {
+	return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+}
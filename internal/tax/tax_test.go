@@ -0,0 +1,132 @@
+package tax
+
+import (
+	"github.com/hayohtee/fumode/internal/money"
+	"testing"
+)
+
+var rates = []Rate{
+	{Country: "United States", TaxClass: ClassStandard, Percent: 5},
+	{Country: "United States", State: "California", TaxClass: ClassStandard, Percent: 7.25},
+	{Country: "Germany", TaxClass: ClassStandard, Percent: 19},
+	{Country: "Germany", TaxClass: ClassReduced, Percent: 7},
+	{Country: "France", State: "Corsica", TaxClass: ClassStandard, Percent: 10},
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name   string
+		region Region
+		class  string
+		want   float64
+	}{
+		{"country rate", Region{Country: "United States", State: "Texas"}, ClassStandard, 5},
+		{"state rate over country rate", Region{Country: "United States", State: "California"}, ClassStandard, 7.25},
+		{"names ignore case and spaces", Region{Country: " united states ", State: "CALIFORNIA"}, ClassStandard, 7.25},
+		{"reduced rate", Region{Country: "Germany"}, ClassReduced, 7},
+		{"reduced falls back to standard", Region{Country: "United States", State: "California"}, ClassReduced, 7.25},
+		{"zero rated", Region{Country: "Germany"}, ClassZero, 0},
+		{"no rate for the country", Region{Country: "Japan"}, ClassStandard, 0},
+		{"state rate only applies to its state", Region{Country: "France", State: "Brittany"}, ClassStandard, 0},
+		{"state rate without country rate", Region{Country: "France", State: "Corsica"}, ClassStandard, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Lookup(rates, tt.region, tt.class); got != tt.want {
+				t.Errorf("Lookup(%+v, %q) = %v, want %v", tt.region, tt.class, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("no rates", func(t *testing.T) {
+		if got := Lookup(nil, Region{Country: "Germany"}, ClassStandard); got != 0 {
+			t.Errorf("Lookup() without rates = %v, want 0", got)
+		}
+	})
+}
+
+func TestCalculate(t *testing.T) {
+	tests := []struct {
+		name      string
+		region    Region
+		lines     []Line
+		inclusive bool
+		want      []LineTax
+		wantTotal int64
+	}{
+		{
+			name:   "exclusive",
+			region: Region{Country: "Germany"},
+			lines: []Line{
+				{TaxClass: ClassStandard, Amount: money.New(10000, "USD")},
+				{TaxClass: ClassReduced, Amount: money.New(5000, "USD")},
+				{TaxClass: ClassZero, Amount: money.New(2000, "USD")},
+			},
+			want:      []LineTax{{19, money.New(1900, "USD")}, {7, money.New(350, "USD")}, {0, money.New(0, "USD")}},
+			wantTotal: 2250,
+		},
+		{
+			name:   "inclusive",
+			region: Region{Country: "Germany"},
+			lines: []Line{
+				{TaxClass: ClassStandard, Amount: money.New(11900, "USD")},
+				{TaxClass: ClassReduced, Amount: money.New(10700, "USD")},
+			},
+			inclusive: true,
+			want:      []LineTax{{19, money.New(1900, "USD")}, {7, money.New(700, "USD")}},
+			wantTotal: 2600,
+		},
+		{
+			name:   "rounded per line",
+			region: Region{Country: "United States", State: "California"},
+			lines: []Line{
+				{TaxClass: ClassStandard, Amount: money.New(1999, "USD")},
+				{TaxClass: ClassStandard, Amount: money.New(1999, "USD")},
+			},
+			want:      []LineTax{{7.25, money.New(145, "USD")}, {7.25, money.New(145, "USD")}},
+			wantTotal: 290,
+		},
+		{
+			name:   "inclusive rounded per line",
+			region: Region{Country: "United States", State: "California"},
+			lines: []Line{
+				{TaxClass: ClassStandard, Amount: money.New(1999, "USD")},
+			},
+			inclusive: true,
+			want:      []LineTax{{7.25, money.New(135, "USD")}},
+			wantTotal: 135,
+		},
+		{
+			name:      "untaxed region",
+			region:    Region{Country: "Japan"},
+			lines:     []Line{{TaxClass: ClassStandard, Amount: money.New(10000, "USD")}},
+			want:      []LineTax{{0, money.New(0, "USD")}},
+			wantTotal: 0,
+		},
+		{
+			name:      "no lines",
+			region:    Region{Country: "Germany"},
+			want:      []LineTax{},
+			wantTotal: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Calculate(rates, tt.region, tt.lines, "USD", tt.inclusive)
+
+			if len(got.Lines) != len(tt.want) {
+				t.Fatalf("got %d lines, want %d", len(got.Lines), len(tt.want))
+			}
+			for i, want := range tt.want {
+				if got.Lines[i] != want {
+					t.Errorf("Lines[%d] = %+v, want %+v", i, got.Lines[i], want)
+				}
+			}
+			if got.Total != money.New(tt.wantTotal, "USD") {
+				t.Errorf("Total = %+v, want %+v", got.Total, money.New(tt.wantTotal, "USD"))
+			}
+		})
+	}
+}
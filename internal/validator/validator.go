@@ -1,11 +1,18 @@
 package validator
 
-import "regexp"
+import (
+	"cmp"
+	"regexp"
+)
 
 // EmailRX is a regular expression pattern for sanity checking the format of
 // email addresses.
 var EmailRX = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
 
+// SlugRX is a regular expression pattern for URL friendly identifiers
+// made of lowercase letters and digits separated by single hyphens.
+var SlugRX = regexp.MustCompile("^[a-z0-9]+(?:-[a-z0-9]+)*$")
+
 // Validator is a type which contains a map of validation errors.
 type Validator struct {
 	Errors map[string]string
@@ -48,6 +55,12 @@ func PermittedValue[T comparable](value T, permittedValues ...T) bool {
 	return false
 }
 
+// Between is a generic function that returns true if a value is
+// within the inclusive range [min, max].
+func Between[T cmp.Ordered](value, min, max T) bool {
+	return value >= min && value <= max
+}
+
 // Matches returns true if a string matches a specific regex pattern.
 func Matches(value string, rx *regexp.Regexp) bool {
 	return rx.MatchString(value)
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS furniture_category_id_idx;
+DROP INDEX IF EXISTS category_parent_id_idx;
+
+ALTER TABLE category
+    DROP CONSTRAINT IF EXISTS category_parent_check,
+    DROP CONSTRAINT IF EXISTS category_slug_key,
+    DROP COLUMN IF EXISTS version,
+    DROP COLUMN IF EXISTS parent_id,
+    DROP COLUMN IF EXISTS description,
+    DROP COLUMN IF EXISTS slug;
@@ -0,0 +1,28 @@
+ALTER TABLE category
+    ADD COLUMN IF NOT EXISTS slug        VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS description TEXT    NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS parent_id   BIGINT REFERENCES category (category_id) ON DELETE RESTRICT,
+    ADD COLUMN IF NOT EXISTS version     INTEGER NOT NULL DEFAULT 1;
+
+-- Backfill slugs from the existing names, suffixing the id when two names
+-- only differ by case or punctuation (e.g. "Sofas" and "sofas").
+WITH slugs AS (SELECT category_id,
+                      TRIM(BOTH '-' FROM REGEXP_REPLACE(LOWER(name), '[^a-z0-9]+', '-', 'g')) AS slug
+               FROM category),
+     ranked AS (SELECT category_id,
+                       slug,
+                       ROW_NUMBER() OVER (PARTITION BY slug ORDER BY category_id) AS rank
+                FROM slugs)
+UPDATE category c
+SET slug = CASE WHEN r.rank = 1 THEN r.slug ELSE r.slug || '-' || r.category_id END
+FROM ranked r
+WHERE c.category_id = r.category_id
+  AND c.slug IS NULL;
+
+ALTER TABLE category
+    ALTER COLUMN slug SET NOT NULL,
+    ADD CONSTRAINT category_slug_key UNIQUE (slug),
+    ADD CONSTRAINT category_parent_check CHECK (parent_id <> category_id);
+
+CREATE INDEX IF NOT EXISTS category_parent_id_idx ON category (parent_id);
+CREATE INDEX IF NOT EXISTS furniture_category_id_idx ON furniture (category_id);
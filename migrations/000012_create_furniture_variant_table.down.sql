@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS wishlist_user_item_idx;
+ALTER TABLE order_item DROP COLUMN IF EXISTS variant_id;
+ALTER TABLE wishlist DROP COLUMN IF EXISTS variant_id;
+ALTER TABLE cart DROP COLUMN IF EXISTS variant_id;
+
+DROP TABLE IF EXISTS furniture_variant;
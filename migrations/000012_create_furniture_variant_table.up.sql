@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS furniture_variant
+(
+    variant_id     BIGSERIAL PRIMARY KEY,
+    furniture_id   BIGINT       NOT NULL REFERENCES furniture (furniture_id) ON DELETE CASCADE,
+    sku            VARCHAR(64) UNIQUE NOT NULL,
+    options        JSONB        NOT NULL DEFAULT '{}',
+    price_delta    DECIMAL(10, 2),
+    price_override DECIMAL(10, 2),
+    stock          INTEGER      NOT NULL DEFAULT 0,
+    image_urls     TEXT[],
+    version        INTEGER      NOT NULL DEFAULT 1,
+    CONSTRAINT furniture_variant_price_check CHECK (price_delta IS NULL OR price_override IS NULL),
+    CONSTRAINT furniture_variant_stock_check CHECK (stock >= 0)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS furniture_variant_options_idx ON furniture_variant (furniture_id, options);
+
+ALTER TABLE cart
+    ADD COLUMN IF NOT EXISTS variant_id BIGINT REFERENCES furniture_variant (variant_id) ON DELETE CASCADE;
+
+ALTER TABLE wishlist
+    ADD COLUMN IF NOT EXISTS variant_id BIGINT REFERENCES furniture_variant (variant_id) ON DELETE CASCADE;
+
+-- A furniture, or a specific variant of it, is in the wishlist of a user
+-- at most once.
+DELETE FROM wishlist w
+USING wishlist other
+WHERE w.user_id = other.user_id AND w.furniture_id = other.furniture_id
+AND w.variant_id IS NOT DISTINCT FROM other.variant_id AND w.wishlist_id > other.wishlist_id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS wishlist_user_item_idx ON wishlist (user_id, furniture_id, COALESCE(variant_id, 0));
+
+ALTER TABLE order_item
+    ADD COLUMN IF NOT EXISTS variant_id BIGINT REFERENCES furniture_variant (variant_id);
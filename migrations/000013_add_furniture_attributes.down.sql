@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS furniture_materials_idx;
+DROP INDEX IF EXISTS furniture_width_cm_idx;
+
+ALTER TABLE furniture
+    DROP COLUMN IF EXISTS warranty_months,
+    DROP COLUMN IF EXISTS care_instructions,
+    DROP COLUMN IF EXISTS assembly_required,
+    DROP COLUMN IF EXISTS materials,
+    DROP COLUMN IF EXISTS weight_kg,
+    DROP COLUMN IF EXISTS seat_height_cm,
+    DROP COLUMN IF EXISTS height_cm,
+    DROP COLUMN IF EXISTS depth_cm,
+    DROP COLUMN IF EXISTS width_cm;
@@ -0,0 +1,13 @@
+ALTER TABLE furniture
+    ADD COLUMN IF NOT EXISTS width_cm          DECIMAL(7, 2),
+    ADD COLUMN IF NOT EXISTS depth_cm          DECIMAL(7, 2),
+    ADD COLUMN IF NOT EXISTS height_cm         DECIMAL(7, 2),
+    ADD COLUMN IF NOT EXISTS seat_height_cm    DECIMAL(7, 2),
+    ADD COLUMN IF NOT EXISTS weight_kg         DECIMAL(7, 2),
+    ADD COLUMN IF NOT EXISTS materials         TEXT[]  NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS assembly_required BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS care_instructions TEXT    NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS warranty_months   INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS furniture_width_cm_idx ON furniture (width_cm);
+CREATE INDEX IF NOT EXISTS furniture_materials_idx ON furniture USING GIN (materials);
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS furniture_name_trgm_idx;
+DROP INDEX IF EXISTS furniture_search_vector_idx;
+
+DROP TRIGGER IF EXISTS category_search_vector_update ON category;
+DROP TRIGGER IF EXISTS furniture_search_vector_update ON furniture;
+DROP FUNCTION IF EXISTS category_search_vector_trigger();
+DROP FUNCTION IF EXISTS furniture_search_vector_trigger();
+DROP FUNCTION IF EXISTS furniture_search_vector(furniture);
+
+ALTER TABLE furniture
+    DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,50 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE furniture
+    ADD COLUMN IF NOT EXISTS search_vector TSVECTOR;
+
+-- The search vector weights the name highest, followed by the category,
+-- the materials and finally the description.
+CREATE OR REPLACE FUNCTION furniture_search_vector(furniture_row furniture) RETURNS TSVECTOR AS
+$$
+SELECT setweight(to_tsvector('english', COALESCE(furniture_row.name, '')), 'A') ||
+       setweight(to_tsvector('english', COALESCE((SELECT name
+                                                  FROM category
+                                                  WHERE category_id = furniture_row.category_id), '')), 'B') ||
+       setweight(to_tsvector('english', ARRAY_TO_STRING(furniture_row.materials, ' ')), 'C') ||
+       setweight(to_tsvector('english', COALESCE(furniture_row.description, '')), 'D')
+$$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION furniture_search_vector_trigger() RETURNS TRIGGER AS
+$$
+BEGIN
+    NEW.search_vector := furniture_search_vector(NEW);
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER furniture_search_vector_update
+    BEFORE INSERT OR UPDATE OF name, description, materials, category_id
+    ON furniture
+    FOR EACH ROW
+EXECUTE FUNCTION furniture_search_vector_trigger();
+
+-- Renaming a category changes the search vector of all its furniture.
+CREATE OR REPLACE FUNCTION category_search_vector_trigger() RETURNS TRIGGER AS
+$$
+BEGIN
+    UPDATE furniture f SET search_vector = furniture_search_vector(f) WHERE f.category_id = NEW.category_id;
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER category_search_vector_update
+    AFTER UPDATE OF name
+    ON category
+    FOR EACH ROW
+EXECUTE FUNCTION category_search_vector_trigger();
+
+UPDATE furniture f SET search_vector = furniture_search_vector(f);
+
+CREATE INDEX IF NOT EXISTS furniture_search_vector_idx ON furniture USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS furniture_name_trgm_idx ON furniture USING GIN (name gin_trgm_ops);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS category_name_trgm_idx;
+DROP TABLE IF EXISTS search_query;
@@ -0,0 +1,12 @@
+-- Search queries are stored aggregated by their normalized text, without
+-- any information about who searched for them.
+CREATE TABLE IF NOT EXISTS search_query
+(
+    query             VARCHAR(200) PRIMARY KEY,
+    search_count      BIGINT                      NOT NULL DEFAULT 1,
+    last_result_count INTEGER                     NOT NULL,
+    last_searched_at  TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS search_query_prefix_idx ON search_query (query text_pattern_ops);
+CREATE INDEX IF NOT EXISTS category_name_trgm_idx ON category USING GIN (name gin_trgm_ops);
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS stock_reservation;
+
+DROP INDEX IF EXISTS cart_user_item_idx;
+
+ALTER TABLE payment
+    DROP COLUMN IF EXISTS reference,
+    DROP COLUMN IF EXISTS status;
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS version,
+    DROP COLUMN IF EXISTS status;
@@ -0,0 +1,26 @@
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS status  VARCHAR(30) NOT NULL DEFAULT 'pending_payment',
+    ADD COLUMN IF NOT EXISTS version INTEGER     NOT NULL DEFAULT 1;
+
+ALTER TABLE payment
+    ADD COLUMN IF NOT EXISTS status    VARCHAR(20) NOT NULL DEFAULT 'pending',
+    ADD COLUMN IF NOT EXISTS reference TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS cart_user_item_idx ON cart (user_id, furniture_id, COALESCE(variant_id, 0));
+
+CREATE TABLE IF NOT EXISTS stock_reservation
+(
+    reservation_id BIGSERIAL PRIMARY KEY,
+    order_id       BIGINT                      NOT NULL REFERENCES orders (order_id) ON DELETE CASCADE,
+    furniture_id   BIGINT                      NOT NULL REFERENCES furniture (furniture_id) ON DELETE CASCADE,
+    variant_id     BIGINT REFERENCES furniture_variant (variant_id) ON DELETE CASCADE,
+    quantity       INTEGER                     NOT NULL CHECK (quantity > 0),
+    status         VARCHAR(20)                 NOT NULL DEFAULT 'active',
+    expires_at     TIMESTAMP(0) WITH TIME ZONE NOT NULL,
+    created_at     TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS stock_reservation_active_idx ON stock_reservation (furniture_id, variant_id)
+    WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS stock_reservation_expires_at_idx ON stock_reservation (expires_at)
+    WHERE status = 'active';
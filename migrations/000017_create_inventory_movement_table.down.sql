@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS inventory_movement;
+
+ALTER TABLE furniture
+    DROP CONSTRAINT IF EXISTS furniture_stock_check;
@@ -0,0 +1,32 @@
+ALTER TABLE furniture
+    ADD CONSTRAINT furniture_stock_check CHECK (stock >= 0);
+
+CREATE TABLE IF NOT EXISTS inventory_movement
+(
+    movement_id  BIGSERIAL PRIMARY KEY,
+    furniture_id BIGINT                      NOT NULL REFERENCES furniture (furniture_id) ON DELETE CASCADE,
+    variant_id   BIGINT REFERENCES furniture_variant (variant_id) ON DELETE CASCADE,
+    quantity     INTEGER                     NOT NULL CHECK (quantity <> 0),
+    reason       VARCHAR(30)                 NOT NULL,
+    actor_id     BIGINT REFERENCES users (user_id) ON DELETE SET NULL,
+    reference    TEXT                        NOT NULL DEFAULT '',
+    note         TEXT                        NOT NULL DEFAULT '',
+    stock_after  INTEGER                     NOT NULL,
+    created_at   TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT inventory_movement_reason_check
+        CHECK (reason IN ('receipt', 'sale', 'return', 'damage', 'manual_correction'))
+);
+
+CREATE INDEX IF NOT EXISTS inventory_movement_item_idx ON inventory_movement (furniture_id, variant_id, movement_id);
+
+-- Open the ledger with the current stock, so that the stock of every
+-- item matches the sum of its movements.
+INSERT INTO inventory_movement (furniture_id, variant_id, quantity, reason, note, stock_after)
+SELECT furniture_id, NULL, stock, 'manual_correction', 'opening balance', stock
+FROM furniture
+WHERE stock <> 0;
+
+INSERT INTO inventory_movement (furniture_id, variant_id, quantity, reason, note, stock_after)
+SELECT furniture_id, variant_id, stock, 'manual_correction', 'opening balance', stock
+FROM furniture_variant
+WHERE stock <> 0;
@@ -0,0 +1,18 @@
+ALTER TABLE shipment
+    DROP COLUMN IF EXISTS warehouse_id;
+
+ALTER TABLE stock_reservation
+    DROP COLUMN IF EXISTS warehouse_id;
+
+DELETE FROM inventory_movement
+WHERE reason = 'transfer';
+
+ALTER TABLE inventory_movement
+    DROP COLUMN IF EXISTS warehouse_id,
+    DROP CONSTRAINT IF EXISTS inventory_movement_reason_check,
+    ADD CONSTRAINT inventory_movement_reason_check
+        CHECK (reason IN ('receipt', 'sale', 'return', 'damage', 'manual_correction'));
+
+DROP TABLE IF EXISTS warehouse_stock;
+
+DROP TABLE IF EXISTS warehouse;
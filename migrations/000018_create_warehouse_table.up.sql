@@ -0,0 +1,67 @@
+CREATE TABLE IF NOT EXISTS warehouse
+(
+    warehouse_id BIGSERIAL PRIMARY KEY,
+    name         VARCHAR(100)                NOT NULL,
+    address      TEXT                        NOT NULL,
+    city         VARCHAR(100)                NOT NULL,
+    state        VARCHAR(100)                NOT NULL,
+    country      VARCHAR(100)                NOT NULL,
+    zip_code     VARCHAR(10)                 NOT NULL,
+    created_at   TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    version      INTEGER                     NOT NULL DEFAULT 1,
+    CONSTRAINT warehouse_name_key UNIQUE (name)
+);
+
+CREATE TABLE IF NOT EXISTS warehouse_stock
+(
+    warehouse_id BIGINT  NOT NULL REFERENCES warehouse (warehouse_id) ON DELETE RESTRICT,
+    furniture_id BIGINT  NOT NULL REFERENCES furniture (furniture_id) ON DELETE CASCADE,
+    variant_id   BIGINT REFERENCES furniture_variant (variant_id) ON DELETE CASCADE,
+    stock        INTEGER NOT NULL,
+    CONSTRAINT warehouse_stock_stock_check CHECK (stock >= 0)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS warehouse_stock_item_idx
+    ON warehouse_stock (warehouse_id, furniture_id, COALESCE(variant_id, 0));
+CREATE INDEX IF NOT EXISTS warehouse_stock_furniture_id_idx ON warehouse_stock (furniture_id, variant_id);
+
+-- The existing stock is moved to a main warehouse, whose address is to be
+-- completed by an admin.
+INSERT INTO warehouse (name, address, city, state, country, zip_code)
+VALUES ('Main warehouse', '', '', '', '', '');
+
+INSERT INTO warehouse_stock (warehouse_id, furniture_id, variant_id, stock)
+SELECT w.warehouse_id, f.furniture_id, NULL, f.stock
+FROM furniture f, warehouse w
+WHERE w.name = 'Main warehouse' AND f.stock > 0;
+
+INSERT INTO warehouse_stock (warehouse_id, furniture_id, variant_id, stock)
+SELECT w.warehouse_id, v.furniture_id, v.variant_id, v.stock
+FROM furniture_variant v, warehouse w
+WHERE w.name = 'Main warehouse' AND v.stock > 0;
+
+ALTER TABLE inventory_movement
+    ADD COLUMN IF NOT EXISTS warehouse_id BIGINT REFERENCES warehouse (warehouse_id) ON DELETE RESTRICT,
+    DROP CONSTRAINT IF EXISTS inventory_movement_reason_check,
+    ADD CONSTRAINT inventory_movement_reason_check
+        CHECK (reason IN ('receipt', 'sale', 'return', 'damage', 'manual_correction', 'transfer'));
+
+ALTER TABLE stock_reservation
+    ADD COLUMN IF NOT EXISTS warehouse_id BIGINT REFERENCES warehouse (warehouse_id) ON DELETE RESTRICT;
+
+UPDATE inventory_movement
+SET warehouse_id = (SELECT warehouse_id FROM warehouse WHERE name = 'Main warehouse');
+
+UPDATE stock_reservation
+SET warehouse_id = (SELECT warehouse_id FROM warehouse WHERE name = 'Main warehouse');
+
+ALTER TABLE inventory_movement
+    ALTER COLUMN warehouse_id SET NOT NULL;
+
+ALTER TABLE stock_reservation
+    ALTER COLUMN warehouse_id SET NOT NULL;
+
+ALTER TABLE shipment
+    ADD COLUMN IF NOT EXISTS warehouse_id BIGINT REFERENCES warehouse (warehouse_id) ON DELETE RESTRICT;
+
+CREATE INDEX IF NOT EXISTS inventory_movement_warehouse_id_idx ON inventory_movement (warehouse_id);
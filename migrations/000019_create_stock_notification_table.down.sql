@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS stock_notification;
+
+ALTER TABLE furniture
+    DROP COLUMN IF EXISTS reorder_threshold;
@@ -0,0 +1,18 @@
+ALTER TABLE furniture
+    ADD COLUMN IF NOT EXISTS reorder_threshold INTEGER NOT NULL DEFAULT 0,
+    ADD CONSTRAINT furniture_reorder_threshold_check CHECK (reorder_threshold >= 0);
+
+CREATE TABLE IF NOT EXISTS stock_notification
+(
+    notification_id BIGSERIAL PRIMARY KEY,
+    user_id         BIGINT                      NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    furniture_id    BIGINT                      NOT NULL REFERENCES furniture (furniture_id) ON DELETE CASCADE,
+    variant_id      BIGINT REFERENCES furniture_variant (variant_id) ON DELETE CASCADE,
+    created_at      TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    notified_at     TIMESTAMP(0) WITH TIME ZONE
+);
+
+-- A customer has at most one pending notification per item.
+CREATE UNIQUE INDEX IF NOT EXISTS stock_notification_pending_idx
+    ON stock_notification (user_id, furniture_id, COALESCE(variant_id, 0))
+    WHERE notified_at IS NULL;
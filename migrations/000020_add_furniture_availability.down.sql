@@ -0,0 +1,10 @@
+ALTER TABLE order_item
+    DROP COLUMN IF EXISTS expected_ship_date,
+    DROP COLUMN IF EXISTS backordered_quantity;
+
+DROP INDEX IF EXISTS furniture_availability_idx;
+
+ALTER TABLE furniture
+    DROP COLUMN IF EXISTS release_date,
+    DROP COLUMN IF EXISTS lead_time_days,
+    DROP COLUMN IF EXISTS availability;
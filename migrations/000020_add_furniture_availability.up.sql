@@ -0,0 +1,21 @@
+ALTER TABLE furniture
+    ADD COLUMN IF NOT EXISTS availability   VARCHAR(20) NOT NULL DEFAULT 'in_stock',
+    ADD COLUMN IF NOT EXISTS lead_time_days INTEGER,
+    ADD COLUMN IF NOT EXISTS release_date   DATE,
+    ADD CONSTRAINT furniture_availability_check
+        CHECK (availability IN ('in_stock', 'backorder', 'preorder', 'discontinued')),
+    -- Backorders need a lead time and pre-orders a release date.
+    ADD CONSTRAINT furniture_lead_time_days_check
+        CHECK (availability <> 'backorder' OR lead_time_days > 0),
+    ADD CONSTRAINT furniture_release_date_check
+        CHECK (availability <> 'preorder' OR release_date IS NOT NULL);
+
+CREATE INDEX IF NOT EXISTS furniture_availability_idx ON furniture (availability);
+
+-- The quantity ordered beyond the stock of a backordered or pre-ordered item,
+-- which is shipped on the expected ship date rather than reserved.
+ALTER TABLE order_item
+    ADD COLUMN IF NOT EXISTS backordered_quantity INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS expected_ship_date   DATE,
+    ADD CONSTRAINT order_item_backordered_quantity_check
+        CHECK (backordered_quantity >= 0 AND backordered_quantity <= quantity);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS bundle_component;
+
+ALTER TABLE furniture
+    DROP CONSTRAINT IF EXISTS furniture_bundle_stock_check,
+    DROP COLUMN IF EXISTS bundle_discount_percent,
+    DROP COLUMN IF EXISTS product_type;
@@ -0,0 +1,24 @@
+-- A bundle holds no stock of its own, it is made of the stock of its
+-- components. Its price is fixed, or a discount on the sum of the prices
+-- of its components when bundle_discount_percent is set.
+ALTER TABLE furniture
+    ADD COLUMN IF NOT EXISTS product_type            VARCHAR(20) NOT NULL DEFAULT 'standard',
+    ADD COLUMN IF NOT EXISTS bundle_discount_percent NUMERIC(5, 2),
+    ADD CONSTRAINT furniture_product_type_check CHECK (product_type IN ('standard', 'bundle')),
+    ADD CONSTRAINT furniture_bundle_discount_percent_check
+        CHECK (bundle_discount_percent > 0 AND bundle_discount_percent < 100),
+    ADD CONSTRAINT furniture_bundle_stock_check CHECK (product_type <> 'bundle' OR stock = 0);
+
+CREATE TABLE IF NOT EXISTS bundle_component
+(
+    bundle_component_id BIGSERIAL PRIMARY KEY,
+    bundle_id           BIGINT  NOT NULL REFERENCES furniture (furniture_id) ON DELETE CASCADE,
+    furniture_id        BIGINT  NOT NULL REFERENCES furniture (furniture_id),
+    variant_id          BIGINT REFERENCES furniture_variant (variant_id),
+    quantity            INTEGER NOT NULL CHECK (quantity > 0),
+    CONSTRAINT bundle_component_self_check CHECK (bundle_id <> furniture_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS bundle_component_item_idx
+    ON bundle_component (bundle_id, furniture_id, COALESCE(variant_id, 0));
+CREATE INDEX IF NOT EXISTS bundle_component_furniture_idx ON bundle_component (furniture_id);
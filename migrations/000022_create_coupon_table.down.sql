@@ -0,0 +1,6 @@
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS discount_amount;
+
+DROP TABLE IF EXISTS coupon_redemption;
+DROP TABLE IF EXISTS cart_coupon;
+DROP TABLE IF EXISTS coupon;
@@ -0,0 +1,52 @@
+CREATE TABLE IF NOT EXISTS coupon
+(
+    coupon_id      BIGSERIAL PRIMARY KEY,
+    code           VARCHAR(50)                 NOT NULL,
+    description    TEXT                        NOT NULL DEFAULT '',
+    discount_type  VARCHAR(20)                 NOT NULL,
+    amount         DECIMAL(10, 2)              NOT NULL CHECK (amount > 0),
+    min_subtotal   DECIMAL(10, 2)              NOT NULL DEFAULT 0 CHECK (min_subtotal >= 0),
+    -- The coupon only discounts the furniture in these categories, or their
+    -- sub categories, and these furniture. Empty means every furniture.
+    category_ids   BIGINT[]                    NOT NULL DEFAULT '{}',
+    furniture_ids  BIGINT[]                    NOT NULL DEFAULT '{}',
+    starts_at      TIMESTAMP(0) WITH TIME ZONE,
+    ends_at        TIMESTAMP(0) WITH TIME ZONE,
+    usage_limit    INTEGER CHECK (usage_limit > 0),
+    per_user_limit INTEGER CHECK (per_user_limit > 0),
+    stackable      BOOLEAN                     NOT NULL DEFAULT FALSE,
+    created_at     TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    version        INTEGER                     NOT NULL DEFAULT 1,
+    CONSTRAINT coupon_discount_type_check CHECK (discount_type IN ('percentage', 'fixed')),
+    CONSTRAINT coupon_percentage_check CHECK (discount_type <> 'percentage' OR amount <= 100),
+    CONSTRAINT coupon_validity_check CHECK (starts_at IS NULL OR ends_at IS NULL OR starts_at < ends_at)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS coupon_code_idx ON coupon (UPPER(code));
+
+-- The coupons applied to the cart of a user.
+CREATE TABLE IF NOT EXISTS cart_coupon
+(
+    user_id    BIGINT                      NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    coupon_id  BIGINT                      NOT NULL REFERENCES coupon (coupon_id) ON DELETE CASCADE,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, coupon_id)
+);
+
+-- The coupons used by the orders. A redemption only counts towards the
+-- usage limits while its order is pending payment or paid.
+CREATE TABLE IF NOT EXISTS coupon_redemption
+(
+    redemption_id BIGSERIAL PRIMARY KEY,
+    coupon_id     BIGINT                      NOT NULL REFERENCES coupon (coupon_id),
+    user_id       BIGINT                      NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    order_id      BIGINT                      NOT NULL REFERENCES orders (order_id) ON DELETE CASCADE,
+    amount        DECIMAL(10, 2)              NOT NULL,
+    created_at    TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT coupon_redemption_order_key UNIQUE (order_id, coupon_id)
+);
+
+CREATE INDEX IF NOT EXISTS coupon_redemption_coupon_idx ON coupon_redemption (coupon_id, user_id);
+
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS discount_amount DECIMAL(10, 2) NOT NULL DEFAULT 0;
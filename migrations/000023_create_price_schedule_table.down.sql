@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS price_history;
+DROP TABLE IF EXISTS price_schedule;
+
+ALTER TABLE furniture
+    DROP CONSTRAINT IF EXISTS furniture_compare_at_price_check,
+    DROP COLUMN IF EXISTS compare_at_price;
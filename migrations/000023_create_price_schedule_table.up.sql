@@ -0,0 +1,42 @@
+-- While a sale is running, price holds the sale price and compare_at_price
+-- the regular price, which is restored when the sale ends.
+ALTER TABLE furniture
+    ADD COLUMN IF NOT EXISTS compare_at_price DECIMAL(10, 2),
+    ADD CONSTRAINT furniture_compare_at_price_check CHECK (compare_at_price > price);
+
+CREATE TABLE IF NOT EXISTS price_schedule
+(
+    schedule_id  BIGSERIAL PRIMARY KEY,
+    furniture_id BIGINT                      NOT NULL REFERENCES furniture (furniture_id) ON DELETE CASCADE,
+    sale_price   DECIMAL(10, 2)              NOT NULL CHECK (sale_price > 0),
+    starts_at    TIMESTAMP(0) WITH TIME ZONE NOT NULL,
+    ends_at      TIMESTAMP(0) WITH TIME ZONE NOT NULL,
+    status       VARCHAR(20)                 NOT NULL DEFAULT 'scheduled',
+    created_at   TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT price_schedule_status_check CHECK (status IN ('scheduled', 'active', 'ended', 'cancelled')),
+    CONSTRAINT price_schedule_period_check CHECK (starts_at < ends_at)
+);
+
+CREATE INDEX IF NOT EXISTS price_schedule_furniture_idx ON price_schedule (furniture_id, starts_at);
+CREATE INDEX IF NOT EXISTS price_schedule_pending_idx ON price_schedule (status, starts_at)
+    WHERE status IN ('scheduled', 'active');
+
+-- Every price a furniture has been sold at, from the time it took effect.
+CREATE TABLE IF NOT EXISTS price_history
+(
+    history_id       BIGSERIAL PRIMARY KEY,
+    furniture_id     BIGINT                      NOT NULL REFERENCES furniture (furniture_id) ON DELETE CASCADE,
+    price            DECIMAL(10, 2)              NOT NULL,
+    compare_at_price DECIMAL(10, 2),
+    reason           VARCHAR(30)                 NOT NULL,
+    schedule_id      BIGINT REFERENCES price_schedule (schedule_id) ON DELETE SET NULL,
+    created_at       TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT price_history_reason_check
+        CHECK (reason IN ('created', 'updated', 'sale_started', 'sale_ended', 'components_changed'))
+);
+
+CREATE INDEX IF NOT EXISTS price_history_furniture_idx ON price_history (furniture_id, created_at);
+
+INSERT INTO price_history (furniture_id, price, reason)
+SELECT furniture_id, price, 'created'
+FROM furniture;
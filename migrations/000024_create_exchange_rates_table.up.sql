@@ -0,0 +1,16 @@
+-- The rates the prices are converted at from the base currency, USD. A rate
+-- is the amount of the currency worth one unit of the base currency.
+CREATE TABLE IF NOT EXISTS exchange_rates
+(
+    currency   CHAR(3) PRIMARY KEY,
+    rate       DECIMAL(18, 8)              NOT NULL CHECK (rate > 0),
+    updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    version    INTEGER                     NOT NULL DEFAULT 1,
+    CONSTRAINT exchange_rates_currency_check CHECK (currency ~ '^[A-Z]{3}$' AND currency <> 'USD')
+);
+
+-- The currency an order is charged in and the rate it was converted at, the
+-- amounts of the order are kept in the base currency.
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS currency      CHAR(3)        NOT NULL DEFAULT 'USD',
+    ADD COLUMN IF NOT EXISTS exchange_rate DECIMAL(18, 8) NOT NULL DEFAULT 1;
@@ -0,0 +1,15 @@
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS prices_include_tax,
+    DROP COLUMN IF EXISTS tax_amount;
+
+ALTER TABLE order_item
+    DROP COLUMN IF EXISTS tax_amount,
+    DROP COLUMN IF EXISTS tax_percent,
+    DROP COLUMN IF EXISTS tax_class,
+    DROP COLUMN IF EXISTS discount_amount;
+
+ALTER TABLE category
+    DROP CONSTRAINT IF EXISTS category_tax_class_check,
+    DROP COLUMN IF EXISTS tax_class;
+
+DROP TABLE IF EXISTS tax_rate;
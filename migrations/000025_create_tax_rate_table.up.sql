@@ -0,0 +1,34 @@
+-- The percentage a tax class is taxed at in a country, or in a state of it
+-- when state is not empty.
+CREATE TABLE IF NOT EXISTS tax_rate
+(
+    rate_id    BIGSERIAL PRIMARY KEY,
+    country    VARCHAR(100)                NOT NULL,
+    state      VARCHAR(100)                NOT NULL DEFAULT '',
+    tax_class  VARCHAR(20)                 NOT NULL DEFAULT 'standard',
+    percent    DECIMAL(5, 2)               NOT NULL CHECK (percent BETWEEN 0 AND 100),
+    name       VARCHAR(100)                NOT NULL DEFAULT '',
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    version    INTEGER                     NOT NULL DEFAULT 1,
+    CONSTRAINT tax_rate_tax_class_check CHECK (tax_class IN ('standard', 'reduced'))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS tax_rate_region_idx
+    ON tax_rate (UPPER(TRIM(country)), UPPER(TRIM(state)), tax_class);
+
+ALTER TABLE category
+    ADD COLUMN IF NOT EXISTS tax_class VARCHAR(20) NOT NULL DEFAULT 'standard',
+    ADD CONSTRAINT category_tax_class_check CHECK (tax_class IN ('standard', 'reduced', 'zero'));
+
+-- The tax of every line is kept with the discount it was computed after and
+-- the percentage it was computed at, so that the invoices can be reproduced
+-- whatever the rates become.
+ALTER TABLE order_item
+    ADD COLUMN IF NOT EXISTS discount_amount DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS tax_class       VARCHAR(20)    NOT NULL DEFAULT 'standard',
+    ADD COLUMN IF NOT EXISTS tax_percent     DECIMAL(5, 2)  NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS tax_amount      DECIMAL(10, 2) NOT NULL DEFAULT 0;
+
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS tax_amount         DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS prices_include_tax BOOLEAN        NOT NULL DEFAULT FALSE;
@@ -0,0 +1,9 @@
+ALTER TABLE shipment
+    DROP COLUMN IF EXISTS shipping_cost,
+    DROP COLUMN IF EXISTS shipping_method,
+    DROP COLUMN IF EXISTS shipping_method_id;
+
+DROP TABLE IF EXISTS shipping_rate;
+DROP TABLE IF EXISTS shipping_method;
+DROP TABLE IF EXISTS shipping_zone_region;
+DROP TABLE IF EXISTS shipping_zone;
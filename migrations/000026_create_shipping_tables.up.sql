@@ -0,0 +1,55 @@
+CREATE TABLE IF NOT EXISTS shipping_zone
+(
+    zone_id    BIGSERIAL PRIMARY KEY,
+    code       VARCHAR(50)                 NOT NULL UNIQUE,
+    name       VARCHAR(100)                NOT NULL,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    version    INTEGER                     NOT NULL DEFAULT 1
+);
+
+-- The countries, or states of them when state is not empty, a zone is made
+-- of. A region belongs to one zone only.
+CREATE TABLE IF NOT EXISTS shipping_zone_region
+(
+    zone_id BIGINT       NOT NULL REFERENCES shipping_zone (zone_id) ON DELETE CASCADE,
+    country VARCHAR(100) NOT NULL,
+    state   VARCHAR(100) NOT NULL DEFAULT ''
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS shipping_zone_region_idx
+    ON shipping_zone_region (UPPER(TRIM(country)), UPPER(TRIM(state)));
+CREATE INDEX IF NOT EXISTS shipping_zone_region_zone_idx ON shipping_zone_region (zone_id);
+
+CREATE TABLE IF NOT EXISTS shipping_method
+(
+    method_id               BIGSERIAL PRIMARY KEY,
+    code                    VARCHAR(50)                 NOT NULL UNIQUE,
+    name                    VARCHAR(100)                NOT NULL,
+    kind                    VARCHAR(20)                 NOT NULL,
+    volumetric_divisor      INTEGER                     NOT NULL DEFAULT 5000 CHECK (volumetric_divisor > 0),
+    free_shipping_threshold DECIMAL(10, 2) CHECK (free_shipping_threshold >= 0),
+    active                  BOOLEAN                     NOT NULL DEFAULT TRUE,
+    created_at              TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    version                 INTEGER                     NOT NULL DEFAULT 1,
+    CONSTRAINT shipping_method_kind_check CHECK (kind IN ('standard', 'white_glove', 'click_and_collect'))
+);
+
+-- The rate tables of the methods, a bracket without max_weight_kg covering
+-- any weight above the other brackets of the zone.
+CREATE TABLE IF NOT EXISTS shipping_rate
+(
+    method_id     BIGINT         NOT NULL REFERENCES shipping_method (method_id) ON DELETE CASCADE,
+    zone_id       BIGINT         NOT NULL REFERENCES shipping_zone (zone_id) ON DELETE CASCADE,
+    max_weight_kg DECIMAL(10, 2) CHECK (max_weight_kg > 0),
+    price         DECIMAL(10, 2) NOT NULL CHECK (price >= 0)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS shipping_rate_bracket_idx
+    ON shipping_rate (method_id, zone_id, COALESCE(max_weight_kg, -1));
+
+-- The method an order is shipped with is kept by name as well, so that it
+-- outlives the method.
+ALTER TABLE shipment
+    ADD COLUMN IF NOT EXISTS shipping_method_id BIGINT REFERENCES shipping_method (method_id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS shipping_method    VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS shipping_cost      DECIMAL(10, 2) NOT NULL DEFAULT 0;
@@ -0,0 +1,6 @@
+ALTER TABLE shipment
+    DROP COLUMN IF EXISTS delivery_window_end,
+    DROP COLUMN IF EXISTS delivery_window_start,
+    DROP COLUMN IF EXISTS delivery_slot_id;
+
+DROP TABLE IF EXISTS delivery_slot;
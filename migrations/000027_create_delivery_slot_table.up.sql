@@ -0,0 +1,24 @@
+-- The delivery windows of a zone and how many deliveries the crews can
+-- make in each. booked counts the shipments of the orders pending payment
+-- or paid that are delivered in the window.
+CREATE TABLE IF NOT EXISTS delivery_slot
+(
+    slot_id    BIGSERIAL PRIMARY KEY,
+    zone_id    BIGINT                      NOT NULL REFERENCES shipping_zone (zone_id) ON DELETE CASCADE,
+    starts_at  TIMESTAMP(0) WITH TIME ZONE NOT NULL,
+    ends_at    TIMESTAMP(0) WITH TIME ZONE NOT NULL,
+    capacity   INTEGER                     NOT NULL CHECK (capacity > 0),
+    booked     INTEGER                     NOT NULL DEFAULT 0 CHECK (booked >= 0),
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    version    INTEGER                     NOT NULL DEFAULT 1,
+    CONSTRAINT delivery_slot_window_check CHECK (ends_at > starts_at),
+    CONSTRAINT delivery_slot_capacity_check CHECK (booked <= capacity)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS delivery_slot_window_idx ON delivery_slot (zone_id, starts_at);
+
+-- The window is kept on the shipment as well, so that it outlives the slot.
+ALTER TABLE shipment
+    ADD COLUMN IF NOT EXISTS delivery_slot_id      BIGINT REFERENCES delivery_slot (slot_id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS delivery_window_start TIMESTAMP(0) WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS delivery_window_end   TIMESTAMP(0) WITH TIME ZONE;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS shipment_event;
+
+ALTER TABLE shipment
+    DROP COLUMN IF EXISTS tracking_number,
+    DROP COLUMN IF EXISTS carrier;
@@ -0,0 +1,18 @@
+ALTER TABLE shipment
+    ADD COLUMN IF NOT EXISTS carrier         VARCHAR(50),
+    ADD COLUMN IF NOT EXISTS tracking_number VARCHAR(100);
+
+-- The timeline of a shipment, as reported by its carrier. An event is only
+-- recorded once however many times the carrier reports it.
+CREATE TABLE IF NOT EXISTS shipment_event
+(
+    event_id    BIGSERIAL PRIMARY KEY,
+    shipment_id BIGINT                      NOT NULL REFERENCES shipment (shipment_id) ON DELETE CASCADE,
+    status      VARCHAR(30)                 NOT NULL,
+    description TEXT                        NOT NULL DEFAULT '',
+    location    VARCHAR(200)                NOT NULL DEFAULT '',
+    occurred_at TIMESTAMP(0) WITH TIME ZONE NOT NULL,
+    created_at  TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS shipment_event_idx ON shipment_event (shipment_id, status, occurred_at);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS shipment_item;
+
+ALTER TABLE shipment
+    DROP CONSTRAINT IF EXISTS shipment_status_check,
+    DROP COLUMN IF EXISTS status,
+    DROP COLUMN IF EXISTS order_id;
@@ -0,0 +1,38 @@
+-- An order is shipped in one or more shipments. The shipment created at
+-- checkout, referenced by the order, holds the shipping address and method,
+-- the other shipments are created when the rest of the items are shipped.
+ALTER TABLE shipment
+    ADD COLUMN IF NOT EXISTS order_id BIGINT REFERENCES orders (order_id) ON DELETE CASCADE,
+    ADD COLUMN IF NOT EXISTS status   VARCHAR(20) NOT NULL DEFAULT 'pending',
+    ADD CONSTRAINT shipment_status_check CHECK (status IN ('pending', 'shipped', 'delivered'));
+
+UPDATE shipment s
+SET order_id = o.order_id,
+    status   = CASE
+                   WHEN o.status = 'delivered' THEN 'delivered'
+                   WHEN s.tracking_number IS NOT NULL THEN 'shipped'
+                   ELSE 'pending'
+        END
+FROM orders o
+WHERE o.shipment_id = s.shipment_id;
+
+CREATE INDEX IF NOT EXISTS shipment_order_idx ON shipment (order_id);
+
+-- The quantities of the order items in a shipment.
+CREATE TABLE IF NOT EXISTS shipment_item
+(
+    shipment_id   BIGINT  NOT NULL REFERENCES shipment (shipment_id) ON DELETE CASCADE,
+    order_item_id BIGINT  NOT NULL REFERENCES order_item (order_item_id) ON DELETE CASCADE,
+    quantity      INTEGER NOT NULL CHECK (quantity > 0),
+    PRIMARY KEY (shipment_id, order_item_id)
+);
+
+CREATE INDEX IF NOT EXISTS shipment_item_order_item_idx ON shipment_item (order_item_id);
+
+-- The orders shipped so far were shipped whole.
+INSERT INTO shipment_item(shipment_id, order_item_id, quantity)
+SELECT s.shipment_id, oi.order_item_id, oi.quantity
+FROM shipment s
+JOIN order_item oi ON oi.order_id = s.order_id
+WHERE s.status <> 'pending'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS return_item;
+DROP TABLE IF EXISTS return_request;
+
+ALTER TABLE payment
+    DROP CONSTRAINT IF EXISTS payment_refund_check,
+    DROP COLUMN IF EXISTS refunded_amount;
@@ -0,0 +1,37 @@
+-- The part of the payment given back to the customer by refunds.
+ALTER TABLE payment
+    ADD COLUMN IF NOT EXISTS refunded_amount DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    ADD CONSTRAINT payment_refund_check CHECK (refunded_amount >= 0 AND refunded_amount <= amount);
+
+-- A request of a customer to return items of a shipped order. It is
+-- reviewed by an admin, and refunded once the items are back.
+CREATE TABLE IF NOT EXISTS return_request
+(
+    return_id       BIGSERIAL PRIMARY KEY,
+    order_id        BIGINT                      NOT NULL REFERENCES orders (order_id) ON DELETE CASCADE,
+    user_id         BIGINT                      NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    status          VARCHAR(20)                 NOT NULL DEFAULT 'requested',
+    reason          VARCHAR(30)                 NOT NULL,
+    description     TEXT                        NOT NULL DEFAULT '',
+    photo_urls      TEXT[]                      NOT NULL DEFAULT '{}',
+    resolution_note TEXT                        NOT NULL DEFAULT '',
+    refund_amount   DECIMAL(10, 2)              NOT NULL DEFAULT 0,
+    created_at      TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    version         INTEGER                     NOT NULL DEFAULT 1,
+    CONSTRAINT return_request_status_check CHECK (status IN ('requested', 'approved', 'rejected', 'refunded'))
+);
+
+CREATE INDEX IF NOT EXISTS return_request_order_idx ON return_request (order_id);
+CREATE INDEX IF NOT EXISTS return_request_status_idx ON return_request (status, created_at);
+
+-- The quantities of the order items returned, and whether they were put
+-- back in stock or written off once received.
+CREATE TABLE IF NOT EXISTS return_item
+(
+    return_id     BIGINT  NOT NULL REFERENCES return_request (return_id) ON DELETE CASCADE,
+    order_item_id BIGINT  NOT NULL REFERENCES order_item (order_item_id) ON DELETE CASCADE,
+    quantity      INTEGER NOT NULL CHECK (quantity > 0),
+    disposition   VARCHAR(20) CHECK (disposition IN ('restock', 'write_off')),
+    PRIMARY KEY (return_id, order_item_id)
+);
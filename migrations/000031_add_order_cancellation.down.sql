@@ -0,0 +1,12 @@
+DELETE FROM inventory_movement
+WHERE reason = 'cancellation';
+
+ALTER TABLE inventory_movement
+    DROP CONSTRAINT IF EXISTS inventory_movement_reason_check,
+    ADD CONSTRAINT inventory_movement_reason_check
+        CHECK (reason IN ('receipt', 'sale', 'return', 'damage', 'manual_correction', 'transfer'));
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS cancelled_by,
+    DROP COLUMN IF EXISTS cancellation_reason,
+    DROP COLUMN IF EXISTS cancelled_at;
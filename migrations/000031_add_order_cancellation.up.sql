@@ -0,0 +1,11 @@
+-- Who cancelled an order, when and why.
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS cancelled_at        TIMESTAMP(0) WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS cancellation_reason TEXT,
+    ADD COLUMN IF NOT EXISTS cancelled_by        BIGINT REFERENCES users (user_id) ON DELETE SET NULL;
+
+-- The stock of a cancelled order that was not shipped is put back.
+ALTER TABLE inventory_movement
+    DROP CONSTRAINT IF EXISTS inventory_movement_reason_check,
+    ADD CONSTRAINT inventory_movement_reason_check
+        CHECK (reason IN ('receipt', 'sale', 'return', 'damage', 'manual_correction', 'transfer', 'cancellation'));
@@ -0,0 +1,5 @@
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS invoiced_at,
+    DROP COLUMN IF EXISTS invoice_number;
+
+DROP TABLE IF EXISTS invoice_counter;
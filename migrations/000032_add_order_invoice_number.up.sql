@@ -0,0 +1,30 @@
+-- The number of the last invoice issued. Invoice numbers are taken from
+-- this single row rather than from a sequence, so that they are gap-free:
+-- the row stays locked until the payment the invoice is issued for is
+-- committed, and the number is given back when it is rolled back.
+CREATE TABLE IF NOT EXISTS invoice_counter
+(
+    counter_id  BOOLEAN PRIMARY KEY DEFAULT TRUE,
+    last_number BIGINT  NOT NULL DEFAULT 0,
+    CONSTRAINT invoice_counter_single_row CHECK (counter_id)
+);
+
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS invoice_number BIGINT UNIQUE,
+    ADD COLUMN IF NOT EXISTS invoiced_at    TIMESTAMP(0) WITH TIME ZONE;
+
+-- The orders paid already are invoiced in the order they were paid.
+WITH numbered AS (
+    SELECT o.order_id, p.payment_date, ROW_NUMBER() OVER (ORDER BY p.payment_date, o.order_id) AS invoice_number
+    FROM orders o
+    JOIN payment p ON o.payment_id = p.payment_id
+    WHERE p.payment_date IS NOT NULL
+)
+UPDATE orders o
+SET invoice_number = n.invoice_number, invoiced_at = n.payment_date
+FROM numbered n
+WHERE o.order_id = n.order_id;
+
+INSERT INTO invoice_counter(last_number)
+SELECT COALESCE(MAX(invoice_number), 0) FROM orders
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,16 @@
+-- When an order pending payment expires. It is set at checkout, so that
+-- orders which reserve no stock, being backordered or pre-ordered, expire
+-- like the others and give back their delivery slot and coupons.
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP(0) WITH TIME ZONE;
+
+-- The orders pending payment already expire with their reservations, or
+-- straight away when they hold none.
+UPDATE orders o
+SET expires_at = COALESCE((SELECT MIN(r.expires_at)
+                           FROM stock_reservation r
+                           WHERE r.order_id = o.order_id AND r.status = 'active'), NOW())
+WHERE o.status = 'pending_payment';
+
+CREATE INDEX IF NOT EXISTS orders_pending_expires_at_idx ON orders (expires_at)
+    WHERE status = 'pending_payment';
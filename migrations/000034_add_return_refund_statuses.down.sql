@@ -0,0 +1,10 @@
+-- The items of these returns were received already, so they must not be
+-- refunded again.
+UPDATE return_request
+SET status = 'refunded'
+WHERE status IN ('refunding', 'refund_failed');
+
+ALTER TABLE return_request
+    DROP CONSTRAINT IF EXISTS return_request_status_check,
+    ADD CONSTRAINT return_request_status_check
+        CHECK (status IN ('requested', 'approved', 'rejected', 'refunded'));
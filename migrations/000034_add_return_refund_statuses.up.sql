@@ -0,0 +1,6 @@
+-- A return is refunding while the payment provider refunds it, so that it
+-- is only refunded once, and refund failed when the provider declined it.
+ALTER TABLE return_request
+    DROP CONSTRAINT IF EXISTS return_request_status_check,
+    ADD CONSTRAINT return_request_status_check
+        CHECK (status IN ('requested', 'approved', 'rejected', 'refunding', 'refunded', 'refund_failed'));